@@ -0,0 +1,57 @@
+// Command socketclient prints the lyrics streamed by lyricsmpris
+// -listen-unix: the current line of the snapshot it gets on connect, then
+// each new line.
+//
+//	lyricsmpris -pipe -listen-unix /tmp/lyrics.sock &
+//	go run ./_examples/socketclient /tmp/lyrics.sock
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+)
+
+type message struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	Index int    `json:"index"`
+	Text  string `json:"text"`
+	Lines []struct {
+		Text string `json:"text"`
+	} `json:"lines"`
+}
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: socketclient SOCKET")
+		os.Exit(2)
+	}
+	conn, err := net.Dial("unix", os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "socketclient:", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(nil, 1<<20) // a snapshot holds the whole lyrics
+	for scanner.Scan() {
+		var m message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			fmt.Fprintln(os.Stderr, "socketclient:", err)
+			continue
+		}
+		switch m.Type {
+		case "snapshot":
+			if m.Index >= 0 {
+				fmt.Println(m.Lines[m.Index].Text)
+			}
+		case "track":
+			fmt.Printf("== %s ==\n", m.Title)
+		case "line":
+			fmt.Println(m.Text)
+		}
+	}
+}
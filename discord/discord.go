@@ -0,0 +1,276 @@
+// Package discord sets the Rich Presence activity of the Discord client
+// running on this machine, through its local IPC socket.
+package discord
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MaxText is the longest Details or State Discord takes, in
+	// characters; longer ones are cut.
+	MaxText = 128
+	// updateInterval is the least time between two activity updates,
+	// within Discord's limit of 5 per 20 seconds.
+	updateInterval = 5 * time.Second
+	// retryInterval is how often a Discord that is not running is looked
+	// for again.
+	retryInterval = 15 * time.Second
+	ioTimeout     = 5 * time.Second
+	// maxFrame bounds the frames read from Discord.
+	maxFrame = 64 << 10
+)
+
+// IPC opcodes.
+const (
+	opHandshake = 0
+	opFrame     = 1
+	opClose     = 2
+)
+
+// Activity is a Rich Presence activity.
+type Activity struct {
+	Details string `json:"details,omitempty"` // first line
+	State   string `json:"state,omitempty"`   // second line
+}
+
+// Client keeps the presence of one Discord application in step with the
+// latest activity set, from a goroutine of its own. SetActivity never
+// waits for Discord.
+type Client struct {
+	appID   string
+	verbose bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu       sync.Mutex
+	activity *Activity     // the activity wanted, nil for none
+	changed  chan struct{} // signals a new activity
+}
+
+// Start starts showing activities as the Discord application appID until
+// Close. Without a running Discord it keeps looking for one.
+func Start(appID string, verbose bool) *Client {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		appID:   appID,
+		verbose: verbose,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		changed: make(chan struct{}, 1),
+	}
+	go c.run(ctx)
+	return c
+}
+
+// SetActivity shows a, or no activity when a is nil. Only the latest
+// activity is kept; it is sent at most every updateInterval.
+func (c *Client) SetActivity(a *Activity) {
+	if a != nil {
+		a = &Activity{Details: cut(a.Details), State: cut(a.State)}
+	}
+	c.mu.Lock()
+	c.activity = a
+	c.mu.Unlock()
+	select {
+	case c.changed <- struct{}{}:
+	default:
+	}
+}
+
+// cut cuts s to MaxText characters, or empties it when it is too short
+// for Discord, which rejects texts of fewer than 2 characters.
+func cut(s string) string {
+	switch n := utf8.RuneCountInString(s); {
+	case n < 2:
+		return ""
+	case n <= MaxText:
+		return s
+	}
+	r := []rune(s)
+	return string(r[:MaxText-1]) + "…"
+}
+
+// Close clears the activity and disconnects.
+func (c *Client) Close() {
+	c.cancel()
+	<-c.done
+}
+
+func (c *Client) run(ctx context.Context) {
+	defer close(c.done)
+	for {
+		conn, err := c.connect()
+		if err == nil {
+			if c.verbose {
+				log.Print("discord: connected")
+			}
+			err = c.serve(ctx, conn)
+			if ctx.Err() != nil {
+				// leave no stale line behind
+				c.send(conn, nil)
+				conn.Close()
+				return
+			}
+			conn.Close()
+		}
+		if c.verbose {
+			log.Printf("discord: %v; retrying in %v", err, retryInterval)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// socketPaths returns where Discord's IPC socket may be: discord-ipc-0 to
+// 9 in the runtime directory, or in that of the Flatpak or Snap package.
+func socketPaths() []string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	var paths []string
+	for _, sub := range []string{"", "app/com.discordapp.Discord", "snap.discord"} {
+		for i := range 10 {
+			paths = append(paths, filepath.Join(dir, sub, "discord-ipc-"+strconv.Itoa(i)))
+		}
+	}
+	return paths
+}
+
+// connect dials the first Discord socket that answers and completes the
+// handshake.
+func (c *Client) connect() (net.Conn, error) {
+	var conn net.Conn
+	for _, path := range socketPaths() {
+		var err error
+		if conn, err = net.DialTimeout("unix", path, ioTimeout); err == nil {
+			break
+		}
+	}
+	if conn == nil {
+		return nil, errors.New("Discord is not running")
+	}
+	handshake, _ := json.Marshal(map[string]any{"v": 1, "client_id": c.appID})
+	if err := writeFrame(conn, opHandshake, handshake); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// the answer is a READY dispatch, or a close frame for a bad appID
+	if _, err := readReply(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("handshake: %w", err)
+	}
+	return conn, nil
+}
+
+// serve sends each new activity, keeping updateInterval between them,
+// until the connection fails or ctx is done.
+func (c *Client) serve(ctx context.Context, conn net.Conn) error {
+	// the activity before this connection, if any, goes out first
+	select {
+	case c.changed <- struct{}{}:
+	default:
+	}
+	var sent time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.changed:
+		}
+		if wait := time.Until(sent.Add(updateInterval)); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(wait):
+			}
+		}
+		c.mu.Lock()
+		a := c.activity
+		c.mu.Unlock()
+		if err := c.send(conn, a); err != nil {
+			return err
+		}
+		sent = time.Now()
+	}
+}
+
+// send sets the activity to a, nil clearing it, and reads the reply.
+func (c *Client) send(conn net.Conn, a *Activity) error {
+	cmd, _ := json.Marshal(map[string]any{
+		"cmd":   "SET_ACTIVITY",
+		"args":  map[string]any{"pid": os.Getpid(), "activity": a},
+		"nonce": strconv.FormatInt(time.Now().UnixNano(), 10),
+	})
+	if err := writeFrame(conn, opFrame, cmd); err != nil {
+		return err
+	}
+	reply, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	var r struct {
+		Evt  string `json:"evt"`
+		Data struct {
+			Message string `json:"message"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(reply, &r) == nil && r.Evt == "ERROR" && c.verbose {
+		// a rejected activity is no reason to drop the connection
+		log.Printf("discord: %s", r.Data.Message)
+	}
+	return nil
+}
+
+// writeFrame writes a frame: the opcode and payload length, both 32 bit
+// little endian, then the JSON payload.
+func writeFrame(conn net.Conn, op uint32, payload []byte) error {
+	frame := binary.LittleEndian.AppendUint32(nil, op)
+	frame = binary.LittleEndian.AppendUint32(frame, uint32(len(payload)))
+	conn.SetWriteDeadline(time.Now().Add(ioTimeout))
+	_, err := conn.Write(append(frame, payload...))
+	return err
+}
+
+// readReply reads a frame, returning its payload; a close frame from
+// Discord is an error with its message.
+func readReply(conn net.Conn) ([]byte, error) {
+	conn.SetReadDeadline(time.Now().Add(ioTimeout))
+	var head [8]byte
+	if _, err := io.ReadFull(conn, head[:]); err != nil {
+		return nil, err
+	}
+	op, n := binary.LittleEndian.Uint32(head[:4]), binary.LittleEndian.Uint32(head[4:])
+	if n > maxFrame {
+		return nil, errors.New("frame too large")
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return nil, err
+	}
+	if op == opClose {
+		var r struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(payload, &r)
+		return nil, fmt.Errorf("closed by Discord: %s", r.Message)
+	}
+	return payload, nil
+}
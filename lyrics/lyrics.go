@@ -2,12 +2,14 @@
 package lyrics
 
 import (
+	"cmp"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 )
@@ -16,18 +18,33 @@ import (
 type LyricLine struct {
 	Time float64
 	Text string
+	// Translation is the line's translation, if the lyrics provide one.
+	Translation string
 }
 
 // Lyric holds all parsed lyric lines.
 type Lyric struct {
+	// Lines are in time order, which lookups by position rely on.
 	Lines []LyricLine
+	// Source names where the lyrics came from, e.g. "lrclib".
+	Source string
 }
 
+// ErrUnreachable is returned when lrclib.net can't be reached.
+var ErrUnreachable = errors.New("lrclib unreachable")
+
+// ErrNotFound is returned when lrclib.net has no synced lyrics for a track.
+var ErrNotFound = errors.New("no synced lyrics found")
+
 // LyricsFetcher defines an interface for fetching lyrics.
 type LyricsFetcher interface {
 	FetchLyrics(title, artist, album string, duration float64) (*Lyric, error)
 }
 
+// lrclibAPI is where the lrclib.net endpoints are; tests point it at a
+// server of their own.
+var lrclibAPI = "https://lrclib.net/api"
+
 // lrclibAPIResponse models the response from lrclib.net API.
 type lrclibAPIResponse struct {
 	TrackName    string  `json:"trackName"`
@@ -43,8 +60,8 @@ func FetchLyrics(title, artist, album string, duration float64) (*Lyric, error)
 	client := &http.Client{Timeout: 10 * time.Second}
 
 	// Try exact match endpoint
-	apiURL := fmt.Sprintf("https://lrclib.net/api/get?track_name=%s&artist_name=%s&album_name=%s&duration=%.0f",
-		url.QueryEscape(title), url.QueryEscape(artist), url.QueryEscape(album), duration)
+	apiURL := fmt.Sprintf("%s/get?track_name=%s&artist_name=%s&album_name=%s&duration=%.0f",
+		lrclibAPI, url.QueryEscape(title), url.QueryEscape(artist), url.QueryEscape(album), duration)
 	lyric, err := fetchAndParse(client, apiURL)
 	if err != nil {
 		return nil, err
@@ -61,6 +78,11 @@ var _ LyricsFetcher = (*defaultLyricsFetcher)(nil)
 
 type defaultLyricsFetcher struct{}
 
+// NewFetcher returns the LyricsFetcher querying lrclib.net; see FetchLyrics.
+func NewFetcher() LyricsFetcher {
+	return &defaultLyricsFetcher{}
+}
+
 func (d *defaultLyricsFetcher) FetchLyrics(title, artist, album string, duration float64) (*Lyric, error) {
 	return FetchLyrics(title, artist, album, duration)
 }
@@ -74,15 +96,15 @@ func fetchAndParse(client *http.Client, apiURL string) (*Lyric, error) {
 	req.Header.Set("User-Agent", "LyricsMPRIS/1.0 (https://github.com/best8oy/LyricsMPRIS)")
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrUnreachable, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 404 || resp.StatusCode == 400 {
 		return nil, nil // Not found, let caller decide fallback
 	}
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("lrclib: unexpected status %d", resp.StatusCode)
+	if err := statusError("lrclib", resp.StatusCode); err != nil {
+		return nil, err
 	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -99,13 +121,26 @@ func fetchAndParse(client *http.Client, apiURL string) (*Lyric, error) {
 	if len(lines) == 0 {
 		return nil, errors.New("no valid lyric lines parsed")
 	}
-	return &Lyric{Lines: lines}, nil
+	return &Lyric{Lines: lines, Source: "lrclib"}, nil
+}
+
+// statusError returns the error of an HTTP status other than 200 from
+// endpoint. Server errors and rate limiting count as lrclib being
+// unreachable: trying again later may succeed.
+func statusError(endpoint string, code int) error {
+	switch {
+	case code == http.StatusOK:
+		return nil
+	case code == http.StatusTooManyRequests || code >= 500:
+		return fmt.Errorf("%w: %s status %d", ErrUnreachable, endpoint, code)
+	}
+	return fmt.Errorf("%s: unexpected status %d", endpoint, code)
 }
 
 // fetchLyricsBySearch tries to find lyrics using the search endpoint.
 func fetchLyricsBySearch(client *http.Client, title, artist string) (*Lyric, error) {
 	q := strings.TrimSpace(artist + " " + title)
-	searchURL := fmt.Sprintf("https://lrclib.net/api/search?q=%s", url.QueryEscape(q))
+	searchURL := fmt.Sprintf("%s/search?q=%s", lrclibAPI, url.QueryEscape(q))
 
 	req, err := http.NewRequest("GET", searchURL, nil)
 	if err != nil {
@@ -114,12 +149,12 @@ func fetchLyricsBySearch(client *http.Client, title, artist string) (*Lyric, err
 	req.Header.Set("User-Agent", "LyricsMPRIS/1.0 (https://github.com/best8oy/LyricsMPRIS)")
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrUnreachable, err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("lrclib search: unexpected status %d", resp.StatusCode)
+	if err := statusError("lrclib search", resp.StatusCode); err != nil {
+		return nil, err
 	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -133,14 +168,17 @@ func fetchLyricsBySearch(client *http.Client, title, artist string) (*Lyric, err
 		if apiResp.SyncedLyrics != "" {
 			lines := parseSyncedLyrics(apiResp.SyncedLyrics)
 			if len(lines) > 0 {
-				return &Lyric{Lines: lines}, nil
+				return &Lyric{Lines: lines, Source: "lrclib search"}, nil
 			}
 		}
 	}
-	return nil, errors.New("no synced lyrics found in search results")
+	return nil, fmt.Errorf("%w in search results", ErrNotFound)
 }
 
-// parseSyncedLyrics parses LRC-style synced lyrics into LyricLine slices.
+// parseSyncedLyrics parses LRC-style synced lyrics into LyricLine slices,
+// in time order whatever the order of the file. In files pairing each
+// line with its translation under the same timestamp the translations go
+// to Translation; see translated.
 func parseSyncedLyrics(synced string) []LyricLine {
 	var lines []LyricLine
 	for _, line := range strings.Split(synced, "\n") {
@@ -158,12 +196,81 @@ func parseSyncedLyrics(synced string) []LyricLine {
 		}
 		var min, sec, centi float64
 		fmt.Sscanf(timestamp, "%02f:%02f.%02f", &min, &sec, &centi)
-		timeVal := min*60 + sec + centi/100
-		lines = append(lines, LyricLine{Time: timeVal, Text: text})
+		lines = append(lines, LyricLine{Time: min*60 + sec + centi/100, Text: text})
+	}
+	if translated(lines) {
+		merged := lines[:0]
+		for _, line := range lines {
+			if n := len(merged); n > 0 && merged[n-1].Time == line.Time {
+				merged[n-1].Translation = line.Text
+				continue
+			}
+			merged = append(merged, line)
+		}
+		lines = merged
 	}
+	// stable, so lines sharing a timestamp keep the file's order
+	slices.SortStableFunc(lines, func(a, b LyricLine) int { return cmp.Compare(a.Time, b.Time) })
 	return lines
 }
 
+// translated reports whether lines, in file order, pair each line with
+// its translation: at least half the timestamps come twice in a row, and
+// none more often. Duets and repeated lines share a timestamp now and then
+// too, and stay lines of their own.
+func translated(lines []LyricLine) bool {
+	stamps, pairs := 0, 0
+	for i := 0; i < len(lines); {
+		j := i + 1
+		for j < len(lines) && lines[j].Time == lines[i].Time {
+			j++
+		}
+		switch j - i {
+		case 1:
+		case 2:
+			pairs++
+		default:
+			return false
+		}
+		stamps++
+		i = j
+	}
+	return pairs > 0 && 2*pairs >= stamps
+}
+
+// FormatTimestamp formats seconds as an LRC "[mm:ss.xx]" timestamp.
+func FormatTimestamp(sec float64) string {
+	centis := int(sec*100 + 0.5)
+	return fmt.Sprintf("[%02d:%02d.%02d]", centis/6000, centis/100%60, centis%100)
+}
+
+// FormatLRC formats lines as LRC text, one "[mm:ss.xx]text" line each.
+// Translations follow their line under the same timestamp.
+func FormatLRC(lines []LyricLine) string {
+	var b strings.Builder
+	for _, line := range lines {
+		stamp := FormatTimestamp(line.Time)
+		b.WriteString(stamp + line.Text + "\n")
+		if line.Translation != "" {
+			b.WriteString(stamp + line.Translation + "\n")
+		}
+	}
+	return b.String()
+}
+
+// FormatText formats lines as plain text, without timestamps.
+// Translations follow their line.
+func FormatText(lines []LyricLine) string {
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line.Text + "\n")
+		if line.Translation != "" {
+			b.WriteString(line.Translation + "\n")
+		}
+	}
+	return b.String()
+}
+
 // Timesynced returns true if the lyrics are time-synced (LRC style).
 func Timesynced(lines []LyricLine) bool {
 	if len(lines) < 2 {
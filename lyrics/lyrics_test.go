@@ -0,0 +1,96 @@
+package lyrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestParseSyncedLyrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		synced string
+		want   []LyricLine
+	}{
+		{
+			name:   "plain",
+			synced: "[00:01.00]one\n[00:02.50]two\n",
+			want:   []LyricLine{{Time: 1, Text: "one"}, {Time: 2.5, Text: "two"}},
+		},
+		{
+			name:   "blank and untimed lines",
+			synced: "[ar:Band]\n[00:01.00]\nnot a line\n[00:02.00]two",
+			want:   []LyricLine{{Time: 2, Text: "two"}},
+		},
+		{
+			name:   "out of order",
+			synced: "[00:03.00]three\n[00:01.00]one\n[00:02.00]two",
+			want:   []LyricLine{{Time: 1, Text: "one"}, {Time: 2, Text: "two"}, {Time: 3, Text: "three"}},
+		},
+		{
+			name:   "translations",
+			synced: "[00:01.00]eins\n[00:01.00]one\n[00:02.00]zwei\n[00:02.00]two\n[00:03.00]drei",
+			want: []LyricLine{
+				{Time: 1, Text: "eins", Translation: "one"},
+				{Time: 2, Text: "zwei", Translation: "two"},
+				{Time: 3, Text: "drei"},
+			},
+		},
+		{
+			name:   "duet sharing a timestamp",
+			synced: "[00:01.00]A: hello\n[00:02.00]B: hi\n[00:03.00]A: together\n[00:03.00]B: together\n[00:04.00]A: bye",
+			want: []LyricLine{
+				{Time: 1, Text: "A: hello"},
+				{Time: 2, Text: "B: hi"},
+				{Time: 3, Text: "A: together"},
+				{Time: 3, Text: "B: together"},
+				{Time: 4, Text: "A: bye"},
+			},
+		},
+		{
+			name:   "three voices",
+			synced: "[00:01.00]a\n[00:01.00]b\n[00:01.00]c\n[00:02.00]d\n[00:02.00]e",
+			want: []LyricLine{
+				{Time: 1, Text: "a"},
+				{Time: 1, Text: "b"},
+				{Time: 1, Text: "c"},
+				{Time: 2, Text: "d"},
+				{Time: 2, Text: "e"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSyncedLyrics(tt.synced); !slices.Equal(got, tt.want) {
+				t.Errorf("parseSyncedLyrics() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchLyricsQueriesDuration(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/get" {
+			w.Write([]byte("[]"))
+			return
+		}
+		query = r.URL.Query()
+		w.Write([]byte(`{"syncedLyrics": "[00:01.00]one"}`))
+	}))
+	defer srv.Close()
+	defer func(api string) { lrclibAPI = api }(lrclibAPI)
+	lrclibAPI = srv.URL + "/api"
+
+	if _, err := FetchLyrics("Song", "Band", "Record", 215.4); err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{"track_name": {"Song"}, "artist_name": {"Band"}, "album_name": {"Record"}, "duration": {"215"}}
+	for k, v := range want {
+		if !slices.Equal(query[k], v) {
+			t.Errorf("query %s = %q, want %q", k, query[k], v)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package lyrics
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// lastCueLength is how long the last line's cue lasts when the track's
+// length is unknown.
+const lastCueLength = 4.0
+
+// bom is the UTF-8 byte order mark some SRT players need to detect the
+// encoding.
+const bom = "\uFEFF"
+
+// ErrNotSynced is returned when subtitles are asked of lyrics without line
+// timings.
+var ErrNotSynced = errors.New("the lyrics are not synced, so they have no timings for subtitles")
+
+// FormatSRT formats synced lines as SubRip subtitles, with a byte order
+// mark first when withBOM is set. Each line is a cue lasting until the
+// next line starts; the last lasts until duration, the track's length in
+// seconds, or for a few seconds when that is 0.
+func FormatSRT(lines []LyricLine, duration float64, withBOM bool) (string, error) {
+	var b strings.Builder
+	if withBOM {
+		b.WriteString(bom)
+	}
+	err := writeCues(&b, lines, duration, ',')
+	return b.String(), err
+}
+
+// FormatVTT formats synced lines as WebVTT subtitles, timed as by
+// FormatSRT.
+func FormatVTT(lines []LyricLine, duration float64) (string, error) {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	err := writeCues(&b, lines, duration, '.')
+	return b.String(), err
+}
+
+// writeCues writes a numbered cue per non-empty line, its times' seconds
+// and milliseconds split by sep. Empty lines, which mark instrumental
+// gaps, only end the cue before them.
+func writeCues(b *strings.Builder, lines []LyricLine, duration float64, sep byte) error {
+	if !Timesynced(lines) {
+		return ErrNotSynced
+	}
+	n := 0
+	for i, line := range lines {
+		if strings.TrimSpace(line.Text) == "" {
+			continue
+		}
+		end := line.Time + lastCueLength
+		if i+1 < len(lines) {
+			end = lines[i+1].Time
+		} else if duration > line.Time {
+			end = duration
+		}
+		if end <= line.Time {
+			continue // a line timed with the next has nothing to show
+		}
+		n++
+		fmt.Fprintf(b, "%d\n%s --> %s\n", n, cueTime(line.Time, sep), cueTime(end, sep))
+		b.WriteString(line.Text + "\n")
+		if line.Translation != "" {
+			b.WriteString(line.Translation + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return nil
+}
+
+// cueTime formats sec as hh:mm:ss followed by sep and milliseconds.
+func cueTime(sec float64, sep byte) string {
+	ms := int(sec*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
@@ -0,0 +1,99 @@
+package lyrics
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFormatSRT(t *testing.T) {
+	lines := []LyricLine{{Time: 1, Text: "one"}, {Time: 2.5, Text: "two"}}
+	tests := []struct {
+		name     string
+		lines    []LyricLine
+		duration float64
+		withBOM  bool
+		want     string
+	}{
+		{
+			name:  "comma before the milliseconds",
+			lines: lines,
+			want:  "1\n00:00:01,000 --> 00:00:02,500\none\n\n2\n00:00:02,500 --> 00:00:06,500\ntwo\n\n",
+		},
+		{
+			name:    "byte order mark",
+			lines:   lines,
+			withBOM: true,
+			want:    "\uFEFF1\n00:00:01,000 --> 00:00:02,500\none\n\n2\n00:00:02,500 --> 00:00:06,500\ntwo\n\n",
+		},
+		{
+			name:     "last cue until the track's end",
+			lines:    lines,
+			duration: 200,
+			want:     "1\n00:00:01,000 --> 00:00:02,500\none\n\n2\n00:00:02,500 --> 00:03:20,000\ntwo\n\n",
+		},
+		{
+			name:     "track shorter than its last line",
+			lines:    lines,
+			duration: 2,
+			want:     "1\n00:00:01,000 --> 00:00:02,500\none\n\n2\n00:00:02,500 --> 00:00:06,500\ntwo\n\n",
+		},
+		{
+			name:  "translation as the cue's second line",
+			lines: []LyricLine{{Time: 1, Text: "eins", Translation: "one"}, {Time: 2, Text: "zwei"}},
+			want:  "1\n00:00:01,000 --> 00:00:02,000\neins\none\n\n2\n00:00:02,000 --> 00:00:06,000\nzwei\n\n",
+		},
+		{
+			name:  "instrumental gap ending the cue before it",
+			lines: []LyricLine{{Time: 1, Text: "one"}, {Time: 3, Text: " "}, {Time: 9, Text: "two"}},
+			want:  "1\n00:00:01,000 --> 00:00:03,000\none\n\n2\n00:00:09,000 --> 00:00:13,000\ntwo\n\n",
+		},
+		{
+			name:  "lines timed together",
+			lines: []LyricLine{{Time: 1, Text: "one"}, {Time: 2, Text: "two"}, {Time: 2, Text: "three"}},
+			want:  "1\n00:00:01,000 --> 00:00:02,000\none\n\n2\n00:00:02,000 --> 00:00:06,000\nthree\n\n",
+		},
+		{
+			name:  "hours and milliseconds rounded",
+			lines: []LyricLine{{Time: 3599.9996, Text: "one"}, {Time: 3723.0424, Text: "two"}},
+			want:  "1\n01:00:00,000 --> 01:02:03,042\none\n\n2\n01:02:03,042 --> 01:02:07,042\ntwo\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FormatSRT(tt.lines, tt.duration, tt.withBOM)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatVTT(t *testing.T) {
+	lines := []LyricLine{{Time: 1, Text: "eins", Translation: "one"}, {Time: 62.25, Text: "zwei"}}
+	want := "WEBVTT\n\n1\n00:00:01.000 --> 00:01:02.250\neins\none\n\n2\n00:01:02.250 --> 00:01:06.250\nzwei\n\n"
+	got, err := FormatVTT(lines, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestSubtitlesNeedTimings(t *testing.T) {
+	for name, lines := range map[string][]LyricLine{
+		"plain":    {{Text: "one"}, {Text: "two"}},
+		"one line": {{Time: 1, Text: "one"}},
+		"none":     nil,
+	} {
+		if _, err := FormatSRT(lines, 0, true); !errors.Is(err, ErrNotSynced) {
+			t.Errorf("SRT of %s lyrics: %v, want ErrNotSynced", name, err)
+		}
+		if _, err := FormatVTT(lines, 0); !errors.Is(err, ErrNotSynced) {
+			t.Errorf("VTT of %s lyrics: %v, want ErrNotSynced", name, err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+//go:build linux
+// +build linux
+
+// Package lyricsbus exports the current lyrics on the session bus, as the
+// org.LyricsMPRIS service, for desktop widgets and extensions.
+package lyricsbus
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+// The bus name, object path and interface of the service.
+const (
+	Name      = "org.LyricsMPRIS"
+	Path      = "/org/LyricsMPRIS"
+	Interface = "org.LyricsMPRIS.Lyrics"
+)
+
+// Service is the exported lyric state:
+//
+//	CurrentLine  s   the current line, empty when there is none
+//	Lines        as  the lyrics of the playing track
+//	Index        i   the current line's index in Lines, -1 for none
+//
+// with PropertiesChanged signals for all three, and LineChanged(i index,
+// s text) each time a new line becomes current.
+type Service struct {
+	conn  *dbus.Conn
+	props *prop.Properties
+}
+
+// Export connects to the session bus, claims Name and exports the service
+// with no lyrics. It fails when another process holds the name.
+func Export() (*Service, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to session bus: %w", err)
+	}
+	s := &Service{conn: conn}
+	if err := s.export(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Service) export() error {
+	props, err := prop.Export(s.conn, Path, prop.Map{
+		Interface: {
+			"CurrentLine": {Value: "", Emit: prop.EmitTrue},
+			"Lines":       {Value: []string{}, Emit: prop.EmitTrue},
+			"Index":       {Value: int32(-1), Emit: prop.EmitTrue},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	s.props = props
+	node := &introspect.Node{
+		Name: Path,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{
+				Name:       Interface,
+				Properties: props.Introspection(Interface),
+				Signals: []introspect.Signal{{
+					Name: "LineChanged",
+					Args: []introspect.Arg{
+						{Name: "index", Type: "i"},
+						{Name: "text", Type: "s"},
+					},
+				}},
+			},
+		},
+	}
+	if err := s.conn.Export(introspect.NewIntrospectable(node), Path, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return err
+	}
+	reply, err := s.conn.RequestName(Name, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return errors.New(Name + " is taken on the session bus")
+	}
+	return nil
+}
+
+// SetLines replaces the lyrics, with no line current.
+func (s *Service) SetLines(lines []string) {
+	if lines == nil {
+		lines = []string{} // nil marshals as no array at all
+	}
+	s.props.SetMust(Interface, "Lines", lines)
+	s.SetIndex(-1, "")
+}
+
+// SetIndex makes line index, with the given text, the current one; -1
+// means none.
+func (s *Service) SetIndex(index int, text string) {
+	s.props.SetMust(Interface, "Index", int32(index))
+	s.props.SetMust(Interface, "CurrentLine", text)
+}
+
+// LineChanged signals that line index became current.
+func (s *Service) LineChanged(index int, text string) error {
+	return s.conn.Emit(Path, Interface+".LineChanged", int32(index), text)
+}
+
+// Close releases the name and disconnects from the session bus.
+func (s *Service) Close() error {
+	s.conn.ReleaseName(Name)
+	return s.conn.Close()
+}
@@ -3,49 +3,385 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/best8oy/LyricsMPRIS/lyrics"
 	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/mqtt"
+	"github.com/best8oy/LyricsMPRIS/notify"
+	"github.com/best8oy/LyricsMPRIS/output"
+	"github.com/best8oy/LyricsMPRIS/pool"
 	"github.com/best8oy/LyricsMPRIS/ui"
+	"golang.org/x/term"
 )
 
+// maxLead bounds -lead: more than this is no latency but a wrong file.
+const maxLead = 5 * time.Second
+
+// maxProgressRate bounds -progress-rate, well past any display's frame rate.
+const maxProgressRate = 100
+
 // Config holds application settings.
 type Config struct {
 	displayMode    string
 	pollIntervalMs int
+	ui             ui.Options
 }
 
 func main() {
 	pipe := flag.Bool("pipe", false, "Pipe current lyric line to stdout (default is modern UI)")
+	query := flag.Bool("query", false, "Print the current line of an instance running with -listen-unix on the same socket (default $XDG_RUNTIME_DIR/lyricsmpris.sock) and exit; prints nothing within 100ms when none answers, e.g. for #(lyricsmpris -query) in tmux status-right")
+	subtitles := flag.String("subtitles", "", "Write srt or vtt subtitles, a cue per line, with -once and the s key instead of lyrics (synced lyrics only)")
+	subtitlesBOM := flag.Bool("subtitles-bom", false, "Start -subtitles srt files with a UTF-8 byte order mark")
+	once := flag.Bool("once", false, "Print the playing track's whole lyrics and exit (LRC with -timestamps, JSON with -output json)")
+	notifyMode := flag.Bool("notify", false, "Show the lyrics as desktop notifications instead of in the terminal")
+	notifyOn := flag.String("notify-on", ui.NotifyLine, "What -notify shows: line for every lyric line, or track for track changes with the first lines")
+	notifyUrgency := flag.String("notify-urgency", notify.UrgencyNormal, "Urgency of -notify notifications: low, normal or critical")
+	notifyTimeout := flag.Duration("notify-timeout", 0, "How long -notify notifications stay up (0 for the notification server's default)")
+	a11y := flag.Bool("a11y", false, "Screen reader friendly output: plain lines with track, pause and lyric state announcements")
+	outputFormat := flag.String("output", ui.OutputText, "Pipe mode output: text, json for one JSON event per line, waybar for a Waybar custom module, polybar, i3blocks, lemonbar or dzen2 (all but text imply -pipe); conky prints lines for ${execpi} once")
+	conkyContext := flag.Int("conky-context", 1, "Lines -output conky shows before and after the current one")
+	conkyCurrent := flag.String("conky-current", ui.DefaultConkyCurrent, "Conky color variable of the current line in -output conky")
+	conkyOther := flag.String("conky-other", ui.DefaultConkyOther, "Conky color variable of the other lines in -output conky")
+	maxWidth := flag.Int("max-width", 0, "Cut pipe mode lines to this many cells, after -format; the short text for -output i3blocks, json is never cut (0 for no limit)")
+	ellipsis := flag.String("ellipsis", "…", "Ends lines cut to -max-width")
+	outputFile := flag.String("output-file", "", "Keep this file holding the current line (empty while paused), e.g. for an OBS text source")
+	outputFileFormat := flag.String("output-file-format", "", "Template for the -output-file content, e.g. '{{.Line}} · {{.Next}}' (fields as for -format)")
+	stateFile := flag.String("state-file", "", "Keep this file holding the whole state as one JSON document (track, status, position, all lines and the current index), e.g. for eww; removed on exit")
+	fifo := flag.String("fifo", "", "Also write the pipe mode stream (in the -output format) to this named pipe, created if missing; lines are dropped while nothing reads it")
+	listenUnix := flag.String("listen-unix", "", "Stream JSON events to clients of a Unix socket at this path, each starting with a snapshot of the lyrics (and answer -query there; $XDG_RUNTIME_DIR/lyricsmpris.sock is where it looks by default)")
+	listenHTTP := flag.String("listen-http", "", "Serve a browser overlay and its events at this address, e.g. :8277 (localhost only unless a host is given)")
+	httpToken := flag.String("http-token", "", "Token the -listen-http WebSocket needs as its token query parameter, and that lets it control the player; without one it only streams events to local same-origin pages")
+	dbusExport := flag.Bool("dbus-export", false, "Export the current lyrics on the session bus as org.LyricsMPRIS")
+	trayIcon := flag.Bool("tray", false, "Show a tray icon with the current line as its tooltip (click to play/pause; menu for next, previous and copy line); nothing happens on desktops without a StatusNotifierItem tray")
+	discordPresence := flag.String("discord-presence", "", "Show the track and current line as your Discord status, through the Discord application with this ID (create one at discord.com/developers; its name shows as the activity name)")
+	progressRate := flag.Float64("progress-rate", ui.DefaultProgressRate, "Progress events a second, with how far into the current line playback is, in -output json and the -listen-unix and -listen-http streams (0 for none)")
+	emitEarly := flag.Duration("emit-early", 0, "Send lines to pipe mode and the outputs (-fifo, sockets, MQTT, ...) this much early for slow displays, on top of -lead: a line goes out at its time - lead - emit-early, but not before the previous line's time - lead; the modern UI stays exact (at most 5s)")
+	fetchDebounce := flag.Duration("fetch-debounce", pool.DefaultDebounce, "Fetch a new track's lyrics only once it has been current this long, so skipping through tracks fetches just the last one (0 fetches at once)")
+	lead := flag.Duration("lead", 0, "Show lines this much early to make up for audio latency, e.g. 300ms; negative delays them (at most ±5s)")
+	verbose := flag.Bool("verbose", false, "Log diagnostics to stderr")
+	trackMarker := flag.Bool("track-marker", false, "With -pipe, print an \"== Artist – Title ==\" line at each track change")
+	onPause := flag.String("on-pause", "keep", "What pipe mode and the structured outputs show while paused: keep the line, blank, or text:PLACEHOLDER")
+	overwrite := flag.Bool("overwrite", false, "With -pipe, rewrite a single terminal line instead of printing one per lyric")
+	barColor := flag.String("bar-color", "", "Color of the current line in -output lemonbar and dzen2, e.g. #ffffff (empty for the bar's own)")
+	barNext := flag.Bool("bar-next", false, "Add the next line after the current one in -output lemonbar and dzen2")
+	barNextColor := flag.String("bar-next-color", "#888888", "Color of the -bar-next line")
+	barDivider := flag.String("bar-divider", " · ", "Goes between the current line and the -bar-next line, written as it is (so bar markup works)")
+	padding := flag.String("padding", "", "Text around the line in -output polybar")
+	prefix := flag.String("prefix", "", "Text before the line in -output polybar, e.g. an icon")
+	suffix := flag.String("suffix", "", "Text after the line in -output polybar")
+	format := flag.String("format", "", "Template for each line in pipe mode, e.g. '{{.Artist}} – {{.Line}}' (fields: Line, Translation, Next, Artist, Title, Album, Elapsed, Index)")
 	pollMs := flag.Int("poll", 2000, "Lyric poll interval in milliseconds")
+	noProgress := flag.Bool("no-progress", false, "Hide the track progress bar in the modern UI")
+	noHeader := flag.Bool("no-header", false, "Hide the track header row in the modern UI")
+	noAnimation := flag.Bool("no-animation", false, "Disable the scroll animation in the modern UI")
+	pulse := flag.Bool("pulse", false, "Briefly brighten each new current line (needs 256 colors; off with -no-animation)")
+	mouse := flag.Bool("mouse", false, "Enable mouse wheel scrolling in the modern UI (interferes with text selection)")
+	pausedText := flag.String("paused-text", "⏸ paused", "Marker shown over the lyrics while playback is paused (empty to disable)")
+	fadePast := flag.String("fade-past", "60,50,40,30", "Brightness percentages fading the lines before the current one (empty to disable)")
+	fadeFuture := flag.String("fade-future", "85,70,55,45", "Brightness percentages fading the lines after the current one (empty to disable)")
+	valign := flag.String("valign", ui.VAlignCenter, "Vertical placement of the current line in the modern UI: top, center or bottom")
+	countdown := flag.String("countdown", ui.CountdownSeconds, "Countdown shown during intros and instrumental gaps: seconds, dots or off")
+	countdownThreshold := flag.Float64("countdown-threshold", ui.DefaultCountdownThreshold, "Seconds of silence after a line that count as an instrumental gap")
+	noOSC52 := flag.Bool("no-osc52", false, "Copy lyrics with wl-copy or xclip instead of OSC 52 terminal escapes")
+	translation := flag.String("translation", ui.TranslationStacked, "Line translations, where available: off, only or stacked (in pipe mode, stacked prints both)")
+	status := flag.Bool("status", false, "Show the lyric source, sync quality and offset in the modern UI")
+	setTitle := flag.Bool("set-title", false, "Show the artist and current line in the terminal title (modern UI only)")
+	layout := flag.String("mode", ui.LayoutWindow, "Layout of the modern UI: window, minimal or two-line")
+	noTime := flag.Bool("no-time", false, "Hide the elapsed and total time beside the progress bar in the modern UI")
+	overflow := flag.String("overflow", ui.OverflowWrap, "Lines wider than the terminal: wrap, ellipsis or marquee (the current line scrolls)")
+	marqueeSpeed := flag.Float64("marquee-speed", ui.DefaultMarqueeSpeed, "Marquee scroll speed in cells per second")
+	stylePast := flag.String("style-past", "faint,italic", "Attributes of the lines before the current one: bold, faint, italic, underline, reverse, strikethrough")
+	styleCurrent := flag.String("style-current", "bold", "Attributes of the current line")
+	styleFuture := flag.String("style-future", "", "Attributes of the lines after the current one")
+	art := flag.String("art", ui.ArtOff, "Cover art beside or above the lyrics: off, kitty (kitty graphics protocol, for kitty and Ghostty), mosaic (half blocks, any terminal) or auto (kitty where the terminal is known to support it, mosaic elsewhere)")
+	artSize := flag.Int("art-size", ui.DefaultArtSize, "Cover art width in terminal cells")
+	timestamps := flag.Bool("timestamps", false, "Show each line's timestamp in the modern UI, and with -once")
+	saveDir := flag.String("save-dir", "", "Directory the s key saves lyrics to (default $XDG_DATA_HOME/lyricsmpris/saved)")
+	idleTimeout := flag.Duration("idle-timeout", ui.DefaultIdleTimeout, "Dim the modern UI after playback stays paused this long (0 to never dim)")
+	blankOnPause := flag.Bool("blank-on-pause", false, "Blank the modern UI entirely instead of dimming it after -idle-timeout")
+	background := flag.String("background", ui.BackgroundColor, "Backgrounds in the modern UI: color, or none to never paint one (for transparent terminals)")
+	flag.Usage = usage
 	flag.Parse()
 
 	cfg := Config{
 		displayMode:    "modern",
 		pollIntervalMs: *pollMs,
+		ui: ui.Options{
+			NoProgress:         *noProgress,
+			NoHeader:           *noHeader,
+			PausedText:         *pausedText,
+			Mouse:              *mouse,
+			NoAnimation:        *noAnimation,
+			Pulse:              *pulse,
+			VAlign:             *valign,
+			Countdown:          *countdown,
+			CountdownThreshold: *countdownThreshold,
+			NoOSC52:            *noOSC52,
+			Translation:        *translation,
+			Status:             *status,
+			SetTitle:           *setTitle,
+			Layout:             *layout,
+			NoTime:             *noTime,
+			Overflow:           *overflow,
+			MarqueeSpeed:       *marqueeSpeed,
+			Art:                *art,
+			ArtSize:            *artSize,
+			Timestamps:         *timestamps,
+			SaveDir:            *saveDir,
+			Subtitles:          *subtitles,
+			SubtitlesBOM:       *subtitlesBOM,
+			IdleTimeout:        *idleTimeout,
+			BlankOnPause:       *blankOnPause,
+			Output:             *outputFormat,
+			MaxWidth:           *maxWidth,
+			ConkyContext:       *conkyContext,
+			ConkyCurrent:       *conkyCurrent,
+			ConkyOther:         *conkyOther,
+			Ellipsis:           *ellipsis,
+			Overwrite:          *overwrite,
+			TrackMarker:        *trackMarker,
+			Bar:                output.BarStyle{Current: *barColor, Next: *barNextColor, ShowNext: *barNext, Divider: *barDivider},
+			Padding:            *padding,
+			Prefix:             *prefix,
+			Suffix:             *suffix,
+			Verbose:            *verbose,
+			Lead:               *lead,
+			NotifyOn:           *notifyOn,
+			NotifyUrgency:      *notifyUrgency,
+			NotifyTimeout:      *notifyTimeout,
+			OutputFile:         *outputFile,
+			StateFile:          *stateFile,
+			FIFO:               *fifo,
+			ListenUnix:         *listenUnix,
+			ListenHTTP:         *listenHTTP,
+			HTTPToken:          *httpToken,
+			DBusExport:         *dbusExport,
+			Tray:               *trayIcon,
+			DiscordPresence:    *discordPresence,
+			ProgressRate:       *progressRate,
+			EmitEarly:          *emitEarly,
+			FetchDebounce:      *fetchDebounce,
+			Player:             mpris.NewClient(),
+			Lyrics:             lyrics.NewFetcher(),
+		},
+	}
+	switch {
+	case *query:
+		cfg.displayMode = "query"
+	case *once:
+		cfg.displayMode = "once"
+	case cfg.ui.Output == ui.OutputConky:
+		cfg.displayMode = "conky" // a one-shot like -once
+	case *a11y:
+		cfg.displayMode = "a11y"
+	case *notifyMode:
+		cfg.displayMode = "notify"
+	case *pipe, cfg.ui.Output != ui.OutputText:
+		cfg.displayMode = "pipe" // the structured outputs are pipe mode formats
+	}
+	switch cfg.ui.VAlign {
+	case ui.VAlignTop, ui.VAlignCenter, ui.VAlignBottom:
+	default:
+		fatal(fmt.Errorf("-valign: unknown alignment %q", cfg.ui.VAlign))
+	}
+	switch cfg.ui.Layout {
+	case ui.LayoutWindow, ui.LayoutMinimal, ui.LayoutTwoLine:
+	default:
+		fatal(fmt.Errorf("-mode: unknown layout %q", cfg.ui.Layout))
+	}
+	switch cfg.ui.Overflow {
+	case ui.OverflowWrap, ui.OverflowEllipsis, ui.OverflowMarquee:
+	default:
+		fatal(fmt.Errorf("-overflow: unknown mode %q", cfg.ui.Overflow))
+	}
+	switch cfg.ui.Art {
+	case ui.ArtOff, ui.ArtAuto, ui.ArtKitty, ui.ArtMosaic:
+	default:
+		fatal(fmt.Errorf("-art: unknown mode %q", cfg.ui.Art))
+	}
+	switch cfg.ui.Countdown {
+	case ui.CountdownSeconds, ui.CountdownDots, ui.CountdownOff:
+	default:
+		fatal(fmt.Errorf("-countdown: unknown style %q", cfg.ui.Countdown))
+	}
+	if cfg.ui.Lead < -maxLead || cfg.ui.Lead > maxLead {
+		fatal(fmt.Errorf("-lead: %v is out of range: want at most ±%v", cfg.ui.Lead, maxLead))
+	}
+	if cfg.ui.EmitEarly < 0 || cfg.ui.EmitEarly > maxLead {
+		fatal(fmt.Errorf("-emit-early: %v is out of range: want 0 to %v", cfg.ui.EmitEarly, maxLead))
+	}
+	if id := cfg.ui.DiscordPresence; id != "" && strings.Trim(id, "0123456789") != "" {
+		fatal(fmt.Errorf("-discord-presence: %q is no application ID, which is a number", id))
+	}
+	if cfg.ui.FetchDebounce < 0 {
+		fatal(fmt.Errorf("-fetch-debounce: %v is negative", cfg.ui.FetchDebounce))
+	}
+	if cfg.ui.ProgressRate < 0 || cfg.ui.ProgressRate > maxProgressRate {
+		fatal(fmt.Errorf("-progress-rate: %v is out of range: want 0 to %d", cfg.ui.ProgressRate, maxProgressRate))
+	}
+	switch cfg.ui.Subtitles {
+	case "", ui.SubtitlesSRT, ui.SubtitlesVTT:
+	default:
+		fatal(fmt.Errorf("-subtitles: unknown format %q: want srt or vtt", cfg.ui.Subtitles))
+	}
+	switch cfg.ui.NotifyOn {
+	case ui.NotifyLine, ui.NotifyTrack:
+	default:
+		fatal(fmt.Errorf("-notify-on: unknown value %q", cfg.ui.NotifyOn))
+	}
+	switch cfg.ui.NotifyUrgency {
+	case notify.UrgencyLow, notify.UrgencyNormal, notify.UrgencyCritical:
+	default:
+		fatal(fmt.Errorf("-notify-urgency: unknown urgency %q", cfg.ui.NotifyUrgency))
+	}
+	switch cfg.ui.Output {
+	case ui.OutputText, ui.OutputJSON, ui.OutputWaybar, ui.OutputPolybar, ui.OutputI3Blocks, ui.OutputLemonbar, ui.OutputDzen2, ui.OutputConky:
+	default:
+		fatal(fmt.Errorf("-output: unknown format %q", cfg.ui.Output))
+	}
+	switch cfg.ui.Translation {
+	case ui.TranslationOff, ui.TranslationOnly, ui.TranslationStacked:
+	default:
+		fatal(fmt.Errorf("-translation: unknown mode %q", cfg.ui.Translation))
+	}
+	switch *background {
+	case ui.BackgroundColor, ui.BackgroundNone:
+		cfg.ui.Theme.Background = *background
+	default:
+		fatal(fmt.Errorf("-background: unknown mode %q", *background))
+	}
+	var err error
+	if cfg.ui.Theme.PastFade, err = ui.ParseFade(*fadePast); err != nil {
+		fatal(fmt.Errorf("-fade-past: %w", err))
+	}
+	if cfg.ui.Theme.FutureFade, err = ui.ParseFade(*fadeFuture); err != nil {
+		fatal(fmt.Errorf("-fade-future: %w", err))
+	}
+	if cfg.ui.Theme.Past, err = ui.ParseAttrs(*stylePast); err != nil {
+		fatal(fmt.Errorf("-style-past: %w", err))
+	}
+	if cfg.ui.Theme.Current, err = ui.ParseAttrs(*styleCurrent); err != nil {
+		fatal(fmt.Errorf("-style-current: %w", err))
 	}
-	if *pipe {
-		cfg.displayMode = "pipe"
+	if cfg.ui.Theme.Future, err = ui.ParseAttrs(*styleFuture); err != nil {
+		fatal(fmt.Errorf("-style-future: %w", err))
+	}
+
+	if *format != "" {
+		if cfg.ui.Format, err = ui.ParseFormat(*format); err != nil {
+			fatal(fmt.Errorf("-format: %w", err))
+		}
+	}
+
+	if cfg.ui.PauseText, err = ui.ParseOnPause(*onPause); err != nil {
+		fatal(fmt.Errorf("-on-pause: %w", err))
+	}
+	if *outputFileFormat != "" {
+		if cfg.ui.OutputFileFormat, err = ui.ParseFormat(*outputFileFormat); err != nil {
+			fatal(fmt.Errorf("-output-file-format: %w", err))
+		}
+	}
+
+	if cfg.ui.MQTT, err = loadMQTTConfig(); err != nil {
+		fatal(fmt.Errorf("mqtt: %w", err))
 	}
 
 	pollInterval := time.Duration(cfg.pollIntervalMs) * time.Millisecond
 
-	ctx := context.Background()
-	// Always start the UI, even if no song is playing yet
-	meta := &mpris.TrackMetadata{}
-	pos := 0.0
-	// Try to get current metadata/position, but ignore errors and let UI handle waiting
-	if m, _, err := mpris.GetMetadata(ctx); err == nil && m != nil {
-		meta = m
+	// a signal ends the display normally, so a -fifo node is removed.
+	// SIGHUP does too on a terminal, which is going away; without one,
+	// e.g. started by a status bar, it rewrites the output files instead.
+	signals := []os.Signal{os.Interrupt, syscall.SIGTERM}
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		signals = append(signals, syscall.SIGHUP)
+	} else {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		cfg.ui.Rewrite = hup
 	}
-	if p, _, err := mpris.GetPositionAndStatus(ctx); err == nil {
-		pos = p
+	ctx, stop := signal.NotifyContext(context.Background(), signals...)
+	defer stop()
+	// Always start the UI, even if no player is running yet: the UI waits
+	// for one and follows whatever it plays.
+	if err := ui.DisplayLyricsContext(ctx, cfg.displayMode, pollInterval, cfg.ui); err != nil {
+		stop()
+		fmt.Fprintln(os.Stderr, "lyricsmpris:", err)
+		os.Exit(1)
 	}
+}
+
+// barExamples are the status bar and MQTT configurations shown in the usage.
+const barExamples = `
+Polybar module example:
+
+  [module/lyrics]
+  type = custom/script
+  exec = lyricsmpris -output polybar -max-width 60 -prefix "♪ "
+  tail = true
+
+i3blocks block example (left click play/pause, right click next, scroll seeks):
 
-	if cfg.displayMode == "pipe" {
-		ui.DisplayLyricsContext(ctx, "pipe", *meta, pos, pollInterval)
-		return
+  [lyrics]
+  command=lyricsmpris -output i3blocks -max-width 30
+  interval=persist
+  format=json
+
+lemonbar and dzen2 read the lines from a pipe:
+
+  lyricsmpris -output lemonbar -bar-color "#ffffff" -bar-next | lemonbar -p
+  lyricsmpris -output dzen2 -max-width 50 | dzen2 -p
+
+eww reads the -state-file document, e.g. written to
+$XDG_RUNTIME_DIR/lyrics.json:
+
+  (defpoll lyrics :interval "500ms" :initial "{}"
+    "cat $XDG_RUNTIME_DIR/lyrics.json 2>/dev/null || echo {}")
+  (label :text {lyrics.line ?: ""})
+
+MQTT publishing is set up in ~/.config/lyricsmpris/mqtt.json rather than
+with flags, keeping the password private. The track goes to PREFIX/track
+(retained JSON) and each line to PREFIX/line; an empty line clears the
+display, also when lyricsmpris dies:
+
+  {"broker": "tcp://localhost:1883", "prefix": "lyricsmpris",
+   "username": "lyrics", "password": "secret"}
+`
+
+// loadMQTTConfig reads the MQTT broker settings from mqtt.json in the
+// user's config directory; there are no flags for them, so the password
+// stays off the process list. It returns nil when there is no such file.
+func loadMQTTConfig() (*mqtt.Config, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, nil
+	}
+	path := filepath.Join(dir, "lyricsmpris", "mqtt.json")
+	cfg, err := mqtt.LoadConfig(path)
+	if cfg != nil && cfg.Password != "" {
+		if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0o077 != 0 {
+			fmt.Fprintf(os.Stderr, "lyricsmpris: %s holds a password but others can read it\n", path)
+		}
 	}
+	return cfg, err
+}
+
+// usage prints the flags, followed by the status bar examples.
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
+	flag.PrintDefaults()
+	fmt.Fprint(out, barExamples)
+}
 
-	ui.DisplayLyricsContext(ctx, "modern", *meta, pos, pollInterval)
+// fatal reports a startup error and exits.
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "lyricsmpris:", err)
+	os.Exit(2)
 }
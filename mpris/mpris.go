@@ -15,18 +15,57 @@ import (
 	"github.com/godbus/dbus/v5"
 )
 
+// ErrNoPlayer is returned when no MPRIS player is available on the session bus.
+var ErrNoPlayer = errors.New("no MPRIS player available")
+
+// ErrCannotSeek is returned when the active player does not support seeking.
+var ErrCannotSeek = errors.New("player can't seek")
+
+// ErrCannotGoNext and ErrCannotGoPrevious are returned when the active
+// player can't skip tracks in that direction.
+var (
+	ErrCannotGoNext     = errors.New("player can't skip to the next track")
+	ErrCannotGoPrevious = errors.New("player can't skip to the previous track")
+)
+
+// ErrCannotPause is returned when the active player can't pause playback.
+var ErrCannotPause = errors.New("player can't pause")
+
+// Capabilities reports which controls the active player supports.
+type Capabilities struct {
+	CanGoNext     bool
+	CanGoPrevious bool
+	CanPause      bool
+	CanSeek       bool
+}
+
 // TrackMetadata holds basic song info
 type TrackMetadata struct {
 	Title  string
 	Artist string
 	Album  string
+	// Player is the player's human-readable Identity, if it reports one.
+	Player string
+	// ArtURL is the location of the cover art (mpris:artUrl), if any.
+	ArtURL string
+	// Bus is the bus name of the player playerctld made active, e.g.
+	// org.mpris.MediaPlayer2.spotify, if it reports it.
+	Bus string
 }
 
 // MPRISClient defines an interface for MPRIS metadata and event handling.
 type MPRISClient interface {
 	GetMetadata(ctx context.Context) (*TrackMetadata, float64, error)
 	GetPositionAndStatus(ctx context.Context) (float64, string, error)
+	GetRate(ctx context.Context) (float64, error)
+	Watch(ctx context.Context, changed chan<- struct{}) error
 	WatchAndHandleEvents(ctx context.Context, onTrackChange func(meta TrackMetadata, pos float64), onSeek func(meta TrackMetadata, pos float64)) error
+	PlayPause(ctx context.Context) error
+	Next(ctx context.Context) error
+	Previous(ctx context.Context) error
+	SetPosition(ctx context.Context, pos float64) error
+	Seek(ctx context.Context, offset float64) error
+	GetCapabilities(ctx context.Context) (Capabilities, error)
 }
 
 // Ensure default implementation matches MPRISClient
@@ -34,15 +73,45 @@ var _ MPRISClient = (*defaultMPRISClient)(nil)
 
 type defaultMPRISClient struct{}
 
+// NewClient returns the MPRISClient of the player playerctld makes active
+// on the session bus.
+func NewClient() MPRISClient {
+	return &defaultMPRISClient{}
+}
+
 func (d *defaultMPRISClient) GetMetadata(ctx context.Context) (*TrackMetadata, float64, error) {
 	return GetMetadata(ctx)
 }
 func (d *defaultMPRISClient) GetPositionAndStatus(ctx context.Context) (float64, string, error) {
 	return GetPositionAndStatus(ctx)
 }
+func (d *defaultMPRISClient) GetRate(ctx context.Context) (float64, error) {
+	return GetRate(ctx)
+}
+func (d *defaultMPRISClient) Watch(ctx context.Context, changed chan<- struct{}) error {
+	return Watch(ctx, changed)
+}
 func (d *defaultMPRISClient) WatchAndHandleEvents(ctx context.Context, onTrackChange func(meta TrackMetadata, pos float64), onSeek func(meta TrackMetadata, pos float64)) error {
 	return WatchAndHandleEvents(ctx, onTrackChange, onSeek)
 }
+func (d *defaultMPRISClient) PlayPause(ctx context.Context) error {
+	return PlayPause(ctx)
+}
+func (d *defaultMPRISClient) Next(ctx context.Context) error {
+	return Next(ctx)
+}
+func (d *defaultMPRISClient) Previous(ctx context.Context) error {
+	return Previous(ctx)
+}
+func (d *defaultMPRISClient) SetPosition(ctx context.Context, pos float64) error {
+	return SetPosition(ctx, pos)
+}
+func (d *defaultMPRISClient) Seek(ctx context.Context, offset float64) error {
+	return Seek(ctx, offset)
+}
+func (d *defaultMPRISClient) GetCapabilities(ctx context.Context) (Capabilities, error) {
+	return GetCapabilities(ctx)
+}
 
 // ListPlayers returns all available MPRIS player names for diagnostics.
 func ListPlayers() ([]string, error) {
@@ -65,7 +134,7 @@ func ListPlayers() ([]string, error) {
 	return players, nil
 }
 
-// getActivePlayer returns only playerctld if available, otherwise error.
+// getActivePlayer returns only playerctld if available, otherwise ErrNoPlayer.
 func getActivePlayer(conn *dbus.Conn) (string, error) {
 	var names []string
 	err := conn.BusObject().Call("org.freedesktop.DBus.ListNames", 0).Store(&names)
@@ -77,7 +146,16 @@ func getActivePlayer(conn *dbus.Conn) (string, error) {
 			return name, nil
 		}
 	}
-	return "", errors.New("playerctld (org.mpris.MediaPlayer2.playerctld) not found on the session bus")
+	return "", fmt.Errorf("playerctld (org.mpris.MediaPlayer2.playerctld) not found on the session bus: %w", ErrNoPlayer)
+}
+
+// playerError maps playerctld's "no active player" D-Bus error to ErrNoPlayer.
+func playerError(err error) error {
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) && strings.HasSuffix(dbusErr.Name, ".NoActivePlayer") {
+		return ErrNoPlayer
+	}
+	return err
 }
 
 // GetMetadata fetches metadata from the first available MPRIS player.
@@ -96,7 +174,7 @@ func GetMetadata(ctx context.Context) (*TrackMetadata, float64, error) {
 	obj := conn.Object(playerName, "/org/mpris/MediaPlayer2")
 	variant, err := obj.GetProperty("org.mpris.MediaPlayer2.Player.Metadata")
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get metadata property: %w", err)
+		return nil, 0, fmt.Errorf("failed to get metadata property: %w", playerError(err))
 	}
 	metadata, ok := variant.Value().(map[string]dbus.Variant)
 	if !ok {
@@ -115,8 +193,16 @@ func GetMetadata(ctx context.Context) (*TrackMetadata, float64, error) {
 	album := getString(metadata, "xesam:album")
 	lengthMicros := getUint64(metadata, "mpris:length")
 	duration := float64(lengthMicros) / 1e6 // microseconds to seconds
-	if title != "" && artist != "" && album != "" && duration > 0 {
-		return &TrackMetadata{Title: title, Artist: artist, Album: album}, duration, nil
+	// Album and duration are optional: streams and some players omit them.
+	if title != "" && artist != "" {
+		return &TrackMetadata{
+			Title:  title,
+			Artist: artist,
+			Album:  album,
+			Player: getIdentity(obj),
+			ArtURL: getString(metadata, "mpris:artUrl"),
+			Bus:    getActiveBus(obj),
+		}, duration, nil
 	}
 	// If metadata is incomplete, return empty TrackMetadata and 0 duration, no error
 	return &TrackMetadata{}, 0, nil
@@ -138,7 +224,7 @@ func GetPositionAndStatus(ctx context.Context) (float64, string, error) {
 	obj := conn.Object(playerName, "/org/mpris/MediaPlayer2")
 	posVar, err := obj.GetProperty("org.mpris.MediaPlayer2.Player.Position")
 	if err != nil {
-		return 0, "", fmt.Errorf("failed to get position property: %w", err)
+		return 0, "", fmt.Errorf("failed to get position property: %w", playerError(err))
 	}
 	pos, ok := posVar.Value().(int64)
 	if !ok {
@@ -146,7 +232,7 @@ func GetPositionAndStatus(ctx context.Context) (float64, string, error) {
 	}
 	statusVar, err := obj.GetProperty("org.mpris.MediaPlayer2.Player.PlaybackStatus")
 	if err != nil {
-		return 0, "", fmt.Errorf("failed to get playback status property: %w", err)
+		return 0, "", fmt.Errorf("failed to get playback status property: %w", playerError(err))
 	}
 	status, ok := statusVar.Value().(string)
 	if !ok {
@@ -155,6 +241,163 @@ func GetPositionAndStatus(ctx context.Context) (float64, string, error) {
 	return float64(pos) / 1e6, status, nil
 }
 
+// GetRate fetches the playback rate, 1 for normal speed. Players without
+// a Rate property count as 1.
+func GetRate(ctx context.Context) (float64, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to session bus: %w", err)
+	}
+	defer conn.Close()
+
+	playerName, err := getActivePlayer(conn)
+	if err != nil {
+		return 0, err
+	}
+
+	obj := conn.Object(playerName, "/org/mpris/MediaPlayer2")
+	rateVar, err := obj.GetProperty("org.mpris.MediaPlayer2.Player.Rate")
+	if err != nil {
+		return 1, nil
+	}
+	if rate, ok := rateVar.Value().(float64); ok && rate > 0 {
+		return rate, nil
+	}
+	return 1, nil
+}
+
+// PlayPause toggles playback on the active player.
+// It returns ErrCannotPause if the player reports CanPause=false.
+func PlayPause(ctx context.Context) error {
+	return callPlayer(ctx, "CanPause", ErrCannotPause, "PlayPause")
+}
+
+// Next skips to the next track on the active player.
+// It returns ErrCannotGoNext if the player reports CanGoNext=false.
+func Next(ctx context.Context) error {
+	return callPlayer(ctx, "CanGoNext", ErrCannotGoNext, "Next")
+}
+
+// Previous skips to the previous track on the active player.
+// It returns ErrCannotGoPrevious if the player reports CanGoPrevious=false.
+func Previous(ctx context.Context) error {
+	return callPlayer(ctx, "CanGoPrevious", ErrCannotGoPrevious, "Previous")
+}
+
+// Seek moves the active player's position by offset seconds, which may be negative.
+// It returns ErrCannotSeek if the player reports CanSeek=false.
+func Seek(ctx context.Context, offset float64) error {
+	return callPlayer(ctx, "CanSeek", ErrCannotSeek, "Seek", int64(offset*1e6))
+}
+
+// GetCapabilities reads the Can* properties of the active player.
+func GetCapabilities(ctx context.Context) (Capabilities, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("failed to connect to session bus: %w", err)
+	}
+	defer conn.Close()
+
+	playerName, err := getActivePlayer(conn)
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	obj := conn.Object(playerName, "/org/mpris/MediaPlayer2")
+	var caps Capabilities
+	for name, dst := range map[string]*bool{
+		"CanGoNext":     &caps.CanGoNext,
+		"CanGoPrevious": &caps.CanGoPrevious,
+		"CanPause":      &caps.CanPause,
+		"CanSeek":       &caps.CanSeek,
+	} {
+		v, err := obj.GetProperty("org.mpris.MediaPlayer2.Player." + name)
+		if err != nil {
+			return Capabilities{}, fmt.Errorf("failed to get %s property: %w", name, playerError(err))
+		}
+		*dst, _ = v.Value().(bool)
+	}
+	return caps, nil
+}
+
+// SetPosition seeks the active player to pos seconds into the current track.
+// It returns ErrCannotSeek if the player reports CanSeek=false.
+func SetPosition(ctx context.Context, pos float64) error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to session bus: %w", err)
+	}
+	defer conn.Close()
+
+	playerName, err := getActivePlayer(conn)
+	if err != nil {
+		return err
+	}
+
+	obj := conn.Object(playerName, "/org/mpris/MediaPlayer2")
+	canSeekVar, err := obj.GetProperty("org.mpris.MediaPlayer2.Player.CanSeek")
+	if err != nil {
+		return fmt.Errorf("failed to get can seek property: %w", playerError(err))
+	}
+	if canSeek, ok := canSeekVar.Value().(bool); !ok || !canSeek {
+		return ErrCannotSeek
+	}
+	variant, err := obj.GetProperty("org.mpris.MediaPlayer2.Player.Metadata")
+	if err != nil {
+		return fmt.Errorf("failed to get metadata property: %w", playerError(err))
+	}
+	metadata, ok := variant.Value().(map[string]dbus.Variant)
+	if !ok {
+		return fmt.Errorf("metadata type assertion failed for %s", playerName)
+	}
+	var trackID dbus.ObjectPath
+	switch id := metadata["mpris:trackid"].Value().(type) {
+	case dbus.ObjectPath:
+		trackID = id
+	case string:
+		trackID = dbus.ObjectPath(id)
+	default:
+		return fmt.Errorf("no track id in metadata for %s", playerName)
+	}
+	if pos < 0 {
+		pos = 0
+	}
+	err = obj.CallWithContext(ctx, "org.mpris.MediaPlayer2.Player.SetPosition", 0, trackID, int64(pos*1e6)).Err
+	if err != nil {
+		return fmt.Errorf("failed to call SetPosition: %w", playerError(err))
+	}
+	return nil
+}
+
+// callPlayer invokes a method of the org.mpris.MediaPlayer2.Player interface
+// on the active player, returning unsupported instead if the player reports
+// the given capability property as false.
+func callPlayer(ctx context.Context, capability string, unsupported error, method string, args ...interface{}) error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to session bus: %w", err)
+	}
+	defer conn.Close()
+
+	playerName, err := getActivePlayer(conn)
+	if err != nil {
+		return err
+	}
+
+	obj := conn.Object(playerName, "/org/mpris/MediaPlayer2")
+	v, err := obj.GetProperty("org.mpris.MediaPlayer2.Player." + capability)
+	if err != nil {
+		return fmt.Errorf("failed to get %s property: %w", capability, playerError(err))
+	}
+	if can, ok := v.Value().(bool); !ok || !can {
+		return unsupported
+	}
+	if err := obj.CallWithContext(ctx, "org.mpris.MediaPlayer2.Player."+method, 0, args...).Err; err != nil {
+		return fmt.Errorf("failed to call %s: %w", method, playerError(err))
+	}
+	return nil
+}
+
 // WatchAndHandleEvents listens for MPRIS property changes and invokes the callback on track/position changes.
 func WatchAndHandleEvents(ctx context.Context, onTrackChange func(meta TrackMetadata, pos float64), onSeek func(meta TrackMetadata, pos float64)) error {
 	conn, err := dbus.ConnectSessionBus()
@@ -216,6 +459,111 @@ func WatchAndHandleEvents(ctx context.Context, onTrackChange func(meta TrackMeta
 	}
 }
 
+// Watch sends on changed whenever the active player reports a change:
+// of track, playback status or rate through PropertiesChanged, a seek
+// through Seeked, or playerctld coming or going. A send never blocks; one
+// pending covers any that follow. Watch returns nil once ctx is done, and
+// an error if the bus cannot be watched.
+func Watch(ctx context.Context, changed chan<- struct{}) error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to session bus: %w", err)
+	}
+	defer conn.Close()
+
+	const player = "org.mpris.MediaPlayer2.playerctld"
+	matches := [][]dbus.MatchOption{
+		{
+			dbus.WithMatchSender(player),
+			dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+			dbus.WithMatchMember("PropertiesChanged"),
+		},
+		{
+			dbus.WithMatchSender(player),
+			dbus.WithMatchInterface("org.mpris.MediaPlayer2.Player"),
+			dbus.WithMatchMember("Seeked"),
+		},
+		{
+			dbus.WithMatchSender("org.freedesktop.DBus"),
+			dbus.WithMatchMember("NameOwnerChanged"),
+			dbus.WithMatchArg(0, player),
+		},
+	}
+	for _, m := range matches {
+		if err := conn.AddMatchSignal(m...); err != nil {
+			return fmt.Errorf("failed to watch the player: %w", err)
+		}
+	}
+	signalCh := make(chan *dbus.Signal, 16)
+	conn.Signal(signalCh)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case sig, ok := <-signalCh:
+			if !ok {
+				return errors.New("session bus connection closed")
+			}
+			if !playerChange(sig) {
+				continue
+			}
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// playerChange reports whether sig changes what the lyrics follow.
+func playerChange(sig *dbus.Signal) bool {
+	switch sig.Name {
+	case "org.mpris.MediaPlayer2.Player.Seeked", "org.freedesktop.DBus.NameOwnerChanged":
+		return true
+	case "org.freedesktop.DBus.Properties.PropertiesChanged":
+	default:
+		return false // e.g. NameAcquired, sent to every connection
+	}
+	if len(sig.Body) < 2 {
+		return false
+	}
+	if iface, _ := sig.Body[0].(string); iface != "org.mpris.MediaPlayer2.Player" {
+		return false
+	}
+	props, _ := sig.Body[1].(map[string]dbus.Variant)
+	for _, name := range []string{"Metadata", "PlaybackStatus", "Position", "Rate"} {
+		if _, ok := props[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// getIdentity returns the player's Identity property, or "" if unavailable.
+func getIdentity(obj dbus.BusObject) string {
+	v, err := obj.GetProperty("org.mpris.MediaPlayer2.Identity")
+	if err != nil {
+		return ""
+	}
+	s, _ := v.Value().(string)
+	return s
+}
+
+// getActiveBus returns the bus name of the player playerctld, behind obj,
+// made active: the first of its PlayerNames.
+func getActiveBus(obj dbus.BusObject) string {
+	v, err := obj.GetProperty("com.github.altdesktop.playerctld.PlayerNames")
+	if err != nil {
+		return ""
+	}
+	names, _ := v.Value().([]string)
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
 // getString safely extracts a string from metadata
 func getString(metadata map[string]dbus.Variant, key string) string {
 	if v, ok := metadata[key]; ok {
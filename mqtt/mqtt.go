@@ -0,0 +1,460 @@
+// Package mqtt publishes messages to an MQTT 3.1.1 broker. It is a
+// publisher only, at QoS 0, which is all lyric lines need: a line is worth
+// nothing once the next one is current.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// Config is the broker a Client publishes to. It comes from a file rather
+// than flags, keeping the password off the process list.
+type Config struct {
+	// Broker is the broker URL: tcp://host:1883, or ssl://host:8883 for
+	// TLS (mqtt:// and mqtts:// are accepted too).
+	Broker   string `json:"broker"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// ClientID defaults to "lyricsmpris-" and the process ID.
+	ClientID string `json:"client_id"`
+	// Prefix starts every topic, e.g. "lyricsmpris" for lyricsmpris/line.
+	Prefix string `json:"prefix"`
+	// Will, when set, is published to Will on WillTopic by the broker if
+	// the connection is lost without a goodbye, and by Close otherwise.
+	WillTopic string `json:"-"`
+	Will      string `json:"-"`
+}
+
+// LoadConfig reads a JSON Config from path. A missing file is no error;
+// the config is nil then.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if c.Broker == "" {
+		return nil, fmt.Errorf("%s: no broker", path)
+	}
+	if _, _, err := c.dialAddr(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if c.Prefix == "" {
+		c.Prefix = "lyricsmpris"
+	}
+	if c.ClientID == "" {
+		c.ClientID = fmt.Sprintf("lyricsmpris-%d", os.Getpid())
+	}
+	return &c, nil
+}
+
+// dialAddr returns the broker's host:port and whether it speaks TLS.
+func (c *Config) dialAddr() (addr string, useTLS bool, err error) {
+	u, err := url.Parse(c.Broker)
+	if err != nil {
+		return "", false, err
+	}
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		useTLS, port = true, "8883"
+	default:
+		return "", false, fmt.Errorf("broker %q: unknown scheme %q", c.Broker, u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
+
+const (
+	// keepAlive is the longest the connection goes silent; a ping is
+	// sent at half of it.
+	keepAlive = 60 * time.Second
+	// queueSize is how many messages wait for the broker before the
+	// oldest are dropped.
+	queueSize  = 32
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+	ioTimeout  = 10 * time.Second
+)
+
+// Packet types, shifted into the fixed header's high nibble.
+const (
+	packetConnect    = 1 << 4
+	packetConnack    = 2 << 4
+	packetPublish    = 3 << 4
+	packetPingreq    = 12 << 4
+	packetPingresp   = 13 << 4
+	packetDisconnect = 14 << 4
+)
+
+type message struct {
+	topic   string
+	payload []byte
+	retain  bool
+}
+
+// Client publishes to the broker of its Config from a goroutine of its
+// own, reconnecting with backoff whenever the connection is lost. Publish
+// never waits for the broker.
+type Client struct {
+	cfg     Config
+	verbose bool
+	dial    func(ctx context.Context) (net.Conn, error)
+	queue   chan message
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu       sync.Mutex
+	retained map[string]message // republished on every connect
+	dirty    map[string]bool    // retained topics not yet published
+	changed  chan struct{}      // signals dirty retained topics
+}
+
+// Start starts publishing to the broker of cfg until Close.
+func Start(cfg Config, verbose bool) *Client {
+	return start(cfg, verbose, cfg.dial)
+}
+
+// start starts a client reaching its broker through dial.
+func start(cfg Config, verbose bool, dial func(ctx context.Context) (net.Conn, error)) *Client {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		cfg:      cfg,
+		verbose:  verbose,
+		dial:     dial,
+		queue:    make(chan message, queueSize),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		retained: make(map[string]message),
+		dirty:    make(map[string]bool),
+		changed:  make(chan struct{}, 1),
+	}
+	go c.run(ctx)
+	return c
+}
+
+// Publish queues payload for the topic Prefix/topic, dropping the oldest
+// queued message when the queue is full. Only the latest retained message
+// of a topic is kept, and published again after every reconnect.
+func (c *Client) Publish(topic string, payload []byte, retain bool) {
+	m := message{topic: c.cfg.Prefix + "/" + topic, payload: payload, retain: retain}
+	if retain {
+		c.mu.Lock()
+		c.retained[m.topic] = m
+		c.dirty[m.topic] = true
+		c.mu.Unlock()
+		select {
+		case c.changed <- struct{}{}:
+		default:
+		}
+		return
+	}
+	for {
+		select {
+		case c.queue <- m:
+			return
+		default:
+		}
+		select {
+		case <-c.queue:
+		default:
+		}
+	}
+}
+
+// Close publishes the will, disconnects and stops the client.
+func (c *Client) Close() {
+	c.cancel()
+	<-c.done
+}
+
+func (c *Client) run(ctx context.Context) {
+	defer close(c.done)
+	backoff := minBackoff
+	for {
+		conn, err := c.connect(ctx)
+		if err == nil {
+			if c.verbose {
+				log.Printf("mqtt: connected to %s", c.cfg.Broker)
+			}
+			backoff = minBackoff
+			err = c.serve(ctx, conn)
+			if ctx.Err() != nil {
+				c.goodbye(conn)
+				return
+			}
+			conn.Close()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("mqtt: %v; retrying in %v", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// dial connects to the broker, over TLS for its TLS schemes.
+func (c *Config) dial(ctx context.Context) (net.Conn, error) {
+	addr, useTLS, err := c.dialAddr()
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: ioTimeout}
+	if useTLS {
+		host, _, _ := net.SplitHostPort(addr)
+		return (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", addr)
+	}
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+// connect dials the broker and completes the MQTT handshake.
+func (c *Client) connect(ctx context.Context) (net.Conn, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(ioTimeout))
+	if _, err := conn.Write(c.connectPacket()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	var ack [4]byte
+	if _, err := io.ReadFull(conn, ack[:]); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("no CONNACK: %w", err)
+	}
+	if ack[0] != packetConnack || ack[1] != 2 {
+		conn.Close()
+		return nil, errors.New("malformed CONNACK")
+	}
+	if ack[3] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("connection refused: %s", connackReason(ack[3]))
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+func connackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("code %d", code)
+}
+
+func (c *Client) connectPacket() []byte {
+	const (
+		flagCleanSession = 0x02
+		flagWill         = 0x04
+		flagPassword     = 0x40
+		flagUsername     = 0x80
+	)
+	flags := byte(flagCleanSession)
+	payload := appendString(nil, c.cfg.ClientID)
+	if c.cfg.WillTopic != "" {
+		flags |= flagWill
+		payload = appendString(payload, c.cfg.WillTopic)
+		payload = appendString(payload, c.cfg.Will)
+	}
+	if c.cfg.Username != "" {
+		flags |= flagUsername
+		payload = appendString(payload, c.cfg.Username)
+		if c.cfg.Password != "" {
+			flags |= flagPassword
+			payload = appendString(payload, c.cfg.Password)
+		}
+	}
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags) // protocol level 4 is MQTT 3.1.1
+	body = binary.BigEndian.AppendUint16(body, uint16(keepAlive/time.Second))
+	return packet(packetConnect, append(body, payload...))
+}
+
+// serve publishes the retained messages, then the queue, pinging the
+// broker when idle, until the connection fails or ctx is done.
+func (c *Client) serve(ctx context.Context, conn net.Conn) error {
+	c.mu.Lock()
+	for topic := range c.retained {
+		c.dirty[topic] = true
+	}
+	c.mu.Unlock()
+	if err := c.publishRetained(conn); err != nil {
+		return err
+	}
+
+	// the broker only ever sends PINGRESP on a publish-only connection;
+	// reading them tells a dead connection from an idle one
+	readErr := make(chan error, 1)
+	pong := make(chan struct{}, 1)
+	go func() {
+		r := bufio.NewReader(conn)
+		for {
+			typ, n, err := readHeader(r)
+			if err == nil {
+				_, err = r.Discard(n)
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+			if typ == packetPingresp {
+				select {
+				case pong <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	ping := time.NewTicker(keepAlive / 2)
+	defer ping.Stop()
+	awaiting := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			return err
+		case <-pong:
+			awaiting = false
+		case <-ping.C:
+			if awaiting {
+				return errors.New("broker stopped answering pings")
+			}
+			if err := write(conn, []byte{packetPingreq, 0}); err != nil {
+				return err
+			}
+			awaiting = true
+		case <-c.changed:
+			if err := c.publishRetained(conn); err != nil {
+				return err
+			}
+		case m := <-c.queue:
+			if err := write(conn, publishPacket(m)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// publishRetained publishes the retained messages not yet published on
+// conn.
+func (c *Client) publishRetained(conn net.Conn) error {
+	c.mu.Lock()
+	var pending []message
+	for topic := range c.dirty {
+		pending = append(pending, c.retained[topic])
+	}
+	clear(c.dirty)
+	c.mu.Unlock()
+	for _, m := range pending {
+		if err := write(conn, publishPacket(m)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// goodbye publishes the will itself, since a clean disconnect makes the
+// broker drop it, then disconnects.
+func (c *Client) goodbye(conn net.Conn) {
+	if c.cfg.WillTopic != "" {
+		write(conn, publishPacket(message{topic: c.cfg.WillTopic, payload: []byte(c.cfg.Will)}))
+	}
+	write(conn, []byte{packetDisconnect, 0})
+	conn.Close()
+}
+
+func write(conn net.Conn, p []byte) error {
+	conn.SetWriteDeadline(time.Now().Add(ioTimeout))
+	_, err := conn.Write(p)
+	return err
+}
+
+func publishPacket(m message) []byte {
+	typ := byte(packetPublish)
+	if m.retain {
+		typ |= 0x01
+	}
+	return packet(typ, append(appendString(nil, m.topic), m.payload...))
+}
+
+// packet returns a packet of type typ: the fixed header, with the body
+// length as a variable length integer, and the body.
+func packet(typ byte, body []byte) []byte {
+	p := []byte{typ}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		p = append(p, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(p, body...)
+}
+
+// readHeader reads a fixed header, returning the packet type and the body
+// length.
+func readHeader(r *bufio.Reader) (typ byte, n int, err error) {
+	if typ, err = r.ReadByte(); err != nil {
+		return 0, 0, err
+	}
+	for shift := 0; shift < 28; shift += 7 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, 0, err
+		}
+		n |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return typ & 0xf0, n, nil
+		}
+	}
+	return 0, 0, errors.New("malformed packet length")
+}
+
+// appendString appends s as an MQTT string: its length, then its bytes.
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
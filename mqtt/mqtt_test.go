@@ -0,0 +1,290 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeBroker hands the test the broker's end of each connection a client
+// dials, over net.Pipe.
+type fakeBroker struct {
+	conns chan net.Conn
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{conns: make(chan net.Conn)}
+}
+
+func (b *fakeBroker) dial(ctx context.Context) (net.Conn, error) {
+	client, server := net.Pipe()
+	select {
+	case b.conns <- server:
+		return client, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// accept returns the next connection, failing the test after two
+// seconds, longer than the first reconnect takes.
+func (b *fakeBroker) accept(t *testing.T) *brokerConn {
+	t.Helper()
+	select {
+	case conn := <-b.conns:
+		t.Cleanup(func() { conn.Close() })
+		conn.SetDeadline(time.Now().Add(2 * time.Second))
+		return &brokerConn{conn: conn, r: bufio.NewReader(conn)}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the client did not connect")
+		return nil
+	}
+}
+
+// brokerConn is the broker's end of a connection.
+type brokerConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// read returns the next packet's first byte, type and flags, and body.
+func (c *brokerConn) read(t *testing.T) (byte, []byte) {
+	t.Helper()
+	first, err := c.r.ReadByte()
+	if err != nil {
+		t.Fatalf("reading a packet: %v", err)
+	}
+	n, shift := 0, 0
+	for {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			t.Fatalf("reading a packet's length: %v", err)
+		}
+		n |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		shift += 7
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		t.Fatalf("reading a packet's body: %v", err)
+	}
+	return first, body
+}
+
+// connect reads a CONNECT and answers it with code.
+func (c *brokerConn) connect(t *testing.T, code byte) connectFields {
+	t.Helper()
+	typ, body := c.read(t)
+	if typ != packetConnect {
+		t.Fatalf("first packet %#x, want CONNECT", typ)
+	}
+	if _, err := c.conn.Write([]byte{packetConnack, 2, 0, code}); err != nil {
+		t.Fatal(err)
+	}
+	return parseConnect(t, body)
+}
+
+// publish reads a PUBLISH, returning its topic, payload and retain flag.
+func (c *brokerConn) publish(t *testing.T) (topic, payload string, retain bool) {
+	t.Helper()
+	typ, body := c.read(t)
+	if typ&0xf0 != packetPublish {
+		t.Fatalf("packet %#x, want PUBLISH", typ)
+	}
+	if typ&0x06 != 0 {
+		t.Errorf("PUBLISH at QoS %d, want 0", typ&0x06>>1)
+	}
+	topic, body = readString(t, body)
+	return topic, string(body), typ&0x01 != 0
+}
+
+// connectFields are what a CONNECT carries.
+type connectFields struct {
+	flags                     byte
+	keepAlive                 uint16
+	clientID, willTopic, will string
+	username, password        string
+}
+
+func parseConnect(t *testing.T, body []byte) connectFields {
+	t.Helper()
+	name, body := readString(t, body)
+	if name != "MQTT" || len(body) < 4 || body[0] != 4 {
+		t.Fatalf("CONNECT of protocol %q, level %v", name, body[:1])
+	}
+	f := connectFields{flags: body[1], keepAlive: binary.BigEndian.Uint16(body[2:4])}
+	body = body[4:]
+	f.clientID, body = readString(t, body)
+	if f.flags&0x04 != 0 {
+		f.willTopic, body = readString(t, body)
+		f.will, body = readString(t, body)
+	}
+	if f.flags&0x80 != 0 {
+		f.username, body = readString(t, body)
+	}
+	if f.flags&0x40 != 0 {
+		f.password, body = readString(t, body)
+	}
+	if len(body) != 0 {
+		t.Errorf("CONNECT with %d bytes left over", len(body))
+	}
+	return f
+}
+
+func readString(t *testing.T, b []byte) (string, []byte) {
+	t.Helper()
+	if len(b) < 2 || len(b) < 2+int(binary.BigEndian.Uint16(b)) {
+		t.Fatalf("truncated string in % x", b)
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	return string(b[2 : 2+n]), b[2+n:]
+}
+
+func TestPacketLength(t *testing.T) {
+	tests := []struct {
+		body, header int // the header's length bytes
+	}{
+		{0, 1}, {127, 1}, {128, 2}, {16383, 2}, {16384, 3}, {2097151, 3}, {2097152, 4},
+	}
+	for _, tt := range tests {
+		p := packet(packetPublish, make([]byte, tt.body))
+		if got := len(p) - 1 - tt.body; got != tt.header {
+			t.Errorf("%d bytes: %d length bytes, want %d", tt.body, got, tt.header)
+		}
+		typ, n, err := readHeader(bufio.NewReader(bytes.NewReader(p)))
+		if err != nil || typ != packetPublish || n != tt.body {
+			t.Errorf("%d bytes: read back %#x, %d, %v", tt.body, typ, n, err)
+		}
+	}
+	if _, _, err := readHeader(bufio.NewReader(bytes.NewReader([]byte{packetPingresp, 0x80, 0x80, 0x80, 0x80, 0x01}))); err == nil {
+		t.Error("read a length of five bytes")
+	}
+}
+
+func TestConnectPacket(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want connectFields
+	}{
+		{"bare", Config{ClientID: "id"}, connectFields{flags: 0x02, clientID: "id"}},
+		{"will", Config{ClientID: "id", WillTopic: "lyricsmpris/status", Will: "offline"},
+			connectFields{flags: 0x06, clientID: "id", willTopic: "lyricsmpris/status", will: "offline"}},
+		{"user name", Config{ClientID: "id", Username: "me"}, connectFields{flags: 0x82, clientID: "id", username: "me"}},
+		{"password", Config{ClientID: "id", Username: "me", Password: "s3cret"},
+			connectFields{flags: 0xc2, clientID: "id", username: "me", password: "s3cret"}},
+		{"all", Config{ClientID: "id", Username: "me", Password: "s3cret", WillTopic: "w", Will: "gone"},
+			connectFields{flags: 0xc6, clientID: "id", willTopic: "w", will: "gone", username: "me", password: "s3cret"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{cfg: tt.cfg}
+			p := c.connectPacket()
+			typ, n, err := readHeader(bufio.NewReader(bytes.NewReader(p)))
+			if err != nil || typ != packetConnect || n != len(p)-2 {
+				t.Fatalf("header %#x, %d, %v", typ, n, err)
+			}
+			got := parseConnect(t, p[2:])
+			tt.want.keepAlive = 60
+			if got != tt.want {
+				t.Errorf("CONNECT %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublishPacket(t *testing.T) {
+	for _, retain := range []bool{false, true} {
+		p := publishPacket(message{topic: "lyricsmpris/line", payload: []byte("one"), retain: retain})
+		want := append([]byte{packetPublish, 21, 0, 16}, "lyricsmpris/lineone"...)
+		if retain {
+			want[0] |= 0x01
+		}
+		if !bytes.Equal(p, want) {
+			t.Errorf("retain %v: % x, want % x", retain, p, want)
+		}
+	}
+}
+
+func TestConnackRefused(t *testing.T) {
+	tests := []struct {
+		ack  []byte
+		want string
+	}{
+		{[]byte{packetConnack, 2, 0, 1}, "unacceptable protocol version"},
+		{[]byte{packetConnack, 2, 0, 2}, "client identifier rejected"},
+		{[]byte{packetConnack, 2, 0, 3}, "server unavailable"},
+		{[]byte{packetConnack, 2, 0, 4}, "bad user name or password"},
+		{[]byte{packetConnack, 2, 0, 5}, "not authorized"},
+		{[]byte{packetConnack, 2, 0, 42}, "code 42"},
+		{[]byte{packetConnack, 3, 0, 0}, "malformed CONNACK"},
+		{[]byte{packetPingresp, 0, 0, 0}, "malformed CONNACK"},
+	}
+	for _, tt := range tests {
+		b := newFakeBroker()
+		c := &Client{cfg: Config{ClientID: "id"}, dial: b.dial}
+		errs := make(chan error, 1)
+		go func() {
+			_, err := c.connect(context.Background())
+			errs <- err
+		}()
+		conn := b.accept(t)
+		conn.read(t)
+		conn.conn.Write(tt.ack)
+		if err := <-errs; err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("CONNACK % x: error %v, want %q", tt.ack, err, tt.want)
+		}
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	log.SetOutput(io.Discard) // the lost connection
+	defer log.SetOutput(os.Stderr)
+	b := newFakeBroker()
+	c := start(Config{ClientID: "id", Prefix: "lyricsmpris", WillTopic: "lyricsmpris/status", Will: "offline"}, false, b.dial)
+	c.Publish("line", []byte("one"), true)
+
+	conn := b.accept(t)
+	if f := conn.connect(t, 0); f.clientID != "id" || f.will != "offline" {
+		t.Errorf("CONNECT %+v", f)
+	}
+	if topic, payload, retain := conn.publish(t); topic != "lyricsmpris/line" || payload != "one" || !retain {
+		t.Errorf("published %q %q, retain %v; want the retained line", topic, payload, retain)
+	}
+	conn.conn.Close() // the connection lost
+
+	// the retained line published again on the new connection
+	conn = b.accept(t)
+	conn.connect(t, 0)
+	if topic, payload, retain := conn.publish(t); topic != "lyricsmpris/line" || payload != "one" || !retain {
+		t.Errorf("republished %q %q, retain %v; want the retained line", topic, payload, retain)
+	}
+	c.Publish("event", []byte("two"), false)
+	if topic, payload, retain := conn.publish(t); topic != "lyricsmpris/event" || payload != "two" || retain {
+		t.Errorf("published %q %q, retain %v; want the event", topic, payload, retain)
+	}
+
+	// a goodbye: the will, which a clean disconnect makes the broker drop
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		c.Close()
+	}()
+	if topic, payload, _ := conn.publish(t); topic != "lyricsmpris/status" || payload != "offline" {
+		t.Errorf("published %q %q, want the will", topic, payload)
+	}
+	if typ, body := conn.read(t); typ != packetDisconnect || len(body) != 0 {
+		t.Errorf("packet %#x, want DISCONNECT", typ)
+	}
+	<-closed
+}
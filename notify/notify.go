@@ -0,0 +1,88 @@
+//go:build linux
+// +build linux
+
+// Package notify sends desktop notifications over the
+// org.freedesktop.Notifications D-Bus interface.
+package notify
+
+import (
+	"fmt"
+	"html"
+	"slices"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Urgency levels of a notification.
+const (
+	UrgencyLow      = "low"
+	UrgencyNormal   = "normal"
+	UrgencyCritical = "critical"
+)
+
+const (
+	service = "org.freedesktop.Notifications"
+	path    = "/org/freedesktop/Notifications"
+)
+
+var urgencies = map[string]byte{UrgencyLow: 0, UrgencyNormal: 1, UrgencyCritical: 2}
+
+// Notifier shows one notification at a time: each one sent replaces the
+// previous, so they never stack up.
+type Notifier struct {
+	// AppName names the sender to the notification server.
+	AppName string
+	// Urgency is UrgencyLow, UrgencyNormal (the default) or UrgencyCritical.
+	Urgency string
+	// Timeout is how long a notification stays up. Zero leaves it to the
+	// notification server.
+	Timeout time.Duration
+
+	conn   *dbus.Conn
+	markup bool   // the server parses markup in bodies
+	id     uint32 // id of the notification shown last, 0 for none
+}
+
+// Send shows a notification with the given summary and plain text body,
+// replacing the one sent before.
+func (n *Notifier) Send(summary, body string) error {
+	if n.conn == nil {
+		conn, err := dbus.ConnectSessionBus()
+		if err != nil {
+			return fmt.Errorf("failed to connect to session bus: %w", err)
+		}
+		n.conn = conn
+		var caps []string
+		n.conn.Object(service, path).Call(service+".GetCapabilities", 0).Store(&caps)
+		n.markup = slices.Contains(caps, "body-markup")
+	}
+	if n.markup {
+		body = html.EscapeString(body)
+	}
+	urgency, ok := urgencies[n.Urgency]
+	if !ok {
+		urgency = urgencies[UrgencyNormal]
+	}
+	timeout := int32(-1) // the server's default
+	if n.Timeout > 0 {
+		timeout = int32(n.Timeout / time.Millisecond)
+	}
+	hints := map[string]dbus.Variant{"urgency": dbus.MakeVariant(urgency)}
+	call := n.conn.Object(service, path).Call(service+".Notify", 0,
+		n.AppName, n.id, "", summary, body, []string{}, hints, timeout)
+	if call.Err != nil {
+		return fmt.Errorf("notification failed: %w", call.Err)
+	}
+	return call.Store(&n.id)
+}
+
+// Close disconnects from the session bus.
+func (n *Notifier) Close() error {
+	if n.conn == nil {
+		return nil
+	}
+	err := n.conn.Close()
+	n.conn = nil
+	return err
+}
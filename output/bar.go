@@ -0,0 +1,59 @@
+package output
+
+import (
+	"strings"
+
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// Bar markup dialects.
+const (
+	BarLemonbar = "lemonbar" // %{F#rrggbb}…%{F-}
+	BarDzen2    = "dzen2"    // ^fg(#rrggbb)…^fg()
+)
+
+// BarStyle styles the lines of a lemonbar or dzen2 bar. Colors are
+// anything the bar takes, e.g. "#ffffff"; empty leaves the bar's own.
+type BarStyle struct {
+	Current string // color of the current line
+	Next    string // color of the next line
+	// ShowNext adds the next line after the current one, past Divider,
+	// which is written as it is, markup and all.
+	ShowNext bool
+	Divider  string
+}
+
+// BarLine returns the bar line for u in dialect: the current line, or
+// while paused *pause when pause is set, and the next line when style asks
+// for it, each cut to width. Lyrics are escaped, so a % or ^ in them shows
+// as it is. The line is empty when there is nothing to show.
+func BarLine(dialect string, u pool.Update, width Width, pause *string, style BarStyle) string {
+	text, ok := CurrentText(u, pause)
+	if !ok || text == "" {
+		return ""
+	}
+	line := barColor(dialect, style.Current, width.Cut(text))
+	next := u.Index + 1
+	if style.ShowNext && u.Playing && u.Index >= 0 && next < len(u.Lines) && u.Lines[next].Text != "" {
+		line += style.Divider + barColor(dialect, style.Next, width.Cut(u.Lines[next].Text))
+	}
+	return line
+}
+
+// barColor escapes text and colors it in dialect.
+func barColor(dialect, color, text string) string {
+	switch dialect {
+	case BarDzen2:
+		text = strings.ReplaceAll(text, "^", "^^")
+		if color == "" {
+			return text
+		}
+		return "^fg(" + color + ")" + text + "^fg()"
+	default:
+		text = strings.ReplaceAll(text, "%", "%%")
+		if color == "" {
+			return text
+		}
+		return "%{F" + color + "}" + text + "%{F-}"
+	}
+}
@@ -0,0 +1,57 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+func TestBarLineGolden(t *testing.T) {
+	lines := []lyrics.LyricLine{
+		{Time: 1, Text: "100% sure"},
+		{Time: 2, Text: "^_^ smile"},
+		{Time: 3, Text: "%{F#ff0000}not markup%{F-}"},
+		{Time: 4, Text: "^fg(red)not markup^fg()"},
+		{Time: 5, Text: "50%^2 %%^^"},
+		{Time: 6, Text: "plain"},
+	}
+	paused := "paused at 100%^"
+	cases := []struct {
+		name    string
+		index   int
+		playing bool
+		width   Width
+		style   BarStyle
+	}{
+		{name: "percent", index: 0, playing: true},
+		{name: "caret", index: 1, playing: true},
+		{name: "lemonbar markup", index: 2, playing: true},
+		{name: "dzen2 markup", index: 3, playing: true},
+		{name: "both, doubled", index: 4, playing: true},
+		{name: "colored", index: 0, playing: true, style: BarStyle{Current: "#ffffff"}},
+		{name: "next line", index: 0, playing: true, style: BarStyle{Current: "#ffffff", Next: "#888888", ShowNext: true, Divider: " | "}},
+		{name: "next line of specials", index: 3, playing: true, style: BarStyle{ShowNext: true, Divider: " %{F#444}·%{F-} "}},
+		{name: "cut", index: 4, playing: true, width: Width{Max: 6, Ellipsis: "…"}},
+		{name: "paused", index: 1, style: BarStyle{Current: "#ffffff"}},
+	}
+	for _, dialect := range []string{BarLemonbar, BarDzen2} {
+		t.Run(dialect, func(t *testing.T) {
+			var b strings.Builder
+			for _, c := range cases {
+				u := pool.Update{
+					State:   pool.StateReady,
+					Track:   mpris.TrackMetadata{Title: "Song", Artist: "Band"},
+					Lines:   lines,
+					Index:   c.index,
+					Playing: c.playing,
+				}
+				fmt.Fprintf(&b, "%s: %s\n", c.name, BarLine(dialect, u, c.width, &paused, c.style))
+			}
+			checkGolden(t, "bar_"+dialect+".golden", []byte(b.String()))
+		})
+	}
+}
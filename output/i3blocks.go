@@ -0,0 +1,41 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// I3Block is the object an i3blocks block with "format=json" reads.
+type I3Block struct {
+	FullText  string `json:"full_text"`
+	ShortText string `json:"short_text,omitempty"`
+}
+
+// NewI3Block returns the block for u: the current line, or while paused
+// *pause when pause is set, and that cut to width as the short text when
+// width has a limit. The text is empty when there is nothing to show.
+func NewI3Block(u pool.Update, width Width, pause *string) I3Block {
+	text, ok := CurrentText(u, pause)
+	if !ok {
+		return I3Block{}
+	}
+	b := I3Block{FullText: text}
+	if width.Max > 0 {
+		b.ShortText = width.Cut(b.FullText)
+	}
+	return b
+}
+
+// I3Click is a click event i3blocks sends on stdin.
+type I3Click struct {
+	Button int `json:"button"`
+}
+
+// WriteI3Block writes b as a single JSON line.
+func WriteI3Block(w io.Writer, b I3Block) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(b)
+}
@@ -0,0 +1,43 @@
+package output
+
+import (
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// seekEpsilon is how far, in seconds, the position may move backwards
+// before it counts as a seek rather than the interpolated position being
+// corrected.
+const seekEpsilon = 1.0
+
+// LineTracker decides when the current lyric line is new, so that each
+// line is emitted once per pass through the lyrics: a line is new when
+// playback moved past the last one emitted, and everything is new again
+// after a seek (pool.Update.Seeked, or the position moving backwards), a
+// track restart or a track change.
+type LineTracker struct {
+	track    mpris.TrackMetadata
+	index    int // last line emitted, -1 for none
+	started  bool
+	position float64
+}
+
+// Next reports whether the current line of u is new, and if so marks it
+// emitted.
+func (l *LineTracker) Next(u pool.Update) bool {
+	if !l.started || u.Track != l.track || u.Seeked || u.Position < l.position-seekEpsilon {
+		l.track, l.started = u.Track, true
+		l.Reset()
+	}
+	l.position = u.Position
+	if u.Index < 0 || u.Index >= len(u.Lines) || u.Index <= l.index {
+		return false
+	}
+	l.index = u.Index
+	return true
+}
+
+// Reset makes the current line new again, e.g. to emit it after a pause.
+func (l *LineTracker) Reset() {
+	l.index = -1
+}
@@ -0,0 +1,74 @@
+package output
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+func TestLineTrackerReplays(t *testing.T) {
+	track := mpris.TrackMetadata{Title: "Song", Artist: "Band"}
+	lines := []lyrics.LyricLine{{Time: 10, Text: "a"}, {Time: 20, Text: "b"}, {Time: 30, Text: "c"}}
+	at := func(position float64) pool.Update {
+		return pool.Update{State: pool.StateReady, Track: track, Lines: lines, Index: pool.IndexAt(lines, position), Playing: true, Position: position}
+	}
+	seeked := func(position float64) pool.Update {
+		u := at(position)
+		u.Seeked = true
+		return u
+	}
+	tests := []struct {
+		name    string
+		updates []pool.Update
+		want    []int // indexes emitted
+	}{
+		{
+			name:    "straight through",
+			updates: []pool.Update{at(5), at(10), at(15), at(20), at(30), at(35)},
+			want:    []int{0, 1, 2},
+		},
+		{
+			name:    "seek back to an earlier line",
+			updates: []pool.Update{at(10), at(20), at(30), at(12), at(20)},
+			want:    []int{0, 1, 2, 0, 1},
+		},
+		{
+			// a seek within the line the pool reports, not the position
+			name:    "seek back within a second",
+			updates: []pool.Update{at(10), at(20), seeked(19.5), at(20.5)},
+			want:    []int{0, 1, 0, 1},
+		},
+		{
+			name:    "position corrected backwards",
+			updates: []pool.Update{at(10), at(20.4), at(20), at(20.2)},
+			want:    []int{0, 1},
+		},
+		{
+			name:    "track loops",
+			updates: []pool.Update{at(10), at(20), at(30), at(39), at(0), at(10), at(20), at(30)},
+			want:    []int{0, 1, 2, 0, 1, 2},
+		},
+		{
+			name:    "seek forward",
+			updates: []pool.Update{at(10), at(30), at(35)},
+			want:    []int{0, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l LineTracker
+			var got []int
+			for _, u := range tt.updates {
+				if l.Next(u) {
+					got = append(got, u.Index)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("emitted %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
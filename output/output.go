@@ -0,0 +1,291 @@
+// Package output turns the pool's updates into a stream of discrete events
+// for machine consumers.
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/pool"
+	"github.com/best8oy/LyricsMPRIS/textwidth"
+)
+
+// Event types.
+const (
+	EventTrack    = "track"     // the player moved to a new track
+	EventRestart  = "restart"   // the track started over, e.g. looping
+	EventEnd      = "end"       // the track played to its end; no line is current
+	EventLine     = "line"      // a new lyric line became current
+	EventNotFound = "not_found" // the track has no synced lyrics
+	EventError    = "error"     // no player, or the lyrics could not be fetched
+	// EventPaused is sent when playback pauses and Tracker.Pause is set;
+	// its text is the placeholder and its index and time the paused line's.
+	EventPaused = "paused"
+	// EventSnapshot starts the stream of a client joining mid-song; see
+	// Snapshot.
+	EventSnapshot = "snapshot"
+	// EventProgress is sent several times a second while a line plays,
+	// with how far into it playback is; see LineProgress.
+	EventProgress = "progress"
+)
+
+// Event is one entry of the event stream. The JSON field names are stable.
+type Event struct {
+	Type   string `json:"type"`
+	Artist string `json:"artist"`
+	Title  string `json:"title"`
+	Album  string `json:"album,omitempty"`
+	Player string `json:"player,omitempty"`
+	// Line is set on EventLine and EventPaused events, its fields inlined.
+	*Line
+	// Error is the error message of EventError events.
+	Error string `json:"error,omitempty"`
+	// Progress is the progress into the line of EventProgress events.
+	Progress *float64 `json:"progress,omitempty"`
+}
+
+// Line describes the lyric line of an EventLine or EventPaused event.
+type Line struct {
+	Text        string  `json:"text"`
+	Translation string  `json:"translation,omitempty"`
+	Index       int     `json:"index"` // index of the line in the lyrics, from 0
+	Time        float64 `json:"time"`  // the line's timestamp in seconds
+}
+
+// Tracker derives events from successive pool updates, reporting each
+// state once.
+type Tracker struct {
+	// Pause, when set, is the placeholder text reported by an EventPaused
+	// event when playback pauses. The line is reported again on resume.
+	Pause *string
+
+	track   mpris.TrackMetadata
+	started bool
+	lines   LineTracker
+	paused  bool   // the pause was reported
+	state   string // last error or not-found state reported
+}
+
+// Events returns the events u brings about since the previous update.
+func (t *Tracker) Events(u pool.Update) []Event {
+	var events []Event
+	base := Event{
+		Artist: u.Track.Artist,
+		Title:  u.Track.Title,
+		Album:  u.Track.Album,
+		Player: u.Track.Player,
+	}
+	add := func(typ string) *Event {
+		e := base
+		e.Type = typ
+		events = append(events, e)
+		return &events[len(events)-1]
+	}
+
+	if u.Track != t.track || !t.started {
+		t.track, t.started = u.Track, true
+		t.state, t.paused = "", false
+		if u.Track.Title != "" {
+			add(EventTrack)
+		}
+	} else if u.Restarted {
+		t.state, t.paused = "", false
+		add(EventRestart)
+	}
+
+	switch {
+	case u.State == pool.StateFetching, u.State == pool.StateStopped:
+	case u.State == pool.StateNotFound:
+		t.report(EventNotFound, add)
+	case u.State == pool.StateEnded:
+		t.report(EventEnd, add)
+	case u.State == pool.StateWaitingForPlayer || u.State == pool.StateError:
+		t.report(u.Err.Error(), add)
+	case t.Pause != nil && !u.Playing:
+		if t.paused {
+			break
+		}
+		t.paused = true
+		t.lines.Reset() // report the line again on resume
+		line := Line{Text: *t.Pause, Index: u.Index}
+		if u.Index >= 0 && u.Index < len(u.Lines) {
+			line.Time = u.Lines[u.Index].Time
+		}
+		add(EventPaused).Line = &line
+	case t.lines.Next(u):
+		t.state, t.paused = "", false
+		line := u.Lines[u.Index]
+		add(EventLine).Line = &Line{
+			Text:        line.Text,
+			Translation: line.Translation,
+			Index:       u.Index,
+			Time:        line.Time,
+		}
+	}
+	return events
+}
+
+// LineProgress returns how far position, in seconds, is into the current
+// line of u, from 0 at its start to 1 at the next line's, or for the last
+// line at the end of the track. The lyrics carry no word timings, so the
+// progress is even across the line. It reports false when there is no
+// current line or its end is unknown.
+func LineProgress(u pool.Update, position float64) (float64, bool) {
+	if u.Err != nil || u.Loading || u.Index < 0 || u.Index >= len(u.Lines) {
+		return 0, false
+	}
+	start, end := u.Lines[u.Index].Time, u.Duration
+	if u.Index+1 < len(u.Lines) {
+		end = u.Lines[u.Index+1].Time
+	}
+	if end <= start {
+		return 0, false
+	}
+	return min(max((position-start)/(end-start), 0), 1), true
+}
+
+// ProgressEvent returns the EventProgress event for position in the
+// current line of u, reporting false when LineProgress does.
+func ProgressEvent(u pool.Update, position float64) (Event, bool) {
+	p, ok := LineProgress(u, position)
+	if !ok {
+		return Event{}, false
+	}
+	p = math.Round(p*1000) / 1000
+	line := u.Lines[u.Index]
+	return Event{
+		Type:     EventProgress,
+		Artist:   u.Track.Artist,
+		Title:    u.Track.Title,
+		Album:    u.Track.Album,
+		Player:   u.Track.Player,
+		Line:     &Line{Text: line.Text, Translation: line.Translation, Index: u.Index, Time: line.Time},
+		Progress: &p,
+	}, true
+}
+
+// Snapshot is the whole state at one update, for a client joining the
+// event stream late: the events that follow it are relative to it.
+type Snapshot struct {
+	Type    string `json:"type"` // always EventSnapshot
+	Artist  string `json:"artist"`
+	Title   string `json:"title"`
+	Album   string `json:"album,omitempty"`
+	Player  string `json:"player,omitempty"`
+	Playing bool   `json:"playing"`
+	Loading bool   `json:"loading,omitempty"` // the lyrics are being fetched
+	// Index is the current line's index in Lines, or -1 for none.
+	Index int    `json:"index"`
+	Lines []Line `json:"lines"`
+	// Error is the message of an error keeping lyrics from Lines.
+	Error string `json:"error,omitempty"`
+}
+
+// NewSnapshot returns the snapshot of u.
+func NewSnapshot(u pool.Update) Snapshot {
+	s := Snapshot{
+		Type:    EventSnapshot,
+		Artist:  u.Track.Artist,
+		Title:   u.Track.Title,
+		Album:   u.Track.Album,
+		Player:  u.Track.Player,
+		Playing: u.Playing,
+		Loading: u.Loading,
+		Index:   -1,
+		Lines:   make([]Line, len(u.Lines)),
+	}
+	for i, l := range u.Lines {
+		s.Lines[i] = Line{Text: l.Text, Translation: l.Translation, Index: i, Time: l.Time}
+	}
+	if u.Index >= 0 && u.Index < len(u.Lines) {
+		s.Index = u.Index
+	}
+	if u.Err != nil {
+		s.Error = u.Err.Error()
+	}
+	return s
+}
+
+// Width limits text to Max display cells, ending text it cuts with
+// Ellipsis. A Max of 0 or less means no limit.
+type Width struct {
+	Max      int
+	Ellipsis string
+}
+
+// Cut returns s cut to the width. Grapheme clusters are never split.
+func (w Width) Cut(s string) string {
+	if w.Max <= 0 {
+		return s
+	}
+	return textwidth.Truncate(s, w.Max, w.Ellipsis)
+}
+
+// CurrentText returns the text an output shows for u: the current line,
+// or while paused *pause when pause is set. It reports false when there is
+// nothing to show.
+func CurrentText(u pool.Update, pause *string) (string, bool) {
+	switch {
+	case u.Err != nil || u.Loading || len(u.Lines) == 0:
+		return "", false
+	case !u.Playing && pause != nil:
+		return *pause, true
+	case u.Index < 0 || u.Index >= len(u.Lines):
+		return "", false
+	}
+	return u.Lines[u.Index].Text, true
+}
+
+// report adds the not-found or end event, or an error event with message
+// state, unless it was the last state reported.
+func (t *Tracker) report(state string, add func(string) *Event) {
+	if state == t.state {
+		return
+	}
+	t.state = state
+	t.lines.Reset()
+	if state == EventNotFound || state == EventEnd {
+		add(state)
+		return
+	}
+	add(EventError).Error = state
+}
+
+// WriteJSON writes events as JSON lines, one object per line.
+func WriteJSON(w io.Writer, events []Event) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sheet is a whole lyric sheet, as one-shot mode dumps it.
+type Sheet struct {
+	Artist string `json:"artist"`
+	Title  string `json:"title"`
+	Album  string `json:"album,omitempty"`
+	Source string `json:"source"`
+	Lines  []Line `json:"lines"`
+}
+
+// NewSheet returns the sheet of lyrics for track.
+func NewSheet(track mpris.TrackMetadata, lyric *lyrics.Lyric) Sheet {
+	s := Sheet{
+		Artist: track.Artist,
+		Title:  track.Title,
+		Album:  track.Album,
+		Source: lyric.Source,
+		Lines:  make([]Line, len(lyric.Lines)),
+	}
+	for i, l := range lyric.Lines {
+		s.Lines[i] = Line{Text: l.Text, Translation: l.Translation, Index: i, Time: l.Time}
+	}
+	return s
+}
@@ -0,0 +1,78 @@
+package output
+
+import (
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// State is the whole state as one JSON document, for widgets that read a
+// file rather than an event stream, e.g. eww:
+//
+//	{"artist": "…", "title": "…", "album": "…", "player": "…",
+//	 "art_url": "file:///…", "status": "playing", "position": 61.2,
+//	 "duration": 215, "updated": 1760600000.5, "source": "lrclib",
+//	 "index": 12, "line": "…",
+//	 "lines": [{"text": "…", "index": 0, "time": 10.5}, …]}
+type State struct {
+	Artist string `json:"artist"`
+	Title  string `json:"title"`
+	Album  string `json:"album,omitempty"`
+	Player string `json:"player,omitempty"`
+	ArtURL string `json:"art_url,omitempty"`
+	// Status is StatusPlaying, StatusPaused or StatusLoading while the
+	// lyrics are fetched.
+	Status string `json:"status"`
+	// Position is the playback position in seconds at Updated, the Unix
+	// time in seconds; a widget adds the time since while playing.
+	Position float64 `json:"position"`
+	Updated  float64 `json:"updated"`
+	// Duration is the track length in seconds, or 0 when unknown.
+	Duration float64 `json:"duration"`
+	// Source names where the lyrics came from, e.g. "lrclib".
+	Source string `json:"source,omitempty"`
+	// Index is the current line's index in Lines, or -1 for none, and
+	// Line its text.
+	Index int    `json:"index"`
+	Line  string `json:"line"`
+	Lines []Line `json:"lines"`
+	// Error is the message of an error keeping lyrics from Lines.
+	Error string `json:"error,omitempty"`
+}
+
+// State statuses.
+const (
+	StatusPlaying = "playing"
+	StatusPaused  = "paused"
+	StatusLoading = "loading"
+)
+
+// NewState returns the state of u, updated at now.
+func NewState(u pool.Update, now time.Time) State {
+	snap := NewSnapshot(u)
+	s := State{
+		Artist:   u.Track.Artist,
+		Title:    u.Track.Title,
+		Album:    u.Track.Album,
+		Player:   u.Track.Player,
+		ArtURL:   u.Track.ArtURL,
+		Status:   StatusPaused,
+		Position: u.Position,
+		Updated:  float64(now.UnixMilli()) / 1000,
+		Duration: u.Duration,
+		Source:   u.Source,
+		Index:    snap.Index,
+		Lines:    snap.Lines,
+		Error:    snap.Error,
+	}
+	switch {
+	case u.Loading:
+		s.Status = StatusLoading
+	case u.Playing:
+		s.Status = StatusPlaying
+	}
+	if s.Index >= 0 {
+		s.Line = s.Lines[s.Index].Text
+	}
+	return s
+}
@@ -0,0 +1,39 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+func TestStateGolden(t *testing.T) {
+	track := mpris.TrackMetadata{Title: "Song", Artist: "Band", Album: "Record", Player: "org.mpris.MediaPlayer2.spotify", ArtURL: "file:///tmp/cover.jpg"}
+	lines := []lyrics.LyricLine{{Time: 10.5, Text: "one"}, {Time: 20, Text: "two <&>", Translation: "deux"}, {Time: 30.25, Text: "three"}}
+	now := time.Unix(1760600000, 500e6)
+	states := []struct {
+		name string
+		u    pool.Update
+	}{
+		{"playing", pool.Update{State: pool.StateReady, Track: track, Lines: lines, Index: 1, Playing: true, Position: 21.5, Duration: 215, Source: "lrclib"}},
+		{"paused before the first line", pool.Update{State: pool.StateReady, Track: track, Lines: lines, Index: -1, Position: 3, Duration: 215, Source: "lrclib"}},
+		{"loading", pool.Update{State: pool.StateFetching, Track: track, Index: -1, Playing: true, Loading: true, Position: 0.5, Duration: 215}},
+		{"error", pool.Update{State: pool.StateError, Track: track, Index: -1, Playing: true, Position: 12, Err: errors.New("lrclib: 503 Service Unavailable")}},
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	for _, s := range states {
+		b.WriteString("# " + s.name + "\n")
+		if err := enc.Encode(NewState(s.u, now)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkGolden(t, "state.golden", b.Bytes())
+}
@@ -0,0 +1,63 @@
+package output
+
+import (
+	"encoding/json"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// Waybar module classes.
+const (
+	ClassPlaying  = "playing"
+	ClassPaused   = "paused"
+	ClassNoLyrics = "no-lyrics"
+)
+
+// WaybarContext is how many lines before and after the current one the
+// Waybar tooltip shows.
+const WaybarContext = 2
+
+// Waybar is the object a Waybar custom module with "return-type": "json"
+// reads. Text and Tooltip are Pango markup, so the module must not set
+// "escape".
+type Waybar struct {
+	Text    string `json:"text"`
+	Tooltip string `json:"tooltip"`
+	Class   string `json:"class"`
+}
+
+// NewWaybar returns the Waybar object for u. The text is the current line,
+// or while paused *pause when pause is set, cut to width; it is empty,
+// never missing, when there is nothing to show.
+func NewWaybar(u pool.Update, width Width, pause *string) Waybar {
+	if u.Err != nil || u.Loading || len(u.Lines) == 0 {
+		return Waybar{Class: ClassNoLyrics}
+	}
+	w := Waybar{Class: ClassPaused}
+	if u.Playing {
+		w.Class = ClassPlaying
+	}
+	if text, ok := CurrentText(u, pause); ok {
+		w.Text = html.EscapeString(width.Cut(text))
+	}
+	var tooltip []string
+	for i := max(0, u.Index-WaybarContext); i <= u.Index+WaybarContext && i < len(u.Lines); i++ {
+		line := html.EscapeString(u.Lines[i].Text)
+		if i == u.Index {
+			line = "<b>" + line + "</b>"
+		}
+		tooltip = append(tooltip, line)
+	}
+	w.Tooltip = strings.Join(tooltip, "\n")
+	return w
+}
+
+// WriteWaybar writes w as a single JSON line.
+func WriteWaybar(wr io.Writer, w Waybar) error {
+	enc := json.NewEncoder(wr)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(w)
+}
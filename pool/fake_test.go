@@ -0,0 +1,321 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+)
+
+// fakePlayer is a scripted player for running a pool without a session
+// bus. Its position advances with the wall clock at its rate while it
+// plays, and every change the test makes is signalled to the watcher, as
+// an MPRIS player's PropertiesChanged would be.
+type fakePlayer struct {
+	mu       sync.Mutex
+	track    mpris.TrackMetadata
+	duration float64
+	position float64 // at at
+	at       time.Time
+	rate     float64
+	playing  bool
+	// err is what every call returns, e.g. the player gone
+	err error
+	// silent makes Watch fail, so that the pool polls
+	silent bool
+	// loop plays the track over and over, as with LoopStatus Track
+	loop    bool
+	changed chan<- struct{}
+	// unsent is a change made before Watch, signalled once it is called
+	unsent bool
+}
+
+// newFakePlayer returns a player playing track from its start.
+func newFakePlayer(track mpris.TrackMetadata, duration float64) *fakePlayer {
+	return &fakePlayer{track: track, duration: duration, at: time.Now(), rate: 1, playing: true}
+}
+
+// positionLocked returns the position now; the lock must be held.
+func (f *fakePlayer) positionLocked() float64 {
+	if !f.playing {
+		return f.position
+	}
+	position := f.position + time.Since(f.at).Seconds()*f.rate
+	if f.loop && f.duration > 0 {
+		position = math.Mod(position, f.duration)
+	}
+	return position
+}
+
+// change applies fn to the player, its position brought up to now, and
+// signals the change.
+func (f *fakePlayer) change(fn func(f *fakePlayer)) {
+	f.mu.Lock()
+	f.position, f.at = f.positionLocked(), time.Now()
+	fn(f)
+	changed := f.changed
+	f.unsent = changed == nil
+	f.mu.Unlock()
+	if changed != nil {
+		signal(changed)
+	}
+}
+
+// signal sends on changed unless a signal is waiting there already.
+func signal(changed chan<- struct{}) {
+	select {
+	case changed <- struct{}{}:
+	default:
+	}
+}
+
+func (f *fakePlayer) seek(position float64) {
+	f.change(func(f *fakePlayer) { f.position = position })
+}
+
+func (f *fakePlayer) pause() {
+	f.change(func(f *fakePlayer) { f.playing = false })
+}
+
+func (f *fakePlayer) play() {
+	f.change(func(f *fakePlayer) { f.playing = true })
+}
+
+func (f *fakePlayer) setRate(rate float64) {
+	f.change(func(f *fakePlayer) { f.rate = rate })
+}
+
+// setTrack moves the player on to track, playing from its start.
+func (f *fakePlayer) setTrack(track mpris.TrackMetadata, duration float64) {
+	f.change(func(f *fakePlayer) {
+		f.track, f.duration, f.position, f.playing = track, duration, 0, true
+	})
+}
+
+// vanish takes the player off the bus; comeBack brings it back.
+func (f *fakePlayer) vanish() {
+	f.change(func(f *fakePlayer) { f.err = fmt.Errorf("fake: %w", mpris.ErrNoPlayer) })
+}
+
+// comeBack puts the player back on the bus, playing track from its start.
+func (f *fakePlayer) comeBack(track mpris.TrackMetadata, duration float64) {
+	f.change(func(f *fakePlayer) {
+		f.err, f.track, f.duration, f.position, f.playing = nil, track, duration, 0, true
+	})
+}
+
+// now returns the player's position.
+func (f *fakePlayer) now() float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.positionLocked()
+}
+
+func (f *fakePlayer) GetMetadata(ctx context.Context) (*mpris.TrackMetadata, float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, 0, f.err
+	}
+	track := f.track
+	return &track, f.duration, nil
+}
+
+func (f *fakePlayer) GetRate(ctx context.Context) (float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rate, f.err
+}
+
+func (f *fakePlayer) GetPositionAndStatus(ctx context.Context) (float64, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return 0, "", f.err
+	}
+	if !f.playing {
+		return f.position, "Paused", nil
+	}
+	return f.positionLocked(), "Playing", nil
+}
+
+func (f *fakePlayer) Watch(ctx context.Context, changed chan<- struct{}) error {
+	if f.silent {
+		return errors.New("fake: no signals")
+	}
+	f.mu.Lock()
+	f.changed = changed
+	if f.unsent {
+		f.unsent = false
+		signal(changed)
+	}
+	f.mu.Unlock()
+	<-ctx.Done()
+	f.mu.Lock()
+	f.changed = nil
+	f.mu.Unlock()
+	return nil
+}
+
+// fakeBus is the active one of several fake players, as playerctld
+// makes one: it answers as the active player does, and signals a switch
+// as a change.
+type fakeBus struct {
+	mu      sync.Mutex
+	players []*fakePlayer
+	active  int
+	changed chan<- struct{}
+}
+
+func (b *fakeBus) player() *fakePlayer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.players[b.active]
+}
+
+// activate makes player i the active one.
+func (b *fakeBus) activate(i int) {
+	b.mu.Lock()
+	b.active = i
+	changed := b.changed
+	b.mu.Unlock()
+	if changed != nil {
+		signal(changed)
+	}
+}
+
+func (b *fakeBus) GetMetadata(ctx context.Context) (*mpris.TrackMetadata, float64, error) {
+	return b.player().GetMetadata(ctx)
+}
+
+func (b *fakeBus) GetRate(ctx context.Context) (float64, error) {
+	return b.player().GetRate(ctx)
+}
+
+func (b *fakeBus) GetPositionAndStatus(ctx context.Context) (float64, string, error) {
+	return b.player().GetPositionAndStatus(ctx)
+}
+
+// Watch passes on the changes of every player; one of a player not
+// active costs no more than a needless reading.
+func (b *fakeBus) Watch(ctx context.Context, changed chan<- struct{}) error {
+	b.mu.Lock()
+	b.changed = changed
+	b.mu.Unlock()
+	var wg sync.WaitGroup
+	for _, p := range b.players {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Watch(ctx, changed)
+		}()
+	}
+	wg.Wait()
+	b.mu.Lock()
+	b.changed = nil
+	b.mu.Unlock()
+	return nil
+}
+
+// fakeProvider serves lyrics by title, after delay. A title it has no
+// lyrics for is lyrics.ErrNotFound.
+type fakeProvider struct {
+	mu     sync.Mutex
+	lyrics map[string][]lyrics.LyricLine
+	delay  time.Duration
+	// errs are returned by the first fetches, one each, before the
+	// lyrics are
+	errs  []error
+	calls []fetchCall
+}
+
+// fetchCall is one FetchLyrics call of a fakeProvider.
+type fetchCall struct {
+	title    string
+	duration float64
+}
+
+func newFakeProvider(byTitle map[string][]lyrics.LyricLine) *fakeProvider {
+	return &fakeProvider{lyrics: byTitle}
+}
+
+func (f *fakeProvider) FetchLyrics(title, artist, album string, duration float64) (*lyrics.Lyric, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, fetchCall{title: title, duration: duration})
+	delay := f.delay
+	var err error
+	if len(f.errs) > 0 {
+		err, f.errs = f.errs[0], f.errs[1:]
+	}
+	lines, ok := f.lyrics[title]
+	f.mu.Unlock()
+	time.Sleep(delay)
+	switch {
+	case err != nil:
+		return nil, err
+	case !ok:
+		return nil, lyrics.ErrNotFound
+	}
+	return &lyrics.Lyric{Lines: lines, Source: "fake"}, nil
+}
+
+// fetches returns the calls made so far.
+func (f *fakeProvider) fetches() []fetchCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fetchCall(nil), f.calls...)
+}
+
+// start runs a pool on player and provider until the end of the test,
+// and returns it and its updates. The player is polled every second
+// unless cfg says otherwise.
+func start(t *testing.T, player PlayerSource, provider LyricProvider, cfg Config) (*Pool, chan Update) {
+	t.Helper()
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(player, provider, cfg)
+	ch := make(chan Update, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Run(ctx, ch)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return p, ch
+}
+
+// waitFor returns the first update from ch that cond holds for, failing
+// the test after a second without one.
+func waitFor(t *testing.T, ch <-chan Update, what string, cond func(Update) bool) Update {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case u, ok := <-ch:
+			if !ok {
+				t.Fatalf("waiting for %s: the pool stopped", what)
+			}
+			if cond(u) {
+				return u
+			}
+		case <-timeout:
+			t.Fatalf("no update %s within a second", what)
+		}
+	}
+}
+
+// atLine is the condition of an update showing line index of the lyrics.
+func atLine(index int) func(Update) bool {
+	return func(u Update) bool { return u.State == StateReady && u.Index == index }
+}
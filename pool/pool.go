@@ -1,44 +1,374 @@
 package pool
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/best8oy/LyricsMPRIS/lyrics"
 	"github.com/best8oy/LyricsMPRIS/mpris"
 )
 
+// State is where the lyrics of an Update stand.
+type State int
+
+// Update states.
+const (
+	// StateIdle is a player with no track, or one without title and
+	// artist to look lyrics up by.
+	StateIdle State = iota
+	// StateWaitingForPlayer is no player on the bus; Err wraps
+	// mpris.ErrNoPlayer.
+	StateWaitingForPlayer
+	// StateFetching is the lyrics of the track being fetched.
+	StateFetching
+	// StateReady is the track's lyrics in Lines.
+	StateReady
+	// StateNotFound is a track without synced lyrics; Err wraps
+	// lyrics.ErrNotFound.
+	StateNotFound
+	// StateError is a failure of the player or the lyric fetch, in Err.
+	StateError
+	// StateEnded is a track with lyrics played to within endMargin of its
+	// end. Lines is left empty, so that the last line does not stay
+	// current until the player moves on to the next track; the lyrics are
+	// back should the track start over.
+	StateEnded
+	// StateStopped is the last update of a pool whose context is done;
+	// the channel is closed after it.
+	StateStopped
+)
+
+func (s State) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StateWaitingForPlayer:
+		return "waiting for player"
+	case StateFetching:
+		return "fetching"
+	case StateReady:
+		return "ready"
+	case StateNotFound:
+		return "not found"
+	case StateError:
+		return "error"
+	case StateEnded:
+		return "ended"
+	case StateStopped:
+		return "stopped"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
 // Update represents the state of the lyrics and player.
 type Update struct {
-	Lines   []lyrics.LyricLine
+	// State is where the lyrics stand; the other fields hold what it
+	// says there is. It is set anew for every update, so nothing of a
+	// track left behind carries over.
+	State State
+	Lines []lyrics.LyricLine
+	// Index is the line of Lines due at Position plus Config.Lead, or -1
+	// before the first line and without lines.
 	Index   int
 	Playing bool
-	Err     error
+	// Err is the error behind StateWaitingForPlayer, StateNotFound and
+	// StateError, and nil otherwise.
+	Err error
+	// Position is the playback position in seconds at the time of the update.
+	Position float64
+	// Rate is the playback rate Position advances at while Playing, 1 at
+	// normal speed; see PositionAfter.
+	Rate float64
+	// Duration is the track length in seconds, or 0 when unknown (e.g. streams).
+	Duration float64
+	// Track is the metadata of the current track.
+	Track mpris.TrackMetadata
+	// Loading is set while lyrics for Track are being fetched, in
+	// StateFetching.
+	Loading bool
+	// Source names where Lines came from, e.g. "lrclib".
+	Source string
+	// Estimated is set when Position was advanced by the wall clock since
+	// the player last reported it.
+	Estimated bool
+	// Failures counts the failed lyric fetches for Track.
+	Failures int
+	// Seeked is set on the first update after the playback position
+	// jumped rather than advanced, whether the player signalled a seek or
+	// not, or the lyric offset changed; Index was looked up afresh for it.
+	Seeked bool
+	// Offset is the track's lyric offset in seconds, added to Position with
+	// Config.Lead to select Index; see Config.Offsets.
+	Offset float64
+	// Restarted is set, with Seeked, when the seek took the track back to
+	// its start: it is playing again, e.g. looping, and every line is due
+	// once more.
+	Restarted bool
+}
+
+// PositionAfter returns the playback position d after the update: Position
+// advanced at Rate while Playing. An update without a Rate, as MPRIS never
+// reports, advances at 1.
+func (u Update) PositionAfter(d time.Duration) float64 {
+	if !u.Playing {
+		return u.Position
+	}
+	rate := u.Rate
+	if rate == 0 {
+		rate = 1
+	}
+	return u.Position + d.Seconds()*rate
 }
 
 type playerState struct {
-	Title    string
-	Artist   string
-	Album    string
-	Playing  bool
+	Title   string
+	Artist  string
+	Album   string
+	Player  string
+	ArtURL  string
+	Bus     string
+	Playing bool
+	// Position is the playback position read at At, which advances at
+	// Rate while playing; see positionAt.
 	Position float64
+	At       time.Time
+	Rate     float64
+	Duration float64
 	Err      error
 }
 
-// Listen polls for player and lyrics updates and writes them to the channel.
-func Listen(ctx context.Context, ch chan Update, pollInterval time.Duration) {
+// positionAt returns the playback position at now: the position read,
+// advanced by the time since at the playback rate while playing and
+// frozen while paused. now and At carry monotonic clock readings, so
+// wall clock jumps do not move it.
+func (s playerState) positionAt(now time.Time) float64 {
+	if !s.Playing || s.At.IsZero() {
+		return s.Position
+	}
+	return s.Position + now.Sub(s.At).Seconds()*s.Rate
+}
+
+// PlayerSource is the player Listen follows; mpris.NewClient returns the
+// one on the session bus.
+type PlayerSource interface {
+	GetMetadata(ctx context.Context) (*mpris.TrackMetadata, float64, error)
+	GetRate(ctx context.Context) (float64, error)
+	GetPositionAndStatus(ctx context.Context) (float64, string, error)
+	// Watch sends on changed whenever the player reports a change, until
+	// ctx is done; see mpris.Watch.
+	Watch(ctx context.Context, changed chan<- struct{}) error
+}
+
+// LyricProvider fetches the lyrics Listen shows; lyrics.NewFetcher returns
+// the one querying lrclib.
+type LyricProvider interface {
+	FetchLyrics(title, artist, album string, duration float64) (*lyrics.Lyric, error)
+}
+
+// DefaultDebounce is how long a new track must stay current before its
+// lyrics are fetched.
+const DefaultDebounce = 400 * time.Millisecond
+
+// Config configures Listen.
+type Config struct {
+	// PollInterval is how often the player is polled when it sends no
+	// signals.
+	PollInterval time.Duration
+	// Retry refetches the current track's lyrics on every value; it may
+	// be nil.
+	Retry <-chan struct{}
+	// Offsets shifts the current track's lyric offset by each value, in
+	// seconds, moving the lines earlier for a positive one; it may be nil.
+	// Each track keeps its own offset, back again when it is played again.
+	Offsets <-chan float64
+	// Lead selects lines this far ahead of the playback position, to make
+	// up for audio latency; a negative lead delays them.
+	Lead time.Duration
+	// Debounce is how long a new track must stay current before its
+	// lyrics are fetched, so skipping through tracks fetches only the
+	// last one. The track is announced at once regardless. Zero fetches
+	// at once.
+	Debounce time.Duration
+	// Verbose logs diagnostics, e.g. how often the player is read, to
+	// stderr.
+	Verbose bool
+	// Early also sends an update this long before each line is due, for
+	// consumers that show lines early (ui.Options.EmitEarly); Index
+	// stays on the line still playing.
+	Early time.Duration
+}
+
+// Pool follows a player and the lyrics of its tracks. Besides the updates
+// Run sends, it keeps the latest for Snapshot and WaitReady, which any
+// goroutine may call.
+type Pool struct {
+	player   PlayerSource
+	provider LyricProvider
+	cfg      Config
+
+	mu   sync.Mutex
+	last Update
+	sent time.Time // when last was sent
+	// ready is closed at the first update neither waiting for a player
+	// nor fetching lyrics
+	ready chan struct{}
+}
+
+// New returns a pool following player, with the lyrics provider finds for
+// its tracks. It does nothing until Run.
+func New(player PlayerSource, provider LyricProvider, cfg Config) *Pool {
+	return &Pool{
+		player:   player,
+		provider: provider,
+		cfg:      cfg,
+		last:     Update{Index: -1},
+		ready:    make(chan struct{}),
+	}
+}
+
+// Listen runs a new pool; see New and Run.
+func Listen(ctx context.Context, player PlayerSource, provider LyricProvider, ch chan Update, cfg Config) {
+	New(player, provider, cfg).Run(ctx, ch)
+}
+
+// Snapshot returns the latest update, with Position and Index advanced to
+// now while playing. Before the first update it returns an idle one.
+func (p *Pool) Snapshot() Update {
+	p.mu.Lock()
+	u, sent := p.last, p.sent
+	p.mu.Unlock()
+	u.Seeked, u.Restarted = false, false // news only when sent
+	if u.Playing && !sent.IsZero() {
+		u.Position = u.PositionAfter(time.Since(sent))
+		u.Index = IndexAt(u.Lines, u.Position+p.cfg.Lead.Seconds()+u.Offset)
+		u.Estimated = true
+	}
+	return u
+}
+
+// WaitReady waits for the first update neither waiting for a player nor
+// fetching lyrics, and returns the snapshot then; or ctx's error once it
+// is done.
+func (p *Pool) WaitReady(ctx context.Context) (Update, error) {
+	select {
+	case <-p.ready:
+		return p.Snapshot(), nil
+	case <-ctx.Done():
+		return Update{}, ctx.Err()
+	}
+}
+
+// publish keeps u, sent at now, for Snapshot.
+func (p *Pool) publish(u Update, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.last, p.sent = u, now
+	if u.State == StateWaitingForPlayer || u.State == StateFetching {
+		return
+	}
+	select {
+	case <-p.ready:
+	default:
+		close(p.ready)
+	}
+}
+
+// Run follows the player and lyrics until ctx is done, and offers their
+// updates to ch, which must be a mailbox with a buffer of one; see Offer.
+// A consumer too busy to take each update gets the latest when it is
+// ready, and never holds the pool up. The player is watched for changes
+// and polled every cfg.PollInterval only when it sends no signals.
+// Between changes Run sleeps until the next line is due. A pool runs
+// once.
+//
+// Once ctx is done Run stops following the player, offers a StateStopped
+// update and closes ch, so a consumer ranging over it ends. A lyric fetch
+// in flight is left to end on its own, its result dropped.
+func (p *Pool) Run(ctx context.Context, ch chan Update) {
+	player, provider, cfg := p.player, p.provider, p.cfg
 	stateCh := make(chan playerState)
-	go listenPlayer(ctx, stateCh, pollInterval)
+	playerDone := make(chan struct{})
+	go func() {
+		defer close(playerDone)
+		listenPlayer(ctx, player, stateCh, cfg.PollInterval, cfg.Verbose)
+	}()
+	defer func() {
+		<-playerDone
+		u := Update{State: StateStopped, Index: -1}
+		p.publish(u, time.Now())
+		Offer(ch, u)
+		close(ch)
+	}()
 
-	ticker := time.NewTicker(pollInterval)
-	defer ticker.Stop()
+	// the next line, or its early update, waits for wake
+	wake := time.NewTimer(0)
+	wake.Stop()
+	defer wake.Stop()
+	var (
+		wakeDue   <-chan time.Time // wake.C while playing through lyrics
+		wakeEarly bool             // the wake is for an early update
+	)
+	// a fetch put off, by the debounce or until a retry, waits for
+	// fetchTimer
+	fetchTimer := time.NewTimer(0)
+	fetchTimer.Stop()
+	var fetchDue <-chan time.Time // fetchTimer.C while a fetch waits
 
 	var (
-		state      playerState
-		index      int
-		lines      []lyrics.LyricLine
-		lastUpdate time.Time
+		state     playerState
+		index     = -1
+		lines     []lyrics.LyricLine
+		source    string
+		fetchErr  error
+		failures  int
+		estimated bool
+		loading   bool
+		seek      bool
+		restart   bool
+		ended     bool // the track played to its end
+		offset    float64
+		// generation counts the lyric fetches started or given up on; a
+		// result is only taken from the latest
+		generation int
+		retryDelay = minRetryDelay
 	)
+	results := make(chan fetchResult)
+	// players keeps the lyrics of each player's track by bus name
+	players := make(map[string]recentLyrics)
+	// offsets keeps the lyric offset of each track it is not zero for
+	offsets := make(map[trackKey]float64)
+
+	// expect clears the lyrics for those of a new track, or none when
+	// loading is false, discarding any fetch in flight.
+	expect := func(load bool) {
+		generation++
+		lines, source, fetchErr, index, loading = nil, "", nil, -1, load
+		fetchTimer.Stop()
+		fetchDue = nil
+	}
+	putOff := func(d time.Duration) {
+		fetchTimer.Reset(d)
+		fetchDue = fetchTimer.C
+	}
+	// fetch fetches st's lyrics in the background, so updates go on while
+	// lrclib takes its time.
+	fetch := func(st playerState) {
+		expect(true)
+		go func(gen int) {
+			lyric, err := provider.FetchLyrics(st.Title, st.Artist, st.Album, st.Duration)
+			select {
+			case results <- fetchResult{generation: gen, lyric: lyric, err: err}:
+			case <-ctx.Done():
+			}
+		}(generation)
+	}
 
 	for {
 		changed := false
@@ -47,92 +377,506 @@ func Listen(ctx context.Context, ch chan Update, pollInterval time.Duration) {
 		case <-ctx.Done():
 			return
 		case newState := <-stateCh:
-			lastUpdate = time.Now()
-			if newState.Title != state.Title || newState.Artist != state.Artist || newState.Album != state.Album {
+			newTrack := !sameTrack(newState, state)
+			if newTrack {
 				changed = true
-				if newState.Title != "" && newState.Artist != "" {
-					lyric, err := lyrics.FetchLyrics(newState.Title, newState.Artist, newState.Album, newState.Position)
-					if err != nil {
-						state.Err = err
-						lines = nil
-					} else if lyric != nil {
-						lines = lyric.Lines
-						state.Err = nil
-					}
-				} else {
-					lines = nil
+				failures, retryDelay = 0, minRetryDelay
+				offset = offsets[keyOf(newState)]
+				recent, known := players[newState.Bus]
+				switch {
+				case newState.Title == "" || newState.Artist == "":
+					expect(false)
+				case known && sameTrack(recent.state, newState):
+					// a player made active again, on the track it was
+					// left on: all of it shows in one update
+					expect(false)
+					lines, source = recent.lines, recent.source
+				case cfg.Debounce > 0:
+					expect(true)
+					putOff(cfg.Debounce)
+				default:
+					fetch(newState)
 				}
-				index = 0
 			}
-			if newState.Playing != state.Playing {
+			if newState.Playing != state.Playing || newState.Duration != state.Duration || !sameErr(newState.Err, state.Err) {
 				changed = true
 			}
+			if !newTrack && drift(state, newState) > driftTolerance.Seconds() {
+				changed = true
+			}
+			if !newTrack && seeked(state, newState) {
+				changed, seek = true, true
+				// a track looping comes back with the same metadata
+				restart = newState.Position < state.positionAt(newState.At) && newState.Position < restartWindow.Seconds()
+			}
 			state = newState
-		case <-ticker.C:
-			if !state.Playing || len(lines) == 0 {
+			estimated = false
+		case <-fetchDue:
+			changed = !loading // a retry, rather than the debounce ending
+			fetch(state)
+		case r := <-results:
+			if r.generation != generation {
+				break // for a track since left
+			}
+			lines, source, fetchErr, index, loading = nil, "", r.err, -1, false
+			if r.err != nil {
+				failures++
+				if transient(r.err) {
+					// the network may be back by then
+					putOff(retryDelay)
+					retryDelay = min(2*retryDelay, maxRetryDelay)
+				}
+			} else if r.lyric != nil {
+				lines, source = r.lyric.Lines, r.lyric.Source
+				if _, ok := players[state.Bus]; !ok && len(players) >= maxRecentPlayers {
+					clear(players) // players come and go seldom
+				}
+				players[state.Bus] = recentLyrics{state: state, lines: lines, source: source}
+			}
+			changed = true
+		case d := <-cfg.Offsets:
+			// kept to the millisecond, so steps never add up to a stray
+			// fraction
+			offset = math.Round((offset+d)*1000) / 1000
+			key := keyOf(state)
+			if _, ok := offsets[key]; !ok && len(offsets) >= maxTrackOffsets {
+				clear(offsets) // offsets are set seldom
+			}
+			if offset == 0 {
+				delete(offsets, key)
+			} else {
+				offsets[key] = offset
+			}
+			changed, seek = true, true
+		case <-cfg.Retry:
+			if state.Title == "" || state.Artist == "" || loading {
 				break
 			}
-			now := time.Now()
-			state.Position += now.Sub(lastUpdate).Seconds()
-			lastUpdate = now
+			fetch(state)
+			changed = true
+		case <-wakeDue:
+			estimated = true
+			changed = wakeEarly
 		}
 
-		newIndex := getIndex(state.Position, index, lines)
+		now := time.Now()
+		position := state.positionAt(now)
+		lyricPosition := position + cfg.Lead.Seconds() + offset
+		newIndex := getIndex(lyricPosition, index, lines)
+		if seek {
+			// the walk from index is for playback moving on
+			newIndex = IndexAt(lines, lyricPosition)
+		}
 		if newIndex != index {
 			changed = true
 			index = newIndex
 		}
+		// players take a while to move on to the next track, or stop at
+		// the end of the last
+		atEnd := state.Duration > 0 && position >= state.Duration-endMargin.Seconds()
+		if atEnd != ended {
+			changed = true
+			ended = atEnd
+		}
 
 		if changed {
-			ch <- Update{
-				Lines:   lines,
-				Index:   index,
-				Playing: state.Playing,
-				Err:     state.Err,
+			st, err := stateOf(state, loading, lines, fetchErr)
+			shown, shownIndex := lines, index
+			if st == StateReady && ended {
+				st, shown, shownIndex = StateEnded, nil, -1
+			}
+			u := Update{
+				State:     st,
+				Lines:     shown,
+				Index:     shownIndex,
+				Playing:   state.Playing,
+				Err:       err,
+				Position:  position,
+				Rate:      state.Rate,
+				Duration:  state.Duration,
+				Track:     state.track(),
+				Loading:   st == StateFetching,
+				Source:    source,
+				Estimated: estimated,
+				Failures:  failures,
+				Seeked:    seek,
+				Offset:    offset,
+				Restarted: restart,
+			}
+			p.publish(u, now)
+			Offer(ch, u)
+		}
+		seek, restart = false, false
+
+		// sleep until the next line, or the end of the track; seeks,
+		// pauses and the like come through stateCh and wake the loop
+		// themselves
+		wake.Stop()
+		wakeDue = nil
+		if state.Playing {
+			d, early, ok := untilNext(lyricPosition, index, lines, state.Rate, cfg.Early)
+			if e, endOK := untilEnd(position, state.Duration, state.Rate); endOK && len(lines) > 0 && !ended && (!ok || e < d) {
+				d, early, ok = e, false, true
+			}
+			if ok {
+				wake.Reset(d)
+				wakeDue, wakeEarly = wake.C, early
 			}
 		}
 	}
 }
 
-func listenPlayer(ctx context.Context, ch chan playerState, interval time.Duration) {
+// untilNext returns how long, at the playback rate, until the line after
+// index is due at position, or until its early update is when that comes
+// first, as reported by early. It reports false when no line follows.
+func untilNext(position float64, index int, lines []lyrics.LyricLine, rate float64, early time.Duration) (d time.Duration, isEarly, ok bool) {
+	if index+1 >= len(lines) || rate <= 0 {
+		return 0, false, false
+	}
+	ahead := lines[index+1].Time - position
+	if early > 0 && ahead > early.Seconds() {
+		ahead, isEarly = ahead-early.Seconds(), true
+	}
+	// a hair late, so the position has surely reached the line
+	return time.Duration(ahead/rate*float64(time.Second)) + time.Millisecond, isEarly, true
+}
+
+// untilEnd returns how long, at the playback rate, until position is
+// within endMargin of the end of a track duration seconds long. It
+// reports false when the duration is unknown.
+func untilEnd(position, duration, rate float64) (time.Duration, bool) {
+	if duration <= 0 || rate <= 0 {
+		return 0, false
+	}
+	ahead := max(duration-endMargin.Seconds()-position, 0)
+	return time.Duration(ahead/rate*float64(time.Second)) + time.Millisecond, true
+}
+
+// endMargin is how close to its end a track counts as played to the end:
+// players seldom report the very end before moving on.
+const endMargin = 500 * time.Millisecond
+
+// Automatic retries of lyric fetches failed for want of lrclib back off
+// from minRetryDelay to maxRetryDelay.
+const (
+	minRetryDelay = 5 * time.Second
+	maxRetryDelay = 2 * time.Minute
+)
+
+// transient reports whether a lyric fetch failed with err may succeed
+// when tried again: lrclib was unreachable, rather than without lyrics.
+func transient(err error) bool {
+	return errors.Is(err, lyrics.ErrUnreachable)
+}
+
+// stateOf returns the State of an update for the player state st and the
+// lyric fetch, and the error behind it. Player errors take precedence over
+// the fetch's.
+func stateOf(st playerState, loading bool, lines []lyrics.LyricLine, fetchErr error) (State, error) {
+	switch {
+	case errors.Is(st.Err, mpris.ErrNoPlayer):
+		return StateWaitingForPlayer, st.Err
+	case st.Err != nil:
+		return StateError, st.Err
+	case st.Title == "" || st.Artist == "":
+		return StateIdle, nil
+	case loading:
+		return StateFetching, nil
+	case errors.Is(fetchErr, lyrics.ErrNotFound):
+		return StateNotFound, fetchErr
+	case fetchErr != nil:
+		return StateError, fetchErr
+	case len(lines) == 0:
+		return StateNotFound, lyrics.ErrNotFound
+	}
+	return StateReady, nil
+}
+
+// fetchResult is the outcome of a lyric fetch started by Listen.
+type fetchResult struct {
+	generation int
+	lyric      *lyrics.Lyric
+	err        error
+}
+
+// Offer puts u in the mailbox ch, a channel with a buffer of one,
+// replacing the update waiting there if the consumer has yet to take it.
+// The sender never waits, and the consumer always gets the freshest state;
+// each Update is whole, so one replaced is no loss. Only a seek or restart
+// it reported is news, and u carries it on for the same track.
+func Offer(ch chan Update, u Update) {
 	for {
 		select {
-		case <-ctx.Done():
+		case ch <- u:
 			return
 		default:
 		}
-		meta, _, err := mpris.GetMetadata(ctx)
-		pos, status, err2 := mpris.GetPositionAndStatus(ctx)
-		st := playerState{Err: err}
-		if err == nil && meta != nil && err2 == nil {
-			st.Title = meta.Title
-			st.Artist = meta.Artist
-			st.Album = meta.Album
-			st.Playing = status == "Playing"
-			st.Position = pos
+		select {
+		case old := <-ch: // stale by now
+			if old.Track == u.Track {
+				u.Seeked = u.Seeked || old.Seeked
+				u.Restarted = u.Restarted || old.Restarted
+			}
+		default:
 		}
-		ch <- st
-		time.Sleep(interval)
 	}
 }
 
-// getIndex returns the index of the current lyric line based on position.
-func getIndex(position float64, curIndex int, lines []lyrics.LyricLine) int {
-	if len(lines) <= 1 {
-		return 0
+// sameErr reports whether two player errors describe the same condition.
+func sameErr(a, b error) bool {
+	if a == nil || b == nil {
+		return a == b
 	}
-	if position >= lines[curIndex].Time {
-		for i := curIndex + 1; i < len(lines); i++ {
-			if position < lines[i].Time {
-				return i - 1
+	return a.Error() == b.Error()
+}
+
+func (s playerState) track() mpris.TrackMetadata {
+	return mpris.TrackMetadata{Title: s.Title, Artist: s.Artist, Album: s.Album, Player: s.Player, ArtURL: s.ArtURL, Bus: s.Bus}
+}
+
+// sameTrack reports whether a and b are the same track of the same player.
+func sameTrack(a, b playerState) bool {
+	return a.Title == b.Title && a.Artist == b.Artist && a.Album == b.Album && a.Bus == b.Bus
+}
+
+// trackKey identifies a track whichever player plays it, for its lyric
+// offset.
+type trackKey struct {
+	title, artist, album string
+}
+
+func keyOf(s playerState) trackKey {
+	return trackKey{title: s.Title, artist: s.Artist, album: s.Album}
+}
+
+// maxTrackOffsets is how many tracks Listen keeps the lyric offsets of.
+const maxTrackOffsets = 256
+
+// maxRecentPlayers is how many players Listen keeps the lyrics of.
+const maxRecentPlayers = 8
+
+// recentLyrics is the lyrics fetched for the track a player was last on,
+// to show at once when it is made active again.
+type recentLyrics struct {
+	state  playerState
+	lines  []lyrics.LyricLine
+	source string
+}
+
+const (
+	// resyncInterval is how often the player state is fetched while the
+	// watcher reports its changes, correcting the position's drift.
+	resyncInterval = 10 * time.Second
+	// settleDelay lets a burst of signals, e.g. Metadata and then
+	// PlaybackStatus at a track change, end before the state is fetched.
+	settleDelay = 20 * time.Millisecond
+	// seekTolerance is how far a fetched position may stray from the
+	// expected one before it counts as a seek.
+	seekTolerance = 1500 * time.Millisecond
+	// driftTolerance is how far it may stray before an update goes out
+	// with the corrected position, for consumers advancing it themselves.
+	driftTolerance = 100 * time.Millisecond
+	// metadataInterval is how long the metadata may go without being
+	// fetched, in case a track changed without a reading to show it.
+	metadataInterval = 10 * time.Second
+	// statsInterval is how often the reading counts are logged.
+	statsInterval = time.Minute
+	// restartWindow is how near its start a seek backwards must land for
+	// the track to count as restarted.
+	restartWindow = 3 * time.Second
+)
+
+// listenPlayer sends the player state when the MPRIS watcher reports a
+// change, and every resyncInterval. Until the watcher has shown it works,
+// and from when a fetch finds a change it failed to report (a player
+// that sends no signals), it polls every interval instead. Only a signal,
+// a reading that looks like a new track or metadataInterval passing has
+// the metadata fetched again; other readings take position and status.
+// When verbose it logs how many readings there were, and how many
+// fetched the metadata, every statsInterval.
+func listenPlayer(ctx context.Context, player PlayerSource, ch chan playerState, interval time.Duration, verbose bool) {
+	changed := make(chan struct{}, 1)
+	errs := make(chan error, 1)
+	go func() { errs <- player.Watch(ctx, changed) }()
+	watchErr := (<-chan error)(errs) // nil once the watcher is gone
+	defer func() {
+		if watchErr != nil {
+			<-watchErr // the watcher ends with ctx
+		}
+	}()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	var stats <-chan time.Time
+	if verbose {
+		t := time.NewTicker(statsInterval)
+		defer t.Stop()
+		stats = t.C
+	}
+	var (
+		last      playerState
+		fetched   time.Time
+		metadata  time.Time // when last had the metadata fetched
+		trusted   bool      // the watcher reports every change
+		readings  int
+		metaReads int
+	)
+	for {
+		signalled := false
+		select {
+		case <-ctx.Done():
+			return
+		case <-stats:
+			log.Printf("mpris: %d player readings in %v, %d of them with metadata", readings, statsInterval, metaReads)
+			readings, metaReads = 0, 0
+			continue
+		case err := <-watchErr:
+			if err != nil {
+				log.Printf("mpris: %v; polling instead", err)
+			}
+			watchErr, trusted = nil, false
+			continue
+		case <-changed:
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(settleDelay):
 			}
+			select {
+			case <-changed:
+			default:
+			}
+			signalled, trusted = true, watchErr != nil
+		case <-timer.C:
+		}
+		st, ok := playerState{}, false
+		if !signalled && time.Since(metadata) < metadataInterval {
+			st, ok = fetchPlayback(ctx, player, last)
 		}
-		return len(lines) - 1
+		if !ok {
+			st, metadata = fetchPlayerState(ctx, player), time.Now()
+			metaReads++
+		}
+		readings++
+		if trusted && !signalled && !fetched.IsZero() && unreported(last, st) {
+			trusted = false
+		}
+		last, fetched = st, time.Now()
+		select {
+		case ch <- st:
+		case <-ctx.Done():
+			return
+		}
+		if trusted {
+			timer.Reset(resyncInterval)
+		} else {
+			timer.Reset(interval)
+		}
+	}
+}
+
+// unreported reports whether st, fetched after prev with no signal
+// between them, has changed in a way the watcher should have reported.
+func unreported(prev, st playerState) bool {
+	if !sameTrack(st, prev) || st.Playing != prev.Playing || st.Rate != prev.Rate || !sameErr(st.Err, prev.Err) {
+		return true
 	}
-	for i := curIndex; i > 0; i-- {
-		if position > lines[i].Time {
+	return seeked(prev, st)
+}
+
+// seeked reports whether the position of st, read after prev with the
+// same track playing, strays from where prev puts it by then.
+func seeked(prev, st playerState) bool {
+	return drift(prev, st) > seekTolerance.Seconds()
+}
+
+// drift returns how far, in seconds, the position of st, read after prev
+// with the same track playing, is from where prev puts it by then. Across
+// a pause or resume, which may have come at any point between the
+// readings (e.g. when polling), anywhere from standing still to playing
+// all along is no drift.
+func drift(prev, st playerState) float64 {
+	if prev.At.IsZero() || st.At.IsZero() {
+		return 0
+	}
+	lo := prev.positionAt(st.At)
+	hi := lo
+	if prev.Playing != st.Playing {
+		lo, hi = prev.Position, prev.Position+st.At.Sub(prev.At).Seconds()*prev.Rate
+	}
+	return max(lo-st.Position, st.Position-hi, 0)
+}
+
+// fetchPlayback updates prev, fetched in full, with the position and status
+// player reads now. It reports false when that fails, or the reading
+// calls for the metadata: the status changed or the position jumped, as
+// at a new track.
+func fetchPlayback(ctx context.Context, player PlayerSource, prev playerState) (playerState, bool) {
+	if prev.Err != nil || prev.At.IsZero() {
+		return prev, false
+	}
+	before := time.Now()
+	pos, status, err := player.GetPositionAndStatus(ctx)
+	at := before.Add(time.Since(before) / 2)
+	st := prev
+	st.Playing = status == "Playing"
+	st.Position, st.At = pos, at
+	if err != nil || st.Playing != prev.Playing || seeked(prev, st) {
+		return prev, false
+	}
+	return st, true
+}
+
+// fetchPlayerState fetches the track and playback state of player.
+func fetchPlayerState(ctx context.Context, player PlayerSource) playerState {
+	meta, duration, err := player.GetMetadata(ctx)
+	rate, err2 := player.GetRate(ctx)
+	// the position is taken to be read halfway through the call
+	before := time.Now()
+	pos, status, err3 := player.GetPositionAndStatus(ctx)
+	at := before.Add(time.Since(before) / 2)
+	// the player may go between the calls: any of them failing is the
+	// player's error, and the track gone with it
+	st := playerState{Err: cmp.Or(err, err2, err3)}
+	if st.Err == nil && meta != nil {
+		st.Title = meta.Title
+		st.Artist = meta.Artist
+		st.Album = meta.Album
+		st.Player = meta.Player
+		st.ArtURL = meta.ArtURL
+		st.Bus = meta.Bus
+		st.Playing = status == "Playing"
+		st.Position, st.At, st.Rate = pos, at, rate
+		st.Duration = duration
+	}
+	return st
+}
+
+// IndexAt returns the index of the lyric line active at position, or -1
+// before the first line and without lines. lines must be in time order,
+// as lyrics.Lyric keeps them; it takes a binary search.
+func IndexAt(lines []lyrics.LyricLine, position float64) int {
+	return sort.Search(len(lines), func(i int) bool { return lines[i].Time > position }) - 1
+}
+
+// getIndex returns what IndexAt does, trying curIndex, the line active
+// before, and the line after it first: while playback moves on one of
+// them is nearly always the answer. curIndex may be out of range for
+// lines, e.g. an index into the track before's.
+func getIndex(position float64, curIndex int, lines []lyrics.LyricLine) int {
+	for i := curIndex; i <= curIndex+1; i++ {
+		if active(lines, i, position) {
 			return i
 		}
 	}
-	return 0
+	return IndexAt(lines, position)
+}
+
+// active reports whether i, a line index or -1, is what IndexAt returns
+// for position.
+func active(lines []lyrics.LyricLine, i int, position float64) bool {
+	if i < -1 || i >= len(lines) {
+		return false
+	}
+	return (i < 0 || lines[i].Time <= position) && (i+1 == len(lines) || position < lines[i+1].Time)
 }
@@ -0,0 +1,825 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+)
+
+func TestGetIndexFirstLine(t *testing.T) {
+	lines := []lyrics.LyricLine{{Time: 5, Text: "one"}, {Time: 8, Text: "two"}}
+	tests := []struct {
+		position float64
+		curIndex int
+		want     int
+	}{
+		{0, -1, -1},
+		{4.99, -1, -1},
+		{5, -1, 0},
+		{6, -1, 0},
+		{0, 0, -1}, // seeked back into the intro
+		{4.99, 1, -1},
+		{0, 5, -1}, // an index into the track before's lines
+	}
+	for _, tt := range tests {
+		if got := getIndex(tt.position, tt.curIndex, lines); got != tt.want {
+			t.Errorf("getIndex(%v, %d) = %d, want %d", tt.position, tt.curIndex, got, tt.want)
+		}
+	}
+	if got := getIndex(3, -1, nil); got != -1 {
+		t.Errorf("getIndex without lines = %d, want -1", got)
+	}
+}
+
+func TestIndexAt(t *testing.T) {
+	lines := []lyrics.LyricLine{{Time: 5, Text: "one"}, {Time: 8, Text: "two"}, {Time: 8, Text: "two again"}, {Time: 12, Text: "three"}}
+	tests := []struct {
+		position float64
+		want     int
+	}{
+		{-1, -1},
+		{0, -1},
+		{4.999, -1}, // before the first line
+		{5, 0},      // exactly on a timestamp
+		{7.999, 0},
+		{8, 2}, // lines sharing a timestamp: the last of them
+		{11.999, 2},
+		{12, 3},
+		{1000, 3}, // after the last
+	}
+	for _, tt := range tests {
+		if got := IndexAt(lines, tt.position); got != tt.want {
+			t.Errorf("IndexAt(%v) = %d, want %d", tt.position, got, tt.want)
+		}
+		for cur := -1; cur <= len(lines); cur++ {
+			if got := getIndex(tt.position, cur, lines); got != tt.want {
+				t.Errorf("getIndex(%v, %d) = %d, want %d", tt.position, cur, got, tt.want)
+			}
+		}
+	}
+	if got := IndexAt(nil, 5); got != -1 {
+		t.Errorf("IndexAt without lines = %d, want -1", got)
+	}
+}
+
+// benchLines returns n lines, one every two seconds.
+func benchLines(n int) []lyrics.LyricLine {
+	lines := make([]lyrics.LyricLine, n)
+	for i := range lines {
+		lines[i] = lyrics.LyricLine{Time: float64(2 * i), Text: "line"}
+	}
+	return lines
+}
+
+func BenchmarkIndexAt(b *testing.B) {
+	for _, n := range []int{100, 5000, 50000} {
+		lines := benchLines(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := range b.N {
+				IndexAt(lines, float64(i%(2*n)))
+			}
+		})
+	}
+}
+
+// BenchmarkGetIndex measures the lookups of playback moving on, a line
+// at a time.
+func BenchmarkGetIndex(b *testing.B) {
+	for _, n := range []int{100, 5000, 50000} {
+		lines := benchLines(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			index := -1
+			for i := range b.N {
+				index = getIndex(float64(i%(2*n)), index, lines)
+			}
+		})
+	}
+}
+
+var song = mpris.TrackMetadata{Title: "Song", Artist: "Band"}
+
+// onTime fails the test unless the player is at most 50ms past time, the
+// update for a line due then having just come.
+func onTime(t *testing.T, player *fakePlayer, time float64) {
+	t.Helper()
+	if late := player.now() - time; late < 0 || late > 0.05 {
+		t.Errorf("line due at %v came at %.3f", time, player.now())
+	}
+}
+
+func TestSessionPlayback(t *testing.T) {
+	lines := []lyrics.LyricLine{{Time: 0.2, Text: "one"}, {Time: 0.4, Text: "two"}, {Time: 0.6, Text: "three"}}
+	player := newFakePlayer(song, 60)
+	provider := newFakeProvider(map[string][]lyrics.LyricLine{"Song": lines})
+	_, ch := start(t, player, provider, Config{})
+
+	u := waitFor(t, ch, "with the lyrics", atLine(-1))
+	if len(u.Lines) != 3 || u.Source != "fake" || u.Track != song || !u.Playing || u.Duration != 60 {
+		t.Errorf("first update with lyrics %+v", u)
+	}
+	for i, l := range lines {
+		waitFor(t, ch, "at "+l.Text, atLine(i))
+		onTime(t, player, l.Time)
+	}
+	if calls := provider.fetches(); len(calls) != 1 {
+		t.Errorf("%d fetches, want 1", len(calls))
+	}
+}
+
+func TestSessionMidSong(t *testing.T) {
+	lines := []lyrics.LyricLine{{Time: 30, Text: "one"}, {Time: 61, Text: "two"}, {Time: 90, Text: "three"}}
+	player := newFakePlayer(song, 215)
+	player.seek(61.2)
+	provider := newFakeProvider(map[string][]lyrics.LyricLine{"Song": lines})
+	_, ch := start(t, player, provider, Config{})
+
+	waitFor(t, ch, "at the line playing", atLine(1))
+	// the lookup is by the track's length, never where playback is
+	if calls := provider.fetches(); len(calls) != 1 || calls[0].duration != 215 {
+		t.Errorf("fetches %+v, want one for a 215s track", calls)
+	}
+}
+
+func TestSessionSeek(t *testing.T) {
+	lines := []lyrics.LyricLine{{Time: 5, Text: "one"}, {Time: 6, Text: "two"}, {Time: 7, Text: "three"}, {Time: 20, Text: "four"}}
+	player := newFakePlayer(song, 60)
+	_, ch := start(t, player, newFakeProvider(map[string][]lyrics.LyricLine{"Song": lines}), Config{})
+	waitFor(t, ch, "with the lyrics", atLine(-1))
+
+	for _, seek := range []struct {
+		to    float64
+		index int
+	}{{6.5, 1}, {20.5, 3}, {5.5, 0}, {1, -1}} {
+		player.seek(seek.to)
+		u := waitFor(t, ch, fmt.Sprintf("after seeking to %v", seek.to), func(u Update) bool { return u.Seeked })
+		if u.Index != seek.index {
+			t.Errorf("seek to %v: line %d, want %d", seek.to, u.Index, seek.index)
+		}
+	}
+}
+
+func TestSessionOffset(t *testing.T) {
+	lines := []lyrics.LyricLine{{Time: 5, Text: "one"}, {Time: 6, Text: "two"}, {Time: 7, Text: "three"}, {Time: 20, Text: "four"}}
+	other := mpris.TrackMetadata{Title: "Other", Artist: "Band"}
+	player := newFakePlayer(song, 60)
+	player.pause()
+	player.seek(6.5)
+	offsets := make(chan float64)
+	provider := newFakeProvider(map[string][]lyrics.LyricLine{"Song": lines, "Other": lines})
+	_, ch := start(t, player, provider, Config{Offsets: offsets})
+	waitFor(t, ch, "at two", atLine(1))
+
+	// mid-line, the offset alone moves the index, either way
+	for _, step := range []struct {
+		by, offset float64
+		index      int
+	}{{0.6, 0.6, 2}, {-2, -1.4, 0}, {-0.2, -1.6, -1}, {0.2, -1.4, 0}} {
+		offsets <- step.by
+		u := waitFor(t, ch, fmt.Sprintf("with offset %v", step.offset), func(u Update) bool { return u.Offset == step.offset })
+		if u.Index != step.index || !u.Seeked || u.Position != 6.5 {
+			t.Errorf("offset %v: line %d at %v, seeked %v; want line %d at 6.5, seeked", step.offset, u.Index, u.Position, u.Seeked, step.index)
+		}
+	}
+
+	// each track keeps its own
+	player.setTrack(other, 60)
+	u := waitFor(t, ch, "on the other track", func(u Update) bool { return u.Track == other })
+	if u.Offset != 0 {
+		t.Errorf("other track's offset %v, want 0", u.Offset)
+	}
+	player.change(func(f *fakePlayer) { f.track, f.position, f.playing = song, 6.5, false })
+	u = waitFor(t, ch, "back at one", atLine(0))
+	if u.Offset != -1.4 {
+		t.Errorf("offset back on the track %v, want -1.4", u.Offset)
+	}
+}
+
+func TestSessionPauseResume(t *testing.T) {
+	lines := []lyrics.LyricLine{{Time: 0.2, Text: "one"}, {Time: 0.4, Text: "two"}}
+	player := newFakePlayer(song, 60)
+	_, ch := start(t, player, newFakeProvider(map[string][]lyrics.LyricLine{"Song": lines}), Config{})
+	waitFor(t, ch, "at one", atLine(0))
+
+	player.pause()
+	u := waitFor(t, ch, "paused", func(u Update) bool { return !u.Playing })
+	if u.Index != 0 {
+		t.Errorf("paused at line %d, want 0", u.Index)
+	}
+	// the next line is not due while paused
+	timeout := time.After(400 * time.Millisecond)
+	for waiting := true; waiting; {
+		select {
+		case u := <-ch:
+			if u.Index != 0 || u.Playing {
+				t.Errorf("update while paused %+v", u)
+			}
+		case <-timeout:
+			waiting = false
+		}
+	}
+	player.play()
+	waitFor(t, ch, "playing", func(u Update) bool { return u.Playing })
+	waitFor(t, ch, "at two", atLine(1))
+	onTime(t, player, 0.4)
+}
+
+func TestSessionTrackChangeSlowFetch(t *testing.T) {
+	other := mpris.TrackMetadata{Title: "Other", Artist: "Band"}
+	provider := newFakeProvider(map[string][]lyrics.LyricLine{
+		"Song":  {{Time: 30, Text: "song"}},
+		"Other": {{Time: 30, Text: "other"}},
+	})
+	provider.delay = 300 * time.Millisecond
+	player := newFakePlayer(song, 60)
+	_, ch := start(t, player, provider, Config{})
+	waitFor(t, ch, "with the lyrics", atLine(-1))
+
+	changed := time.Now()
+	player.setTrack(other, 60)
+	u := waitFor(t, ch, "for the new track", func(u Update) bool { return u.Track == other })
+	if d := time.Since(changed); d > 100*time.Millisecond {
+		t.Errorf("new track announced after %v", d)
+	}
+	if u.State != StateFetching || !u.Loading || u.Lines != nil {
+		t.Errorf("new track announced as %v with %d lines, want fetching without lines", u.State, len(u.Lines))
+	}
+	u = waitFor(t, ch, "with the new lyrics", func(u Update) bool {
+		if u.Track == other && u.State != StateReady && u.Lines != nil {
+			t.Errorf("the new track shown with %q while %v", u.Lines[0].Text, u.State)
+		}
+		return u.State == StateReady
+	})
+	if d := time.Since(changed); d < provider.delay {
+		t.Errorf("new lyrics after %v, before the fetch could end", d)
+	}
+	if u.Track != other || u.Lines[0].Text != "other" {
+		t.Errorf("ready with %v, %q", u.Track, u.Lines[0].Text)
+	}
+}
+
+func TestSessionPlayerGone(t *testing.T) {
+	provider := newFakeProvider(map[string][]lyrics.LyricLine{"Song": {{Time: 30, Text: "song"}}})
+	player := newFakePlayer(song, 60)
+	_, ch := start(t, player, provider, Config{})
+	waitFor(t, ch, "with the lyrics", atLine(-1))
+
+	player.vanish()
+	u := waitFor(t, ch, "without a player", func(u Update) bool { return u.State == StateWaitingForPlayer })
+	if !errors.Is(u.Err, mpris.ErrNoPlayer) || u.Lines != nil || u.Track != (mpris.TrackMetadata{}) {
+		t.Errorf("player gone: %v, %d lines, track %v", u.Err, len(u.Lines), u.Track)
+	}
+	player.comeBack(song, 60)
+	u = waitFor(t, ch, "with the player back", func(u Update) bool { return u.State != StateWaitingForPlayer })
+	if u.State != StateReady || u.Err != nil || u.Track != song {
+		t.Errorf("player back: %v, %v, track %v", u.State, u.Err, u.Track)
+	}
+	// the lyrics of the track it left are kept
+	if calls := provider.fetches(); len(calls) != 1 {
+		t.Errorf("%d fetches, want 1", len(calls))
+	}
+}
+
+func TestSessionPlayerGoneCycles(t *testing.T) {
+	other := mpris.TrackMetadata{Title: "Other", Artist: "Band"}
+	provider := newFakeProvider(map[string][]lyrics.LyricLine{
+		"Song":  {{Time: 0, Text: "song"}},
+		"Other": {{Time: 0, Text: "other"}},
+	})
+	player := newFakePlayer(song, 60)
+	_, ch := start(t, player, provider, Config{})
+	waitFor(t, ch, "with the lyrics", atLine(0))
+	baseline := runtime.NumGoroutine()
+
+	tracks := []mpris.TrackMetadata{other, other, song, other, song}
+	for i, track := range tracks {
+		player.vanish()
+		u := waitFor(t, ch, fmt.Sprintf("without a player, cycle %d", i), func(u Update) bool { return u.State == StateWaitingForPlayer })
+		if u.Lines != nil || u.Index != -1 || u.Track != (mpris.TrackMetadata{}) {
+			t.Errorf("cycle %d: player gone with %d lines at %d, track %v", i, len(u.Lines), u.Index, u.Track)
+		}
+		player.comeBack(track, 60)
+		u = waitFor(t, ch, fmt.Sprintf("back on %s, cycle %d", track.Title, i), atLine(0))
+		if u.Track != track || u.Lines[0].Text != strings.ToLower(track.Title) {
+			t.Errorf("cycle %d: back on %v with %q, want %v", i, u.Track, u.Lines[0].Text, track)
+		}
+	}
+	// a track other than the one left is fetched, as after any change
+	var titles []string
+	for _, c := range provider.fetches() {
+		titles = append(titles, c.title)
+	}
+	if want := []string{"Song", "Other", "Song", "Other", "Song"}; !slices.Equal(titles, want) {
+		t.Errorf("fetches %v, want %v", titles, want)
+	}
+	// nor does a cycle leave a goroutine behind
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > baseline; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines after %d cycles, %d before", runtime.NumGoroutine(), len(tracks), baseline)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSessionPlayersAlternating(t *testing.T) {
+	// lines far apart, so neither player moves on a line meanwhile
+	lines := func(text string) []lyrics.LyricLine {
+		return []lyrics.LyricLine{{Time: 0, Text: text}, {Time: 10, Text: text}, {Time: 20, Text: text}, {Time: 30, Text: text}}
+	}
+	provider := newFakeProvider(map[string][]lyrics.LyricLine{"Song": lines("song"), "Other": lines("other")})
+	a := newFakePlayer(mpris.TrackMetadata{Title: "Song", Artist: "Band", Bus: "org.mpris.MediaPlayer2.a"}, 60)
+	b := newFakePlayer(mpris.TrackMetadata{Title: "Other", Artist: "Band", Bus: "org.mpris.MediaPlayer2.b"}, 60)
+	a.seek(10.5)
+	b.seek(20.5)
+	bus := &fakeBus{players: []*fakePlayer{a, b}}
+	_, ch := start(t, bus, provider, Config{})
+	waitFor(t, ch, "at a's line", atLine(1))
+
+	// each update is all one player's: its track, its lines, and the line
+	// at its position
+	want := map[string]struct {
+		text   string
+		player *fakePlayer
+		index  int
+	}{
+		a.track.Bus: {"song", a, 1},
+		b.track.Bus: {"other", b, 2},
+	}
+	for i := range 8 {
+		active := bus.players[(i+1)%2]
+		bus.activate((i + 1) % 2)
+		first := true
+		waitFor(t, ch, fmt.Sprintf("ready after switch %d", i), func(u Update) bool {
+			w := want[u.Track.Bus]
+			if w.player == nil {
+				t.Fatalf("switch %d: update for no player: %+v", i, u)
+			}
+			if u.Lines != nil && u.Lines[0].Text != w.text {
+				t.Errorf("switch %d: %s with the lines of %q", i, u.Track.Title, u.Lines[0].Text)
+			}
+			if d := math.Abs(u.Position - w.player.now()); d > 0.5 {
+				t.Errorf("switch %d: %s at %.2f, its player at %.2f", i, u.Track.Title, u.Position, w.player.now())
+			}
+			if u.State == StateReady && u.Index != w.index {
+				t.Errorf("switch %d: %s at line %d, want %d", i, u.Track.Title, u.Index, w.index)
+			}
+			if w.player != active {
+				return false // the switch has yet to come through
+			}
+			// once fetched, a player's lyrics come with the switch
+			if first && i > 0 && u.State != StateReady {
+				t.Errorf("switch %d: first update for %s %v, want ready", i, u.Track.Title, u.State)
+			}
+			first = false
+			return u.State == StateReady
+		})
+	}
+	if calls := provider.fetches(); len(calls) != 2 {
+		t.Errorf("%d fetches, want 2", len(calls))
+	}
+}
+
+func TestPositionOverLongPlayback(t *testing.T) {
+	// a player over an hour: rate changes and pauses, read every few
+	// seconds, the reading taken as the anchor each time
+	start := time.Now()
+	var (
+		truth   float64 // where the player really is
+		rate    = 1.0
+		playing = true
+		anchor  = playerState{Playing: true, Rate: 1, At: start}
+		now     = start
+	)
+	events := map[int]func(){
+		100: func() { rate = 1.5 },
+		250: func() { playing = false },
+		260: func() { playing = true },
+		400: func() { rate = 0.5 },
+		555: func() { playing = false },
+		556: func() { playing = true },
+		700: func() { rate = 1 },
+	}
+	for step := range 1000 {
+		if event, ok := events[step]; ok {
+			event()
+		}
+		// 3.7s between readings, so no reading lines up with a second
+		d := 3700 * time.Millisecond
+		if playing {
+			truth += d.Seconds() * rate
+		}
+		now = now.Add(d)
+		if got := anchor.positionAt(now); anchor.Playing == playing && anchor.Rate == rate && math.Abs(got-truth) > 1e-6 {
+			t.Fatalf("step %d: position %.6f, want %.6f", step, got, truth)
+		}
+		reading := playerState{Playing: playing, Rate: rate, Position: truth, At: now}
+		// a pause, resume or rate change is no seek, wherever it came
+		// between the readings
+		if anchor.Rate == rate && drift(anchor, reading) > driftTolerance.Seconds() {
+			t.Errorf("step %d: drift %.3fs across a change of state", step, drift(anchor, reading))
+		}
+		anchor = reading
+	}
+	if total := now.Sub(start); total < time.Hour {
+		t.Fatalf("simulated %v", total)
+	}
+	// paused, the position stands still however long
+	if got := (playerState{Position: 42, At: start}).positionAt(start.Add(time.Hour)); got != 42 {
+		t.Errorf("paused position %v after an hour, want 42", got)
+	}
+}
+
+func TestSessionRate(t *testing.T) {
+	lines := []lyrics.LyricLine{{Time: 0.4, Text: "one"}, {Time: 0.8, Text: "two"}}
+	player := newFakePlayer(song, 60)
+	player.setRate(2)
+	_, ch := start(t, player, newFakeProvider(map[string][]lyrics.LyricLine{"Song": lines}), Config{})
+	started := time.Now()
+	if u := waitFor(t, ch, "at one", atLine(0)); u.Rate != 2 {
+		t.Errorf("at one: rate %v, want 2", u.Rate)
+	}
+	onTime(t, player, 0.4)
+	player.setRate(0.5)
+	if u := waitFor(t, ch, "at two", atLine(1)); u.Rate != 0.5 {
+		t.Errorf("at two: rate %v, want 0.5", u.Rate)
+	}
+	onTime(t, player, 0.8)
+	// 0.4s of lyrics at double speed, then 0.4s at half
+	if d := time.Since(started); d < 900*time.Millisecond || d > 1100*time.Millisecond {
+		t.Errorf("lines took %v to play, want 1s", d)
+	}
+}
+
+func TestSessionSkipBurst(t *testing.T) {
+	last := mpris.TrackMetadata{Title: "Track 9", Artist: "Band"}
+	provider := newFakeProvider(map[string][]lyrics.LyricLine{"Track 9": {{Time: 30, Text: "last"}}})
+	player := newFakePlayer(mpris.TrackMetadata{Title: "Track 0", Artist: "Band"}, 60)
+	_, ch := start(t, player, provider, Config{Debounce: 100 * time.Millisecond})
+
+	for i := 1; i <= 9; i++ {
+		track := mpris.TrackMetadata{Title: fmt.Sprint("Track ", i), Artist: "Band"}
+		player.setTrack(track, 60)
+		// each track shows at once, its lyrics put off
+		u := waitFor(t, ch, "for "+track.Title, func(u Update) bool { return u.Track == track })
+		if u.State != StateFetching {
+			t.Errorf("%s announced as %v, want fetching", track.Title, u.State)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	u := waitFor(t, ch, "with the lyrics", func(u Update) bool { return u.State != StateFetching })
+	if u.State != StateReady || u.Track != last {
+		t.Errorf("after the burst: %v for %v", u.State, u.Track)
+	}
+	time.Sleep(200 * time.Millisecond) // for any fetch coming late
+	if calls := provider.fetches(); len(calls) != 1 || calls[0].title != last.Title {
+		t.Errorf("fetches %+v, want one for %s", calls, last.Title)
+	}
+}
+
+func TestSessionSlowProvider(t *testing.T) {
+	other := mpris.TrackMetadata{Title: "Other", Artist: "Band"}
+	provider := newFakeProvider(map[string][]lyrics.LyricLine{
+		"Song":  {{Time: 0, Text: "song"}},
+		"Other": {{Time: 0, Text: "other"}},
+	})
+	provider.delay = 500 * time.Millisecond
+	player := newFakePlayer(song, 60)
+	_, ch := start(t, player, provider, Config{})
+	waitFor(t, ch, "fetching", func(u Update) bool { return u.State == StateFetching })
+
+	// the player is followed while the fetch takes its time
+	for _, step := range []struct {
+		what string
+		do   func()
+		cond func(Update) bool
+	}{
+		{"paused", player.pause, func(u Update) bool { return !u.Playing }},
+		{"playing", player.play, func(u Update) bool { return u.Playing }},
+		{"seeked", func() { player.seek(30) }, func(u Update) bool { return u.Seeked }},
+	} {
+		changed := time.Now()
+		step.do()
+		u := waitFor(t, ch, step.what, step.cond)
+		if d := time.Since(changed); d > 100*time.Millisecond {
+			t.Errorf("%s after %v", step.what, d)
+		}
+		if u.State != StateFetching || !u.Loading {
+			t.Errorf("%s: %v, want still fetching", step.what, u.State)
+		}
+	}
+
+	// the fetch for the track left ends while the next one's is on, and
+	// is dropped
+	player.setTrack(other, 60)
+	u := waitFor(t, ch, "with lyrics", func(u Update) bool { return u.State == StateReady })
+	if u.Track != other || u.Lines[0].Text != "other" {
+		t.Errorf("ready with %q for %v", u.Lines[0].Text, u.Track)
+	}
+}
+
+func TestSessionSlowConsumer(t *testing.T) {
+	// a line every 20ms, and a consumer taking 200ms over each update
+	lines := make([]lyrics.LyricLine, 100)
+	for i := range lines {
+		lines[i] = lyrics.LyricLine{Time: 0.02 * float64(i+1), Text: fmt.Sprint(i)}
+	}
+	player := newFakePlayer(song, 60)
+	_, ch := start(t, player, newFakeProvider(map[string][]lyrics.LyricLine{"Song": lines}), Config{})
+	waitFor(t, ch, "at the first line", atLine(0))
+
+	for range 6 {
+		time.Sleep(200 * time.Millisecond)
+		u := <-ch
+		// the pool went on without the consumer: what waits is the
+		// latest, a line old at most, give or take scheduling
+		if stale := player.now() - u.Position; stale > 0.05 {
+			t.Errorf("update %.3fs old when taken", stale)
+		}
+		if want := IndexAt(lines, u.Position); u.Index != want {
+			t.Errorf("update at %.3f on line %d, want %d", u.Position, u.Index, want)
+		}
+	}
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	provider := newFakeProvider(map[string][]lyrics.LyricLine{"Song": {{Time: 0.1, Text: "one"}}})
+	provider.errs = []error{fmt.Errorf("fake: %w", lyrics.ErrUnreachable)}
+	player := newFakePlayer(song, 0)
+	player.vanish()
+	retry := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan Update, 1)
+	go New(player, provider, Config{PollInterval: time.Second, Retry: retry}).Run(ctx, ch)
+
+	var states []State
+	timeout := time.After(2 * time.Second)
+	for done := false; !done; {
+		var u Update
+		select {
+		case u = <-ch:
+		case <-timeout:
+			t.Fatalf("lifecycle stuck after %v", states)
+		}
+		if n := len(states); n > 0 && states[n-1] == u.State {
+			continue
+		}
+		states = append(states, u.State)
+		switch u.State {
+		case StateWaitingForPlayer:
+			player.comeBack(song, 1.2) // ends 0.7s on, within endMargin
+		case StateError:
+			if !errors.Is(u.Err, lyrics.ErrUnreachable) || u.Lines != nil {
+				t.Errorf("fetch failed with %v and %d lines", u.Err, len(u.Lines))
+			}
+			retry <- struct{}{}
+		case StateReady:
+			if u.Err != nil || u.Failures != 1 {
+				t.Errorf("ready with error %v after %d failures, want none after 1", u.Err, u.Failures)
+			}
+		case StateEnded:
+			if u.Lines != nil || u.Index != -1 {
+				t.Errorf("ended with %d lines at line %d", len(u.Lines), u.Index)
+			}
+			cancel()
+		case StateStopped:
+			done = true
+		}
+	}
+	want := []State{StateWaitingForPlayer, StateFetching, StateError, StateFetching, StateReady, StateEnded, StateStopped}
+	if !slices.Equal(states, want) {
+		t.Errorf("states %v, want %v", states, want)
+	}
+	if _, ok := <-ch; ok {
+		t.Error("channel open after StateStopped")
+	}
+}
+
+func TestRunLeavesNoGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := range 5 {
+		provider := newFakeProvider(map[string][]lyrics.LyricLine{"Song": {{Time: 0, Text: "one"}}})
+		// the last cycles stop with a fetch in flight
+		if i >= 3 {
+			provider.delay = 100 * time.Millisecond
+		}
+		player := newFakePlayer(song, 60)
+		// and one with the player polled rather than watched
+		player.silent = i == 2
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := make(chan Update, 1)
+		go New(player, provider, Config{PollInterval: 10 * time.Millisecond, Retry: make(chan struct{}), Offsets: make(chan float64)}).Run(ctx, ch)
+		waitFor(t, ch, fmt.Sprintf("with the track, cycle %d", i), func(u Update) bool { return u.Track == song })
+		cancel()
+		waitFor(t, ch, fmt.Sprintf("stopped, cycle %d", i), func(u Update) bool { return u.State == StateStopped })
+		if _, ok := <-ch; ok {
+			t.Errorf("cycle %d: channel open after StateStopped", i)
+		}
+	}
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines after 5 runs, %d before", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSessionJumps(t *testing.T) {
+	lines := make([]lyrics.LyricLine, 100)
+	for i := range lines {
+		lines[i] = lyrics.LyricLine{Time: float64(i + 1), Text: fmt.Sprint(i)}
+	}
+	jumps := []float64{0.5, 2, 30, -0.5, -2, -30, 1.2, -1.2, 10, -40}
+	for _, polled := range []bool{false, true} {
+		name := "signalled"
+		if polled {
+			name = "polled"
+		}
+		t.Run(name, func(t *testing.T) {
+			player := newFakePlayer(song, 300)
+			player.silent = polled // no signals, so no word of the seek either
+			player.seek(50)
+			_, ch := start(t, player, newFakeProvider(map[string][]lyrics.LyricLine{"Song": lines}), Config{PollInterval: 50 * time.Millisecond})
+			waitFor(t, ch, "with the lyrics", func(u Update) bool { return u.State == StateReady })
+
+			for _, jump := range jumps {
+				to := player.now() + jump
+				player.seek(to)
+				u := waitFor(t, ch, fmt.Sprintf("after jumping %+v", jump), func(u Update) bool { return math.Abs(u.Position-to) < 0.15 })
+				if want := IndexAt(lines, u.Position); u.Index != want {
+					t.Errorf("jump %+v to %.2f: line %d, want %d", jump, u.Position, u.Index, want)
+				}
+				// small jumps are the position corrected
+				if seek := math.Abs(jump) > seekTolerance.Seconds(); u.Seeked != seek {
+					t.Errorf("jump %+v: seeked %v, want %v", jump, u.Seeked, seek)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionLoop(t *testing.T) {
+	// long enough for its end to be a seek back
+	const duration = 1.8
+	lines := []lyrics.LyricLine{{Time: 0.1, Text: "one"}, {Time: 0.3, Text: "two"}}
+	for _, polled := range []bool{false, true} {
+		name := "signalled"
+		if polled {
+			name = "polled"
+		}
+		t.Run(name, func(t *testing.T) {
+			player := newFakePlayer(song, duration)
+			player.loop, player.silent = true, polled
+			if !polled {
+				// players re-send the metadata as the track starts over
+				time.AfterFunc(time.Duration(duration*float64(time.Second)), func() { player.change(func(*fakePlayer) {}) })
+			}
+			_, ch := start(t, player, newFakeProvider(map[string][]lyrics.LyricLine{"Song": lines}), Config{PollInterval: 50 * time.Millisecond})
+
+			var shown []int
+			restarts, ends := 0, 0
+			timeout := time.After(time.Duration(3 * duration * float64(time.Second)))
+			for ends < 2 {
+				select {
+				case u := <-ch:
+					if u.Restarted {
+						restarts++
+					}
+					switch {
+					case u.State == StateEnded:
+						ends++
+					case u.State == StateReady && u.Index >= 0 && (len(shown) == 0 || shown[len(shown)-1] != u.Index):
+						shown = append(shown, u.Index)
+					}
+				case <-timeout:
+					t.Fatalf("still playing after %d ends, lines %v", ends, shown)
+				}
+			}
+			if want := []int{0, 1, 0, 1}; !slices.Equal(shown, want) || restarts != 1 {
+				t.Errorf("lines %v with %d restarts, want %v with 1", shown, restarts, want)
+			}
+		})
+	}
+}
+
+func TestSessionPauseCycles(t *testing.T) {
+	lines := make([]lyrics.LyricLine, 40)
+	for i := range lines {
+		lines[i] = lyrics.LyricLine{Time: 0.25 * float64(i+1), Text: fmt.Sprint(i)}
+	}
+	player := newFakePlayer(song, 60)
+	_, ch := start(t, player, newFakeProvider(map[string][]lyrics.LyricLine{"Song": lines}), Config{})
+	waitFor(t, ch, "with the lyrics", func(u Update) bool { return u.State == StateReady })
+
+	for i := range 10 {
+		time.Sleep(40 * time.Millisecond)
+		player.pause()
+		u := waitFor(t, ch, fmt.Sprint("paused ", i), func(u Update) bool { return !u.Playing })
+		// frozen where the player stopped
+		if d := math.Abs(u.Position - player.now()); d > 0.1 {
+			t.Errorf("pause %d: at %.3f, player at %.3f", i, u.Position, player.now())
+		}
+		time.Sleep(30 * time.Millisecond)
+		player.play()
+		waitFor(t, ch, fmt.Sprint("playing ", i), func(u Update) bool { return u.Playing })
+	}
+	// the lines after come when they are due, however the pauses fell
+	index := IndexAt(lines, player.now())
+	for next := index + 1; next <= index+2; next++ {
+		waitFor(t, ch, fmt.Sprint("at line ", next), atLine(next))
+		if late := player.now() - lines[next].Time; math.Abs(late) > 0.1 {
+			t.Errorf("line %d %.3fs late after the pauses", next, late)
+		}
+	}
+}
+
+func TestGetIndexLinesSwapped(t *testing.T) {
+	timed := func(n int) []lyrics.LyricLine {
+		lines := make([]lyrics.LyricLine, n)
+		for i := range lines {
+			lines[i] = lyrics.LyricLine{Time: float64(2 * (i + 1)), Text: fmt.Sprint(i)}
+		}
+		return lines
+	}
+	long, short := timed(60), timed(12)
+
+	// playing through the long lyrics, swapped for the short ones at
+	// line 45 with the index carried over
+	index := -1
+	for position := 0.0; position < 120; position += 0.5 {
+		lines := long
+		if position >= 91 {
+			lines = short
+		}
+		index = getIndex(position, index, lines)
+		if want := IndexAt(lines, position); index != want {
+			t.Fatalf("at %v with %d lines: line %d, want %d", position, len(lines), index, want)
+		}
+	}
+
+	for _, lines := range [][]lyrics.LyricLine{short, long[:1], nil} {
+		for _, cur := range []int{-5, -1, 0, 11, 12, 45, 59, 100} {
+			for _, position := range []float64{0, 1, 2, 13, 23.9, 24, 500} {
+				if got, want := getIndex(position, cur, lines), IndexAt(lines, position); got != want {
+					t.Errorf("getIndex(%v, %d) of %d lines = %d, want %d", position, cur, len(lines), got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestSnapshotConcurrent(t *testing.T) {
+	other := mpris.TrackMetadata{Title: "Other", Artist: "Band"}
+	lines := make([]lyrics.LyricLine, 50)
+	for i := range lines {
+		lines[i] = lyrics.LyricLine{Time: 0.02 * float64(i+1), Text: fmt.Sprint(i)}
+	}
+	player := newFakePlayer(song, 60)
+	p, ch := start(t, player, newFakeProvider(map[string][]lyrics.LyricLine{"Song": lines, "Other": lines}), Config{Lead: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if u, err := p.WaitReady(ctx); err != nil || u.State == StateWaitingForPlayer || u.State == StateFetching {
+				t.Errorf("WaitReady: %v, %v", u.State, err)
+			}
+			for ctx.Err() == nil {
+				u := p.Snapshot()
+				if want := IndexAt(u.Lines, u.Position+0.01+u.Offset); u.Index != want {
+					t.Errorf("snapshot at %.3f on line %d, want %d", u.Position, u.Index, want)
+				}
+				if u.Seeked || u.Restarted {
+					t.Error("snapshot carries a seek")
+				}
+			}
+		}()
+	}
+	// the session goes on meanwhile
+	go func() {
+		for range ch {
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+	player.seek(0.5)
+	time.Sleep(50 * time.Millisecond)
+	player.pause()
+	time.Sleep(50 * time.Millisecond)
+	player.play()
+	player.setTrack(other, 60)
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	wg.Wait()
+}
@@ -0,0 +1,73 @@
+// Package textwidth measures and cuts text by its display width in
+// terminal cells, never splitting a grapheme cluster.
+package textwidth
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+)
+
+// clusterWidth returns the display width of a grapheme cluster, given the
+// width uniseg measured. Clusters of several runes (emoji with variation
+// selectors, ZWJ sequences, flags, combining marks, Hangul jamo) take the
+// width of the glyph they render as; single runes follow runewidth, which
+// honors the East Asian ambiguous-width setting.
+func clusterWidth(cluster string, width int) int {
+	if utf8.RuneCountInString(cluster) == 1 {
+		r, _ := utf8.DecodeRuneInString(cluster)
+		return runewidth.RuneWidth(r)
+	}
+	return width
+}
+
+// Measure returns the display width of s in cells, counting each grapheme
+// cluster once.
+func Measure(s string) int {
+	n, state := 0, -1
+	for s != "" {
+		var cluster string
+		var width int
+		cluster, s, width, state = uniseg.FirstGraphemeClusterInString(s, state)
+		n += clusterWidth(cluster, width)
+	}
+	return n
+}
+
+// Truncate shortens s to at most width cells, ending it with tail when
+// anything was cut. A width below 1 yields "".
+func Truncate(s string, width int, tail string) string {
+	if width < 1 {
+		return ""
+	}
+	if Measure(s) <= width {
+		return s
+	}
+	return Slice(s, 0, width-Measure(tail)) + tail
+}
+
+// Slice returns the part of s from cell start that fits in width cells.
+// A cluster straddling the left edge is replaced by blanks, one
+// straddling the right edge is left out.
+func Slice(s string, start, width int) string {
+	var b strings.Builder
+	pos, state := 0, -1
+	for s != "" {
+		var cluster string
+		var w int
+		cluster, s, w, state = uniseg.FirstGraphemeClusterInString(s, state)
+		w = clusterWidth(cluster, w)
+		switch {
+		case pos+w > start+width:
+			return b.String()
+		case pos >= start:
+			b.WriteString(cluster)
+		case pos+w > start:
+			b.WriteString(strings.Repeat(" ", pos+w-start))
+		}
+		pos += w
+	}
+	return b.String()
+}
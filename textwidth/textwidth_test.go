@@ -0,0 +1,67 @@
+package textwidth
+
+import "testing"
+
+func TestMeasure(t *testing.T) {
+	tests := []struct {
+		name, s string
+		want    int
+	}{
+		{"ascii", "hello", 5},
+		{"cjk", "日本語", 6},
+		{"family emoji", "👨‍👩‍👧‍👦", 2},
+		{"flag", "🇯🇵", 2},
+		{"two flags", "🇯🇵🇫🇷", 4},
+		{"variation selector", "\u2764\ufe0f", 2},
+		{"skin tone", "👍🏽", 2},
+		{"combining mark", "e\u0301", 1},
+		{"hangul jamo", "\u1100\u1161\u11a8", 2},
+		{"precomposed hangul", "\uac01", 2},
+		{"mixed", "a👨‍👩‍👧b", 4},
+	}
+	for _, tt := range tests {
+		if got := Measure(tt.s); got != tt.want {
+			t.Errorf("%s: Measure(%q) = %d, want %d", tt.name, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	family := "👨‍👩‍👧‍👦"
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"hello", 5, "hello"},
+		{"hello world", 6, "hello…"},
+		{"ab" + family + "cd", 4, "ab…"}, // the family would straddle the edge
+		{"ab" + family + "cd", 5, "ab" + family + "…"},
+		{"🇯🇵🇫🇷🇩🇪", 5, "🇯🇵🇫🇷…"},
+		{"e\u0301e\u0301e\u0301", 2, "e\u0301…"},
+		{"hello", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := Truncate(tt.s, tt.width, "…"); got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestSlice(t *testing.T) {
+	tests := []struct {
+		s            string
+		start, width int
+		want         string
+	}{
+		{"日本語", 0, 4, "日本"},
+		{"日本語", 1, 4, " 本"}, // the first cluster straddles the left edge
+		{"日本語", 0, 3, "日"},  // the second straddles the right edge
+		{"a🇯🇵b", 1, 2, "🇯🇵"},
+	}
+	for _, tt := range tests {
+		if got := Slice(tt.s, tt.start, tt.width); got != tt.want {
+			t.Errorf("Slice(%q, %d, %d) = %q, want %q", tt.s, tt.start, tt.width, got, tt.want)
+		}
+	}
+}
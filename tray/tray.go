@@ -0,0 +1,326 @@
+//go:build linux
+// +build linux
+
+// Package tray shows a tray icon through the StatusNotifierItem D-Bus
+// protocol, with the current lyric line as its tooltip and a small menu.
+package tray
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+const (
+	itemPath       = "/StatusNotifierItem"
+	itemInterface  = "org.kde.StatusNotifierItem"
+	menuPath       = "/MenuBar"
+	menuInterface  = "com.canonical.dbusmenu"
+	watcherName    = "org.kde.StatusNotifierWatcher"
+	watcherPath    = "/StatusNotifierWatcher"
+	iconName       = "audio-x-generic"
+	menuPrevious   = 1
+	menuNext       = 2
+	menuCopyLine   = 3
+	menuSeparator  = 4
+	menuLayoutRoot = 0
+)
+
+// ErrNoWatcher is returned by Start when no StatusNotifierWatcher runs,
+// e.g. on GNOME without an AppIndicator extension.
+var ErrNoWatcher = errors.New("no StatusNotifierWatcher on the session bus")
+
+// Actions are run when the icon is clicked or its menu used. They run on
+// D-Bus goroutines; nil ones do nothing.
+type Actions struct {
+	PlayPause func() // left click
+	Next      func()
+	Previous  func()
+	CopyLine  func()
+}
+
+// Tray is a registered tray icon.
+type Tray struct {
+	conn    *dbus.Conn
+	name    string
+	props   *prop.Properties
+	actions Actions
+
+	mu      sync.Mutex
+	tooltip toolTip
+}
+
+// toolTip is the StatusNotifierItem ToolTip property, (sa(iiay)ss).
+type toolTip struct {
+	IconName    string
+	IconPixmap  []pixmap
+	Title       string
+	Description string
+}
+
+type pixmap struct {
+	Width, Height int32
+	Data          []byte
+}
+
+// Start registers a tray icon with the StatusNotifierWatcher.
+func Start(actions Actions) (*Tray, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to session bus: %w", err)
+	}
+	var hasWatcher bool
+	err = conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, watcherName).Store(&hasWatcher)
+	if err != nil || !hasWatcher {
+		conn.Close()
+		return nil, ErrNoWatcher
+	}
+	t := &Tray{
+		conn:    conn,
+		name:    fmt.Sprintf("org.kde.StatusNotifierItem-%d-1", os.Getpid()),
+		actions: actions,
+		tooltip: toolTip{IconName: iconName, IconPixmap: []pixmap{}, Title: "lyricsmpris"},
+	}
+	if err := t.export(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Tray) export() error {
+	props, err := prop.Export(t.conn, itemPath, prop.Map{
+		itemInterface: {
+			"Category":   {Value: "ApplicationStatus"},
+			"Id":         {Value: "lyricsmpris"},
+			"Title":      {Value: "lyricsmpris"},
+			"Status":     {Value: "Active"},
+			"IconName":   {Value: iconName},
+			"ToolTip":    {Value: t.tooltip, Emit: prop.EmitFalse},
+			"ItemIsMenu": {Value: false},
+			"Menu":       {Value: dbus.ObjectPath(menuPath)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	t.props = props
+	if err := t.conn.Export((*item)(t), itemPath, itemInterface); err != nil {
+		return err
+	}
+	menuProps, err := prop.Export(t.conn, menuPath, prop.Map{
+		menuInterface: {
+			"Version":       {Value: uint32(3)},
+			"Status":        {Value: "normal"},
+			"TextDirection": {Value: "ltr"},
+			"IconThemePath": {Value: []string{}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if err := t.conn.Export((*menu)(t), menuPath, menuInterface); err != nil {
+		return err
+	}
+	itemNode := &introspect.Node{Interfaces: []introspect.Interface{
+		introspect.IntrospectData,
+		prop.IntrospectData,
+		{
+			Name:       itemInterface,
+			Methods:    introspect.Methods((*item)(t)),
+			Properties: props.Introspection(itemInterface),
+			Signals:    []introspect.Signal{{Name: "NewToolTip"}},
+		},
+	}}
+	menuNode := &introspect.Node{Interfaces: []introspect.Interface{
+		introspect.IntrospectData,
+		prop.IntrospectData,
+		{
+			Name:       menuInterface,
+			Methods:    introspect.Methods((*menu)(t)),
+			Properties: menuProps.Introspection(menuInterface),
+		},
+	}}
+	t.conn.Export(introspect.NewIntrospectable(itemNode), itemPath, "org.freedesktop.DBus.Introspectable")
+	t.conn.Export(introspect.NewIntrospectable(menuNode), menuPath, "org.freedesktop.DBus.Introspectable")
+
+	reply, err := t.conn.RequestName(t.name, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("%s is taken on the session bus", t.name)
+	}
+	return t.conn.Object(watcherName, watcherPath).
+		Call(watcherName+".RegisterStatusNotifierItem", 0, t.name).Err
+}
+
+// SetToolTip shows title and text, e.g. the track and the current line,
+// in the icon's tooltip.
+func (t *Tray) SetToolTip(title, text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tooltip.Title == title && t.tooltip.Description == text {
+		return
+	}
+	t.tooltip.Title, t.tooltip.Description = title, text
+	t.props.SetMust(itemInterface, "ToolTip", t.tooltip)
+	t.conn.Emit(itemPath, itemInterface+".NewToolTip")
+}
+
+// Close removes the icon and disconnects from the session bus.
+func (t *Tray) Close() error {
+	t.conn.ReleaseName(t.name)
+	return t.conn.Close()
+}
+
+func run(action func()) {
+	if action != nil {
+		action()
+	}
+}
+
+// item holds the org.kde.StatusNotifierItem methods.
+type item Tray
+
+// Activate is a primary (left) click.
+func (i *item) Activate(x, y int32) *dbus.Error {
+	run(i.actions.PlayPause)
+	return nil
+}
+
+// SecondaryActivate is a middle click.
+func (i *item) SecondaryActivate(x, y int32) *dbus.Error {
+	run(i.actions.Next)
+	return nil
+}
+
+// ContextMenu is only called by hosts that do not show Menu themselves.
+func (i *item) ContextMenu(x, y int32) *dbus.Error {
+	return nil
+}
+
+// Scroll is a wheel turn over the icon.
+func (i *item) Scroll(delta int32, orientation string) *dbus.Error {
+	return nil
+}
+
+// menu holds the com.canonical.dbusmenu methods.
+type menu Tray
+
+// menuLayout is a dbusmenu layout item, (ia{sv}av).
+type menuLayout struct {
+	ID       int32
+	Props    map[string]dbus.Variant
+	Children []dbus.Variant
+}
+
+// menuItems are the menu entries, in order.
+var menuItems = []struct {
+	id    int32
+	label string
+}{
+	{menuPrevious, "Previous"},
+	{menuNext, "Next"},
+	{menuSeparator, ""},
+	{menuCopyLine, "Copy line"},
+}
+
+func itemProps(label string) map[string]dbus.Variant {
+	if label == "" {
+		return map[string]dbus.Variant{"type": dbus.MakeVariant("separator")}
+	}
+	return map[string]dbus.Variant{"label": dbus.MakeVariant(label)}
+}
+
+// GetLayout returns the menu, which is flat: items have no children.
+func (m *menu) GetLayout(parentID, recursionDepth int32, propertyNames []string) (uint32, menuLayout, *dbus.Error) {
+	if parentID != menuLayoutRoot {
+		return 1, menuLayout{ID: parentID, Props: map[string]dbus.Variant{}, Children: []dbus.Variant{}}, nil
+	}
+	root := menuLayout{
+		ID:       menuLayoutRoot,
+		Props:    map[string]dbus.Variant{"children-display": dbus.MakeVariant("submenu")},
+		Children: []dbus.Variant{},
+	}
+	for _, e := range menuItems {
+		root.Children = append(root.Children, dbus.MakeVariant(menuLayout{
+			ID:       e.id,
+			Props:    itemProps(e.label),
+			Children: []dbus.Variant{},
+		}))
+	}
+	return 1, root, nil
+}
+
+// groupProperty is a dbusmenu item's properties, (ia{sv}).
+type groupProperty struct {
+	ID    int32
+	Props map[string]dbus.Variant
+}
+
+func (m *menu) GetGroupProperties(ids []int32, propertyNames []string) ([]groupProperty, *dbus.Error) {
+	var props []groupProperty
+	for _, e := range menuItems {
+		for _, id := range ids {
+			if id == e.id {
+				props = append(props, groupProperty{ID: e.id, Props: itemProps(e.label)})
+			}
+		}
+	}
+	return props, nil
+}
+
+func (m *menu) GetProperty(id int32, name string) (dbus.Variant, *dbus.Error) {
+	for _, e := range menuItems {
+		if e.id == id {
+			if v, ok := itemProps(e.label)[name]; ok {
+				return v, nil
+			}
+		}
+	}
+	return dbus.MakeVariant(""), nil
+}
+
+func (m *menu) Event(id int32, eventID string, data dbus.Variant, timestamp uint32) *dbus.Error {
+	if eventID != "clicked" {
+		return nil
+	}
+	switch id {
+	case menuPrevious:
+		run(m.actions.Previous)
+	case menuNext:
+		run(m.actions.Next)
+	case menuCopyLine:
+		run(m.actions.CopyLine)
+	}
+	return nil
+}
+
+// eventGroup is one event of EventGroup, (isvu).
+type eventGroup struct {
+	ID        int32
+	EventID   string
+	Data      dbus.Variant
+	Timestamp uint32
+}
+
+func (m *menu) EventGroup(events []eventGroup) ([]int32, *dbus.Error) {
+	for _, e := range events {
+		m.Event(e.ID, e.EventID, e.Data, e.Timestamp)
+	}
+	return []int32{}, nil
+}
+
+func (m *menu) AboutToShow(id int32) (bool, *dbus.Error) {
+	return false, nil
+}
+
+func (m *menu) AboutToShowGroup(ids []int32) ([]int32, []int32, *dbus.Error) {
+	return []int32{}, []int32{}, nil
+}
@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// A11yModeContext prints lyrics for screen readers: plain lines, written
+// once each and never repainted, with track changes, pauses and lyric
+// states announced in between.
+func A11yModeContext(ctx context.Context, pollInterval time.Duration, opts Options) {
+	ch := listen(ctx, pollInterval, nil, opts)
+	a := announcer{w: os.Stdout, translation: opts.Translation, index: -1}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case upd, ok := <-ch:
+			if !ok {
+				return
+			}
+			a.update(upd)
+		}
+	}
+}
+
+// announcer turns pool updates into plain text announcements, each state
+// announced once.
+type announcer struct {
+	w           io.Writer
+	translation string
+	track       mpris.TrackMetadata
+	started     bool // something was announced for track
+	playing     bool
+	index       int    // last line printed, -1 for none
+	state       string // last lyric state announced, "" after a line
+}
+
+func (a *announcer) update(u pool.Update) {
+	if u.Track != a.track || !a.started {
+		a.track, a.started = u.Track, true
+		a.playing, a.index, a.state = u.Playing, -1, ""
+		if u.Track.Title != "" {
+			a.say("Now playing: " + u.Track.Artist + " – " + u.Track.Title)
+		}
+	} else if u.Playing != a.playing {
+		a.playing = u.Playing
+		if u.Playing {
+			a.say("Resumed")
+		} else {
+			a.say("Paused")
+		}
+	}
+
+	switch {
+	case u.State == pool.StateWaitingForPlayer:
+		a.announce("No player running")
+	case u.State == pool.StateError:
+		a.announce("Lyrics unavailable: " + u.Err.Error())
+	case u.State == pool.StateFetching:
+		a.announce("Searching lyrics")
+	case u.State == pool.StateIdle:
+		a.announce("Nothing playing")
+	case u.State == pool.StateNotFound:
+		a.announce("No lyrics found")
+	case u.State == pool.StateEnded || u.State == pool.StateStopped:
+	case u.Index < 0 || u.Index >= len(u.Lines):
+		a.announce("Waiting for the first line")
+	case u.Index != a.index:
+		a.index, a.state = u.Index, ""
+		original, translation := lineTexts(u.Lines[u.Index], a.translation)
+		a.say(original)
+		if translation != "" {
+			a.say(translation)
+		}
+	}
+}
+
+// announce says the lyric state text unless it was the last one said.
+func (a *announcer) announce(text string) {
+	if text == a.state {
+		return
+	}
+	a.state = text
+	a.index = -1 // a line after the state is new again
+	a.say(text)
+}
+
+func (a *announcer) say(text string) {
+	fmt.Fprintln(a.w, stripControl(text))
+}
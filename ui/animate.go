@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	gloss "github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+const (
+	// animationDuration is how long the window takes to glide to a new line.
+	animationDuration = 150 * time.Millisecond
+	// frameInterval is the delay between animation frames.
+	frameInterval = 16 * time.Millisecond
+	// maxAnimatedLines is the largest line jump that is animated; bigger jumps
+	// (seeks, track changes) cut straight to the new line.
+	maxAnimatedLines = 3
+)
+
+// frameMsg advances the scroll animation and the pulse.
+type frameMsg time.Time
+
+func frame() tea.Cmd {
+	return tea.Tick(frameInterval, func(t time.Time) tea.Msg {
+		return frameMsg(t)
+	})
+}
+
+// animating reports whether a scroll animation is in progress.
+func (m *Model) animating() bool {
+	return m.animFrom != 0 && time.Since(m.animStart) < animationDuration
+}
+
+// animationOffset returns how many rows the window still lags behind its
+// target position, easing out to 0 over animationDuration.
+func (m *Model) animationOffset() int {
+	if !m.animating() {
+		return 0
+	}
+	p := float64(time.Since(m.animStart)) / float64(animationDuration)
+	remaining := 1 - p*(2-p) // ease out
+	return int(float64(m.animFrom)*remaining + 0.5*sign(m.animFrom))
+}
+
+// animate starts gliding the window from the line it was centered on to the
+// one it is centered on now, returning the command driving the frames.
+func (m *Model) animate(from, to int) tea.Cmd {
+	if m.opts.NoAnimation || from == to || m.lyricsHeight < 1 ||
+		from < 0 || to < 0 || from >= len(m.state.Lines) || to >= len(m.state.Lines) ||
+		to-from > maxAnimatedLines || from-to > maxAnimatedLines {
+		m.animFrom = 0
+		return nil
+	}
+	// the window's top row depends on how many rows the centered line wraps
+	// to, so the distance between two windows does too
+	rowsFrom, rowsTo := len(m.renderLine(from)), len(m.renderLine(to))
+	dist := m.blockTop(m.lyricsHeight, rowsTo) - m.blockTop(m.lyricsHeight, rowsFrom)
+	for i := min(from, to); i < max(from, to); i++ {
+		if to > from {
+			dist += len(m.renderLine(i))
+		} else {
+			dist -= len(m.renderLine(i))
+		}
+	}
+	wasAnimating := m.animating()
+	m.animFrom = dist + m.animationOffset()
+	m.animStart = time.Now()
+	if wasAnimating {
+		return nil // the running frame loop picks up the new animation
+	}
+	return frame()
+}
+
+func sign(n int) float64 {
+	if n < 0 {
+		return -1
+	}
+	return 1
+}
+
+// pulseDuration is how long a newly current line stays brightened.
+const pulseDuration = 300 * time.Millisecond
+
+// pulseColor is the brighter shade of the current line color a new current
+// line pulses in.
+var pulseColor = gloss.CompleteAdaptiveColor{
+	Dark:  gloss.CompleteColor{TrueColor: "#87ff87", ANSI256: "120", ANSI: "10"},
+	Light: gloss.CompleteColor{TrueColor: "#00af00", ANSI256: "34", ANSI: "10"},
+}
+
+// pulseEnabled reports whether opts asks for the pulse and the terminal can
+// show it: it needs animations and at least 256 colors.
+func pulseEnabled(opts Options) bool {
+	return opts.Pulse && !opts.NoAnimation && gloss.ColorProfile() <= termenv.ANSI256
+}
+
+// pulsing reports whether line index is pulsing.
+func (m *Model) pulsing(index int) bool {
+	return m.pulseLine == index && time.Since(m.pulseStart) < pulseDuration
+}
+
+// startPulse pulses the new current line index, returning the command
+// driving the frames. The pulse only restyles that line's rows, which is
+// all the renderer redraws.
+func (m *Model) startPulse(index int) tea.Cmd {
+	if !m.pulse || index < 0 {
+		return nil
+	}
+	running := m.animating() || m.pulsing(m.pulseLine)
+	m.pulseLine, m.pulseStart = index, time.Now()
+	if running {
+		return nil // the running frame loop carries the pulse too
+	}
+	return frame()
+}
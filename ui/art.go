@@ -0,0 +1,336 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"  // register decoders for cover art
+	_ "image/jpeg" //
+	_ "image/png"  //
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	gloss "github.com/charmbracelet/lipgloss"
+)
+
+// Album art display modes.
+const (
+	ArtOff    = "off"    // no cover art
+	ArtAuto   = "auto"   // kitty graphics where supported, the mosaic elsewhere
+	ArtKitty  = "kitty"  // kitty graphics protocol, for kitty and Ghostty
+	ArtMosaic = "mosaic" // half-block mosaic, works in any color terminal
+)
+
+// DefaultArtSize is the default cover art width in cells.
+const DefaultArtSize = 20
+
+// maxArtBytes caps the size of a cover read.
+const maxArtBytes = 10 << 20
+
+// maxArtPixels caps the pixels of a cover decoded, of which a small file
+// can claim a great many.
+const maxArtPixels = 4096 * 4096
+
+// maxKittyPixels caps the longer side of the image transmitted to the
+// terminal; a cover a few dozen cells wide needs no more.
+const maxKittyPixels = 512
+
+// minLyricsWidth is the narrowest lyric area kept beside the cover art;
+// on narrower terminals the art goes above the lyrics.
+const minLyricsWidth = 20
+
+// minLyricsHeight is the fewest lyric rows kept below the cover art; on
+// shorter terminals the art is left out.
+const minLyricsHeight = 3
+
+// artMsg carries the decoded cover art for url.
+type artMsg struct {
+	url string
+	img image.Image
+	// kitty is set when the image was transmitted to the terminal for
+	// kitty placeholders to show.
+	kitty bool
+}
+
+// artMode returns how the cover art is drawn: opts.Art with ArtAuto
+// settled, falling back to the mosaic for kitty graphics without a
+// terminal to transmit the image to.
+func (m *Model) artMode() string {
+	mode := m.opts.Art
+	if mode == ArtAuto {
+		mode = ArtMosaic
+		if kittySupported() {
+			mode = ArtKitty
+		}
+	}
+	if mode == ArtKitty && m.out == nil {
+		mode = ArtMosaic
+	}
+	return mode
+}
+
+// artCells returns the size of the cover art in cells for mode. Cells are
+// about twice as tall as wide, so a square cover takes half as many rows
+// as columns.
+func (m *Model) artCells(mode string) (cols, rows int) {
+	cols = m.opts.ArtSize
+	if cols <= 0 {
+		cols = DefaultArtSize
+	}
+	if mode == ArtKitty {
+		cols = min(cols, 2*len(kittyDiacritics))
+	}
+	return cols, (cols + 1) / 2
+}
+
+// artCmd starts loading the cover art at url off the UI goroutine and
+// clears the current art. It returns nil when art is off or url is
+// already shown.
+func (m *Model) artCmd(url string) tea.Cmd {
+	mode := m.artMode()
+	if mode == ArtOff || url == m.artURL {
+		return nil
+	}
+	m.artURL, m.artView = url, ""
+	if url == "" {
+		return nil
+	}
+	ctx, out := m.ctx, m.out
+	cols, rows := m.artCells(mode)
+	return func() tea.Msg {
+		img, err := loadArt(ctx, url)
+		if err != nil {
+			return nil // the lyrics are what matters; go without art
+		}
+		msg := artMsg{url: url, img: img}
+		if mode == ArtKitty {
+			seq, err := kittyTransmit(fitImage(img, maxKittyPixels), kittyImageID, cols, rows)
+			if err == nil {
+				_, err = out.WriteString(seq)
+			}
+			msg.kitty = err == nil // the mosaic otherwise
+		}
+		return msg
+	}
+}
+
+// setArt renders the loaded cover art, unless the track moved on meanwhile.
+func (m *Model) setArt(msg artMsg) {
+	if msg.url != m.artURL {
+		return
+	}
+	if msg.kitty {
+		cols, rows := m.artCells(ArtKitty)
+		m.artView = kittyPlaceholders(kittyImageID, cols, rows)
+		return
+	}
+	cols, rows := m.artCells(ArtMosaic)
+	m.artView = renderMosaic(msg.img, cols, rows, m.background())
+}
+
+// lyricsArea renders the lyric window into height rows, beside the cover
+// art when there's room, above it otherwise, or alone.
+func (m *Model) lyricsArea(height int) string {
+	art := m.artView
+	if art == "" {
+		return m.lyricsView(m.w, height)
+	}
+	artW, artH := gloss.Width(art), gloss.Height(art)
+	switch {
+	case m.w >= artW+1+minLyricsWidth && height >= artH:
+		lyrics := m.lyricsView(m.w-artW-1, height)
+		return gloss.JoinHorizontal(gloss.Top,
+			gloss.PlaceVertical(height, m.vPosition(), art), " ", lyrics)
+	case m.w >= artW && height-artH >= minLyricsHeight:
+		m.lyricsTop += artH
+		return gloss.JoinVertical(gloss.Left,
+			gloss.PlaceHorizontal(m.w, gloss.Center, art),
+			m.lyricsView(m.w, height-artH))
+	}
+	return m.lyricsView(m.w, height)
+}
+
+// loadArt reads and decodes the cover art at rawURL: a local file, or an
+// HTTP(S) download cached on disk by URL.
+func loadArt(ctx context.Context, rawURL string) (image.Image, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "file":
+		data, err := readArtFile(u.Path)
+		if err != nil {
+			return nil, err
+		}
+		return decodeArt(data)
+	case "http", "https":
+		return fetchArt(ctx, rawURL)
+	}
+	return nil, fmt.Errorf("unsupported art URL %q", rawURL)
+}
+
+// fetchArt downloads and decodes rawURL, going through the on-disk cache.
+// Only covers that decode are cached.
+func fetchArt(ctx context.Context, rawURL string) (image.Image, error) {
+	path, cacheErr := artCachePath(rawURL)
+	if cacheErr == nil {
+		if data, err := readArtFile(path); err == nil {
+			if img, err := decodeArt(data); err == nil {
+				return img, nil
+			}
+			os.Remove(path) // fetch it anew
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("art: unexpected status %d", resp.StatusCode)
+	}
+	data, err := readArt(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	img, err := decodeArt(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheErr == nil && os.MkdirAll(filepath.Dir(path), 0o755) == nil {
+		os.WriteFile(path, data, 0o644) // best effort
+	}
+	return img, nil
+}
+
+// readArtFile reads the cover in the file at path; see readArt.
+func readArtFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readArt(f)
+}
+
+// readArt reads a cover from r. One over maxArtBytes is an error, rather
+// than cut short.
+func readArt(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxArtBytes+1))
+	if err == nil && len(data) > maxArtBytes {
+		err = fmt.Errorf("art: larger than %d bytes", maxArtBytes)
+	}
+	return data, err
+}
+
+// decodeArt decodes a cover, checking its dimensions before decoding it
+// whole.
+func decodeArt(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxArtPixels {
+		return nil, fmt.Errorf("art: unreasonable size %dx%d", cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// artCachePath returns the cache file for the art at rawURL.
+func artCachePath(rawURL string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(dir, "lyricsmpris", "art", hex.EncodeToString(sum[:])), nil
+}
+
+// renderMosaic draws img in cols×rows cells of upper half blocks, each
+// cell showing two vertically stacked pixels. Without background the cells
+// are full blocks of a single pixel instead. Covers are near enough square
+// that the image is simply stretched to fit.
+func renderMosaic(img image.Image, cols, rows int, background bool) string {
+	if img.Bounds().Empty() || cols < 1 || rows < 1 {
+		return ""
+	}
+	px := scaleImage(img, cols, rows*2)
+	hex := func(x, y int) gloss.Color {
+		c := px.RGBAAt(x, y)
+		return gloss.Color(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B))
+	}
+	lines := make([]string, rows)
+	for y := range lines {
+		var line strings.Builder
+		for x := 0; x < cols; x++ {
+			if !background {
+				line.WriteString(gloss.NewStyle().
+					Foreground(hex(x, 2*y)).
+					Render("█"))
+				continue
+			}
+			line.WriteString(gloss.NewStyle().
+				Foreground(hex(x, 2*y)).
+				Background(hex(x, 2*y+1)).
+				Render("▀"))
+		}
+		lines[y] = line.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fitImage returns img scaled down, keeping its aspect, so that neither
+// side exceeds side pixels; smaller images are returned as they are.
+func fitImage(img image.Image, side int) image.Image {
+	b := img.Bounds()
+	if b.Dx() <= side && b.Dy() <= side {
+		return img
+	}
+	if b.Dx() >= b.Dy() {
+		return scaleImage(img, side, max(b.Dy()*side/b.Dx(), 1))
+	}
+	return scaleImage(img, max(b.Dx()*side/b.Dy(), 1), side)
+}
+
+// scaleImage returns img scaled to w×h pixels, each the average of the
+// source pixels it covers.
+func scaleImage(img image.Image, w, h int) *image.RGBA {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+			x1, y1 = max(x1, x0+1), max(y1, y0+1)
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, _ := img.At(sx, sy).RGBA()
+					r, g, bl, n = r+uint64(pr), g+uint64(pg), bl+uint64(pb), n+1
+				}
+			}
+			out.SetRGBA(x, y, color.RGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(bl / n >> 8), 0xff})
+		}
+	}
+	return out
+}
@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	gloss "github.com/charmbracelet/lipgloss"
+)
+
+// pngBytes returns a w×h PNG.
+func pngBytes(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadArtLimit(t *testing.T) {
+	if _, err := readArt(bytes.NewReader(make([]byte, maxArtBytes))); err != nil {
+		t.Errorf("readArt of %d bytes: %v", maxArtBytes, err)
+	}
+	if _, err := readArt(bytes.NewReader(make([]byte, maxArtBytes+1))); err == nil {
+		t.Errorf("readArt of %d bytes: no error", maxArtBytes+1)
+	}
+}
+
+func TestDecodeArtSize(t *testing.T) {
+	if _, err := decodeArt(pngBytes(t, 64, 64)); err != nil {
+		t.Errorf("decodeArt 64x64: %v", err)
+	}
+	// the header alone claims the size, and is all there is to decode
+	if _, err := decodeArt(pngHeader(8192, 8192)); err == nil || !strings.Contains(err.Error(), "8192x8192") {
+		t.Errorf("decodeArt 8192x8192: %v, want its size refused", err)
+	}
+}
+
+// pngHeader returns the signature and header chunk of a w×h RGBA PNG.
+func pngHeader(w, h uint32) []byte {
+	ihdr := binary.BigEndian.AppendUint32([]byte("IHDR"), w)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, h)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0) // 8-bit RGBA, not interlaced
+	b := []byte("\x89PNG\r\n\x1a\n")
+	b = binary.BigEndian.AppendUint32(b, uint32(len(ihdr)-4))
+	b = append(b, ihdr...)
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(ihdr))
+}
+
+func TestFetchArtCachesOnlyDecoded(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	cover := pngBytes(t, 16, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cut" {
+			w.Write(cover[:len(cover)/2])
+			return
+		}
+		w.Write(cover)
+	}))
+	defer srv.Close()
+
+	for _, path := range []string{"/cut", "/whole"} {
+		rawURL := srv.URL + path
+		_, err := fetchArt(context.Background(), rawURL)
+		cache, cacheErr := artCachePath(rawURL)
+		if cacheErr != nil {
+			t.Fatal(cacheErr)
+		}
+		_, statErr := os.Stat(cache)
+		switch {
+		case path == "/cut" && err == nil:
+			t.Error("fetchArt of a cut off cover: no error")
+		case path == "/cut" && statErr == nil:
+			t.Error("cut off cover cached")
+		case path == "/whole" && err != nil:
+			t.Errorf("fetchArt: %v", err)
+		case path == "/whole" && statErr != nil:
+			t.Errorf("cover not cached: %v", statErr)
+		}
+	}
+}
+
+func TestKittyTransmitChunks(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for i := range img.Pix {
+		img.Pix[i] = byte(i * 7919 >> 3) // noise, so the PNG takes several chunks
+	}
+	seq, err := kittyTransmit(img, 42, 20, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := strings.Split(strings.TrimSuffix(seq, "\x1b\\"), "\x1b\\")
+	if len(chunks) < 2 {
+		t.Fatalf("%d chunks, want several", len(chunks))
+	}
+	if !strings.HasPrefix(chunks[0], "\x1b_Ga=T,U=1,f=100,q=2,i=42,c=20,r=10,m=1;") {
+		t.Errorf("first chunk starts %q", chunks[0][:40])
+	}
+	for i, c := range chunks[1:] {
+		want := "\x1b_Gm=1;"
+		if i == len(chunks)-2 {
+			want = "\x1b_Gm=0;"
+		}
+		if !strings.HasPrefix(c, want) {
+			t.Errorf("chunk %d starts %q, want %q", i+1, c[:8], want)
+		}
+		if payload := c[strings.Index(c, ";")+1:]; len(payload) > kittyChunk {
+			t.Errorf("chunk %d carries %d bytes", i+1, len(payload))
+		}
+	}
+}
+
+func TestKittyPlaceholders(t *testing.T) {
+	art := kittyPlaceholders(0x010203, 20, 10)
+	if w, h := gloss.Width(art), gloss.Height(art); w != 20 || h != 10 {
+		t.Errorf("placeholders are %dx%d cells, want 20x10", w, h)
+	}
+	for y, line := range strings.Split(art, "\n") {
+		want := "\x1b[38;2;1;2;3m" + string(kittyPlaceholder) + string(kittyDiacritics[y]) + string(kittyDiacritics[0])
+		if !strings.HasPrefix(line, want) {
+			t.Errorf("row %d starts %q, want %q", y, line[:len(want)], want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
+)
+
+// maxClipboardBytes caps copied text so the encoded OSC 52 sequence stays
+// within the 100kB most terminals and tmux accept.
+const maxClipboardBytes = 74994
+
+// copyText copies text to the clipboard off the UI goroutine and reports
+// the outcome as a notice. It writes an OSC 52 sequence to the terminal,
+// which works over SSH and inside tmux, or runs wl-copy or xclip when
+// OSC 52 is disabled or stderr is redirected.
+func (m *Model) copyText(text, what string) tea.Cmd {
+	noOSC52 := m.opts.NoOSC52
+	return func() tea.Msg {
+		text := capBytes(text, maxClipboardBytes)
+		var err error
+		// without a terminal on stderr the sequence would only end up
+		// in a log
+		if noOSC52 || !term.IsTerminal(int(os.Stderr.Fd())) {
+			err = copyCommand(text)
+		} else {
+			err = copyOSC52(text)
+		}
+		if err != nil {
+			return noticeMsg("copy failed: " + err.Error())
+		}
+		return noticeMsg("copied " + what)
+	}
+}
+
+// copyOSC52 writes text to the terminal clipboard with an OSC 52 sequence.
+// The sequence goes to stderr so it doesn't interleave with the frames
+// bubbletea renders to stdout.
+func copyOSC52(text string) error {
+	seq := osc52.New(text)
+	switch {
+	case os.Getenv("TMUX") != "":
+		seq = seq.Tmux()
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		seq = seq.Screen()
+	}
+	_, err := seq.WriteTo(os.Stderr)
+	return err
+}
+
+// copyCommand pipes text into the first available clipboard tool.
+func copyCommand(text string) error {
+	var cmd *exec.Cmd
+	switch {
+	case os.Getenv("WAYLAND_DISPLAY") != "" && hasCommand("wl-copy"):
+		cmd = exec.Command("wl-copy")
+	case hasCommand("xclip"):
+		cmd = exec.Command("xclip", "-selection", "clipboard")
+	default:
+		return errors.New("no clipboard tool found (install wl-copy or xclip)")
+	}
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
+
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// capBytes shortens s to at most n bytes without splitting a rune.
+func capBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
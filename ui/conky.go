@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/output"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// Default conky color variables.
+const (
+	DefaultConkyCurrent = "color1"
+	DefaultConkyOther   = "color2"
+)
+
+// ConkyContext prints the current line with opts.ConkyContext lines
+// before and after it, in conky color variables, and returns; it is meant
+// for ${execpi}. The lines come from the instance serving opts.ListenUnix
+// when one answers, and are fetched otherwise. Without a player or lyrics
+// it prints nothing.
+func ConkyContext(ctx context.Context, opts Options) error {
+	s, ok := querySnapshot(opts)
+	if !ok {
+		var err error
+		if s, err = fetchSnapshot(ctx, opts); err != nil {
+			if errors.Is(err, mpris.ErrNoPlayer) || errors.Is(err, lyrics.ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+	}
+	return writeConky(os.Stdout, s, opts)
+}
+
+// fetchSnapshot returns the snapshot of a pool run until it is ready; see
+// waitReady.
+func fetchSnapshot(ctx context.Context, opts Options) (output.Snapshot, error) {
+	u, err := waitReady(ctx, opts)
+	if err != nil {
+		return output.Snapshot{}, err
+	}
+	if u.State == pool.StateNotFound || u.State == pool.StateError {
+		return output.Snapshot{}, u.Err
+	}
+	return output.NewSnapshot(u), nil
+}
+
+// writeConky writes the window of lines around the current one of s.
+func writeConky(w io.Writer, s output.Snapshot, opts Options) error {
+	if s.Index < 0 || s.Index >= len(s.Lines) {
+		return nil
+	}
+	current, other := opts.ConkyCurrent, opts.ConkyOther
+	if current == "" {
+		current = DefaultConkyCurrent
+	}
+	if other == "" {
+		other = DefaultConkyOther
+	}
+	around := max(opts.ConkyContext, 0)
+	var b strings.Builder
+	for i := max(0, s.Index-around); i <= s.Index+around && i < len(s.Lines); i++ {
+		color := other
+		if i == s.Index {
+			color = current
+		}
+		text := opts.width().Cut(stripControl(s.Lines[i].Text))
+		fmt.Fprintf(&b, "${%s}%s${color}\n", color, conkyEscape(text))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// conkyEscape keeps conky from reading variables in s.
+func conkyEscape(s string) string {
+	return strings.ReplaceAll(s, "$", "$$")
+}
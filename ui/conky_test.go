@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+)
+
+func TestConkyWithoutInstance(t *testing.T) {
+	lines := []lyrics.LyricLine{{Time: 10, Text: "one"}, {Time: 20, Text: "two $5"}, {Time: 30, Text: "three"}, {Time: 40, Text: "four"}}
+	r := pipeStdout(t)
+	stdout := os.Stdout
+	opts := Options{
+		ListenUnix:   filepath.Join(t.TempDir(), "none.sock"), // no instance to ask
+		ConkyContext: 1,
+		Player:       newFakePlayer(mpris.TrackMetadata{Title: "Song", Artist: "Band"}, 60, 25, true),
+		Lyrics:       fakeLyrics{lines: lines},
+	}
+	err := ConkyContext(context.Background(), opts)
+	stdout.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(r)
+	want := "${color2}one${color}\n${color1}two $$5${color}\n${color2}three${color}\n"
+	if string(got) != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"context"
+	"strings"
+
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/pool"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Player is the player the UI follows and controls; mpris.NewClient
+// returns the active one on the session bus. Every control, whether from
+// the keys, the tray, i3blocks clicks or WebSocket commands, goes through
+// it.
+type Player interface {
+	pool.PlayerSource
+	PlayPause(ctx context.Context) error
+	Next(ctx context.Context) error
+	Previous(ctx context.Context) error
+	// Seek moves the position by offset seconds, SetPosition to pos.
+	Seek(ctx context.Context, offset float64) error
+	SetPosition(ctx context.Context, pos float64) error
+	GetCapabilities(ctx context.Context) (mpris.Capabilities, error)
+}
+
+// seekStep is how far the arrow keys seek, in seconds.
+const seekStep = 5.0
+
+// capsMsg carries the active player's capabilities.
+type capsMsg mpris.Capabilities
+
+// capabilitiesCmd reads the active player's capabilities off the UI goroutine.
+func (m *Model) capabilitiesCmd() tea.Cmd {
+	ctx, player := m.ctx, m.opts.Player
+	return func() tea.Msg {
+		caps, err := player.GetCapabilities(ctx)
+		if err != nil {
+			return nil
+		}
+		return capsMsg(caps)
+	}
+}
+
+// keyHint returns the key help shown briefly at startup, listing only the
+// controls the player supports once its capabilities are known.
+func (m *Model) keyHint() string {
+	caps := mpris.Capabilities{CanGoNext: true, CanGoPrevious: true, CanPause: true, CanSeek: true}
+	if m.caps != nil {
+		caps = *m.caps
+	}
+	hints := []string{"q quit"}
+	if caps.CanPause {
+		hints = append(hints, "space play/pause")
+	}
+	if caps.CanGoNext || caps.CanGoPrevious {
+		hints = append(hints, "n/p next/prev")
+	}
+	if caps.CanSeek {
+		hints = append(hints, "←/→ seek")
+	}
+	hints = append(hints, "-/+ offset", "? help")
+	return strings.Join(hints, " · ")
+}
@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// press runs the action bound to key, and the player control it returns.
+func press(t *testing.T, m *Model, key string) {
+	t.Helper()
+	for _, b := range keymap {
+		if slices.Contains(b.keys, key) {
+			if cmd := b.action(m); cmd != nil {
+				if msg, ok := cmd().(noticeMsg); ok {
+					t.Errorf("key %q: %s", key, msg)
+				}
+			}
+			return
+		}
+	}
+	t.Fatalf("no binding for %q", key)
+}
+
+func TestKeyControls(t *testing.T) {
+	track := mpris.TrackMetadata{Title: "Song", Artist: "Band"}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{" ", "playpause"},
+		{"n", "next"},
+		{"p", "previous"},
+		{"left", "seek -5"},
+		{"right", "seek 5"},
+		{"enter", "position 13.9"}, // line 1, less the lead
+	}
+	for _, tt := range tests {
+		player := newFakePlayer(track, 200, 15, true)
+		opts := Options{Player: player, Lead: 100 * time.Millisecond}
+		m := newTestModel(30, 16, opts, pool.Update{
+			State: pool.StateReady, Lines: wrappingLines(4), Index: 1, Playing: true, Position: 15, Duration: 200, Track: track,
+		})
+		press(t, m, tt.key)
+		if got := player.used(); !slices.Equal(got, []string{tt.want}) {
+			t.Errorf("key %q: controls %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestClickControls(t *testing.T) {
+	player := newFakePlayer(mpris.TrackMetadata{Title: "Song", Artist: "Band"}, 200, 15, true)
+	clicks := `{"button":1}` + "\n" + `{"button":3}` + "\n" + `{"button":4}` + "\n" + `{"button":5}` + "\n" + `{"button":2}` + "\n"
+	readClicks(context.Background(), strings.NewReader(clicks), player, false)
+	want := []string{"playpause", "next", "seek 5", "seek -5"}
+	if got := player.used(); !slices.Equal(got, want) {
+		t.Errorf("controls %q, want %q", got, want)
+	}
+}
+
+func TestCapabilitiesFromPlayer(t *testing.T) {
+	player := newFakePlayer(mpris.TrackMetadata{Title: "Song", Artist: "Band"}, 200, 15, true)
+	m := newTestModel(30, 16, Options{Player: player}, pool.Update{Index: -1})
+	msg, ok := m.capabilitiesCmd()().(capsMsg)
+	if !ok || !msg.CanSeek || !msg.CanPause {
+		t.Errorf("capabilities %+v, %v", msg, ok)
+	}
+}
+
+func TestOffsetKeys(t *testing.T) {
+	m := newTestModel(30, 16, Options{}, pool.Update{Index: -1})
+	for _, step := range []struct {
+		key  string
+		want float64
+	}{{"+", 0.1}, {"=", 0.2}, {"-", 0.1}, {"]", 1.1}, {"[", 0.1}, {"-", 0}, {"[", -1}} {
+		press(t, m, step.key)
+		if math.Abs(m.offset-step.want) > 1e-9 {
+			t.Errorf("after %q: offset %v, want %v", step.key, m.offset, step.want)
+		}
+	}
+	for _, label := range []string{"-/+", "[/]"} {
+		if !slices.ContainsFunc(keymap, func(b binding) bool { return b.label == label }) {
+			t.Errorf("no help for %s", label)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Countdown styles for intros and instrumental gaps.
+const (
+	CountdownSeconds = "seconds" // "♪ 12s"
+	CountdownDots    = "dots"    // "● ● ●", one dot disappearing per third of the gap
+	CountdownOff     = "off"
+)
+
+// DefaultCountdownThreshold is the default CountdownThreshold, in seconds.
+const DefaultCountdownThreshold = 5
+
+// countdown reports whether the playhead is in an intro or a long
+// instrumental gap, returning the seconds until the next line and the total
+// length of the wait. The intro is everything before the first line; a gap
+// is a line followed by more than twice the threshold of silence, and its
+// countdown starts once the line has been shown for the threshold.
+func (m *Model) countdown() (remaining, span float64, intro, ok bool) {
+	lines := m.state.Lines
+	if m.opts.Countdown == CountdownOff || len(lines) == 0 || !m.synced() {
+		return 0, 0, false, false
+	}
+	pos := m.lyricPosition()
+	if pos < lines[0].Time {
+		return lines[0].Time - pos, lines[0].Time, true, true
+	}
+	next := m.state.Index + 1
+	if m.state.Index < 0 || next >= len(lines) {
+		return 0, 0, false, false
+	}
+	start, end := lines[m.state.Index].Time, lines[next].Time
+	threshold := m.opts.CountdownThreshold
+	if end-start <= 2*threshold || pos-start < threshold || pos >= end {
+		return 0, 0, false, false
+	}
+	return end - pos, end - start - threshold, false, true
+}
+
+// countdownText formats the wait until the next line in the configured style.
+func (m *Model) countdownText(remaining, span float64) string {
+	if m.opts.Countdown == CountdownDots {
+		dots := 3
+		if span > 0 {
+			dots = int(math.Ceil(3 * remaining / span))
+		}
+		dots = max(1, min(3, dots))
+		return strings.TrimSpace(strings.Repeat("● ", dots))
+	}
+	return fmt.Sprintf("♪ %ds", int(math.Ceil(remaining)))
+}
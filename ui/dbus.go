@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"log"
+	"slices"
+
+	"github.com/best8oy/LyricsMPRIS/lyricsbus"
+	"github.com/best8oy/LyricsMPRIS/output"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// busExporter keeps the lyricsbus service in step with the pool, setting
+// each property only when it changes so that clients see no spurious
+// PropertiesChanged signals.
+type busExporter struct {
+	svc     *lyricsbus.Service
+	verbose bool
+	lines   []string
+	index   int
+	tracker output.LineTracker
+}
+
+func newBusExporter(opts Options) (*busExporter, error) {
+	svc, err := lyricsbus.Export()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Verbose {
+		log.Printf("dbus-export: serving %s at %s", lyricsbus.Name, lyricsbus.Path)
+	}
+	return &busExporter{svc: svc, verbose: opts.Verbose, index: -1}, nil
+}
+
+func (b *busExporter) update(u pool.Update) {
+	var lines []string
+	if u.Err == nil && !u.Loading {
+		lines = make([]string, len(u.Lines))
+		for i, l := range u.Lines {
+			lines[i] = l.Text
+		}
+	}
+	if !slices.Equal(lines, b.lines) {
+		b.lines, b.index = lines, -1
+		b.svc.SetLines(lines)
+	}
+	index, text := -1, ""
+	if u.Index >= 0 && u.Index < len(b.lines) {
+		index, text = u.Index, b.lines[u.Index]
+	}
+	if index != b.index {
+		b.index = index
+		b.svc.SetIndex(index, text)
+	}
+	if index >= 0 && b.tracker.Next(u) {
+		if err := b.svc.LineChanged(index, text); err != nil && b.verbose {
+			log.Printf("dbus-export: %v", err)
+		}
+	}
+}
+
+func (b *busExporter) close() {
+	b.svc.Close()
+}
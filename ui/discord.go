@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"github.com/best8oy/LyricsMPRIS/discord"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// discordPresence shows the track and current line as the Discord Rich
+// Presence, clearing it while paused or without a line.
+type discordPresence struct {
+	client *discord.Client
+	last   *discord.Activity
+}
+
+func newDiscordPresence(appID string, opts Options) *discordPresence {
+	return &discordPresence{client: discord.Start(appID, opts.Verbose)}
+}
+
+func (d *discordPresence) update(u pool.Update) {
+	var a *discord.Activity
+	if u.Playing && u.Err == nil && !u.Loading && u.Index >= 0 && u.Index < len(u.Lines) {
+		a = &discord.Activity{
+			Details: u.Track.Artist + " – " + u.Track.Title,
+			State:   u.Lines[u.Index].Text,
+		}
+	}
+	if a == nil && d.last == nil || a != nil && d.last != nil && *a == *d.last {
+		return
+	}
+	d.last = a
+	d.client.SetActivity(a)
+}
+
+// close clears the presence and disconnects.
+func (d *discordPresence) close() {
+	d.client.Close()
+}
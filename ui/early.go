@@ -0,0 +1,17 @@
+package ui
+
+import "github.com/best8oy/LyricsMPRIS/pool"
+
+// emitEarly returns u as the outputs see it with opts.EmitEarly: the line
+// selected at position + lead + offset + EmitEarly rather than without
+// EmitEarly, but never more than one line past u.Index, so a line never
+// goes out before the line it follows has really started. The terminal
+// UI, a11y and notify modes get u itself.
+func emitEarly(u pool.Update, opts Options) pool.Update {
+	if opts.EmitEarly <= 0 || u.Err != nil || u.Loading || len(u.Lines) == 0 {
+		return u
+	}
+	position := u.Position + (opts.Lead + opts.EmitEarly).Seconds() + u.Offset
+	u.Index = min(pool.IndexAt(u.Lines, position), u.Index+1)
+	return u
+}
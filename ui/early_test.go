@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+func TestEmitEarly(t *testing.T) {
+	lines := []lyrics.LyricLine{{Time: 10, Text: "one"}, {Time: 20, Text: "two"}, {Time: 21, Text: "three"}, {Time: 30, Text: "four"}}
+	tests := []struct {
+		name     string
+		position float64
+		offset   float64
+		lead     time.Duration
+		early    time.Duration
+		want     int
+	}{
+		{name: "off", position: 19.5, want: 0},
+		{name: "early alone", position: 19.5, early: time.Second, want: 1},
+		{name: "not yet", position: 18.5, early: time.Second, want: 0},
+		// the line goes out at its time - lead - early
+		{name: "lead and early add up", position: 19.3, lead: 300 * time.Millisecond, early: 400 * time.Millisecond, want: 1},
+		{name: "short of both", position: 19.2, lead: 300 * time.Millisecond, early: 400 * time.Millisecond, want: 0},
+		{name: "offset too", position: 18.8, offset: 0.5, lead: 300 * time.Millisecond, early: 400 * time.Millisecond, want: 1},
+		// three would be due, but two has not started
+		{name: "one line ahead at most", position: 19.5, early: 2 * time.Second, want: 1},
+		{name: "before the first line", position: 9.5, early: time.Second, want: 0},
+		{name: "last line", position: 29.5, early: 5 * time.Second, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position := tt.position + tt.lead.Seconds() + tt.offset
+			u := pool.Update{State: pool.StateReady, Lines: lines, Index: pool.IndexAt(lines, position), Position: tt.position, Offset: tt.offset, Playing: true}
+			got := emitEarly(u, Options{Lead: tt.lead, EmitEarly: tt.early})
+			if got.Index != tt.want {
+				t.Errorf("index %d, want %d", got.Index, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmitEarlyTiming(t *testing.T) {
+	// one is due at 0.5 - lead - early; two's own time would be 0.3 but
+	// it waits for one to really start at 0.5 - lead
+	lines := []lyrics.LyricLine{{Time: 0.5, Text: "one"}, {Time: 0.6, Text: "two"}}
+	player := newFakePlayer(mpris.TrackMetadata{Title: "Song", Artist: "Band"}, 60, 0, true)
+	opts := Options{Player: player, Lyrics: fakeLyrics{lines: lines}, Lead: 100 * time.Millisecond, EmitEarly: 200 * time.Millisecond}
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 700*time.Millisecond)
+	defer cancel()
+	var early, exact []time.Duration // when each line became current
+	for u := range listen(ctx, time.Second, nil, opts) {
+		if e := emitEarly(u, opts); e.Index == len(early) {
+			early = append(early, time.Since(start))
+		}
+		if u.Index == len(exact) {
+			exact = append(exact, time.Since(start))
+		}
+	}
+	check := func(what string, got []time.Duration, want ...time.Duration) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s: lines at %v, want at %v", what, got, want)
+		}
+		for i := range want {
+			if d := got[i] - want[i]; d < -10*time.Millisecond || d > 50*time.Millisecond {
+				t.Errorf("%s: line %d at %v, want %v", what, i, got[i], want[i])
+			}
+		}
+	}
+	check("outputs", early, 200*time.Millisecond, 400*time.Millisecond)
+	check("terminal", exact, 400*time.Millisecond, 500*time.Millisecond)
+}
@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+)
+
+// fakePlayer is a player on one track, playing or paused, for running the
+// pool behind ui tests without a session bus. Its position advances with
+// the wall clock while it plays, and it records the controls used.
+type fakePlayer struct {
+	mu       sync.Mutex
+	track    mpris.TrackMetadata
+	duration float64
+	position float64 // at at
+	at       time.Time
+	playing  bool
+	changed  chan<- struct{}
+	// controls are the controls used so far, e.g. "seek 5"
+	controls []string
+}
+
+func newFakePlayer(track mpris.TrackMetadata, duration, position float64, playing bool) *fakePlayer {
+	return &fakePlayer{track: track, duration: duration, position: position, at: time.Now(), playing: playing}
+}
+
+// setTrack moves the player to track, at its start, and signals the change.
+func (f *fakePlayer) setTrack(track mpris.TrackMetadata) {
+	f.mu.Lock()
+	f.track, f.position, f.at = track, 0, time.Now()
+	changed := f.changed
+	f.mu.Unlock()
+	if changed != nil {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (f *fakePlayer) GetMetadata(ctx context.Context) (*mpris.TrackMetadata, float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	track := f.track
+	return &track, f.duration, nil
+}
+
+func (f *fakePlayer) GetRate(ctx context.Context) (float64, error) {
+	return 1, nil
+}
+
+func (f *fakePlayer) GetPositionAndStatus(ctx context.Context) (float64, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.playing {
+		return f.position, "Paused", nil
+	}
+	return f.position + time.Since(f.at).Seconds(), "Playing", nil
+}
+
+func (f *fakePlayer) Watch(ctx context.Context, changed chan<- struct{}) error {
+	f.mu.Lock()
+	f.changed = changed
+	f.mu.Unlock()
+	<-ctx.Done()
+	return nil
+}
+
+// fakeLyrics serves the lyrics of byTitle for the tracks there, and lines
+// for the others.
+type fakeLyrics struct {
+	lines   []lyrics.LyricLine
+	byTitle map[string][]lyrics.LyricLine
+}
+
+func (f fakeLyrics) FetchLyrics(title, artist, album string, duration float64) (*lyrics.Lyric, error) {
+	lines, ok := f.byTitle[title]
+	if !ok {
+		lines = f.lines
+	}
+	if len(lines) == 0 {
+		return nil, lyrics.ErrNotFound
+	}
+	return &lyrics.Lyric{Lines: lines, Source: "fake"}, nil
+}
+
+// control records a control used.
+func (f *fakePlayer) control(format string, args ...any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.controls = append(f.controls, fmt.Sprintf(format, args...))
+	return nil
+}
+
+// used returns the controls used so far.
+func (f *fakePlayer) used() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.controls)
+}
+
+func (f *fakePlayer) PlayPause(ctx context.Context) error { return f.control("playpause") }
+func (f *fakePlayer) Next(ctx context.Context) error      { return f.control("next") }
+func (f *fakePlayer) Previous(ctx context.Context) error  { return f.control("previous") }
+
+func (f *fakePlayer) Seek(ctx context.Context, offset float64) error {
+	return f.control("seek %g", offset)
+}
+
+func (f *fakePlayer) SetPosition(ctx context.Context, pos float64) error {
+	return f.control("position %g", pos)
+}
+
+func (f *fakePlayer) GetCapabilities(ctx context.Context) (mpris.Capabilities, error) {
+	return mpris.Capabilities{CanGoNext: true, CanGoPrevious: true, CanPause: true, CanSeek: true}, nil
+}
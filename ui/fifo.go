@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// fifoWriter writes the pipe mode stream to the named pipe opts.FIFO for
+// any readers that come and go. It never blocks: with no reader attached,
+// or one that falls behind, the stream is dropped.
+type fifoWriter struct {
+	path    string
+	verbose bool
+	created bool // the node is ours to remove
+	fd      int  // write end, -1 while no reader is attached
+	stream  *pipeStream
+	buf     bytes.Buffer
+	mu      sync.Mutex // held while writing, so close waits for a write
+	closed  bool
+}
+
+// openFIFO creates the named pipe at path unless one is there already.
+func openFIFO(path string, opts Options) (*fifoWriter, error) {
+	f := &fifoWriter{path: path, verbose: opts.Verbose, fd: -1, stream: newPipeStream(opts)}
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if err := syscall.Mkfifo(path, 0o644); err != nil {
+			return nil, &os.PathError{Op: "mkfifo", Path: path, Err: err}
+		}
+		f.created = true
+	case err != nil:
+		return nil, err
+	case info.Mode()&os.ModeNamedPipe == 0:
+		return nil, fmt.Errorf("%s exists and is not a named pipe", path)
+	}
+	return f, nil
+}
+
+// update writes what u changes in the stream, dropping it when no reader
+// is attached.
+func (f *fifoWriter) update(u pool.Update) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return
+	}
+	f.buf.Reset()
+	if err := f.stream.write(&f.buf, u); err != nil || f.buf.Len() == 0 {
+		return
+	}
+	if f.fd < 0 {
+		// without O_NONBLOCK opening waits for a reader; with it the
+		// open fails with ENXIO until one is there
+		fd, err := syscall.Open(f.path, syscall.O_WRONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+		if err != nil {
+			if !errors.Is(err, syscall.ENXIO) {
+				log.Printf("fifo: %v", err)
+			}
+			return
+		}
+		f.fd = fd
+		if f.verbose {
+			log.Printf("fifo: reader attached to %s", f.path)
+		}
+	}
+	// written on the raw descriptor, so a full pipe fails with EAGAIN
+	// rather than parking the lyric loop until the reader catches up
+	_, err := syscall.Write(f.fd, f.buf.Bytes())
+	switch {
+	case err == nil:
+	case errors.Is(err, syscall.EAGAIN):
+		if f.verbose {
+			log.Print("fifo: reader is behind; dropping a line")
+		}
+	default:
+		// EPIPE once the last reader is gone; reopen for the next one
+		syscall.Close(f.fd)
+		f.fd = -1
+		if f.verbose {
+			log.Printf("fifo: reader detached from %s", f.path)
+		}
+	}
+}
+
+// close closes the write end and removes the node if openFIFO made it.
+func (f *fifoWriter) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	if f.fd >= 0 {
+		syscall.Close(f.fd)
+		f.fd = -1
+	}
+	if f.created {
+		os.Remove(f.path)
+	}
+}
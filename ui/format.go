@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// PipeLine is the data a pipe mode or output file format template is
+// rendered with.
+type PipeLine struct {
+	Line        string // the line, or its translation with TranslationOnly
+	Translation string // the translation shown under Line, if any
+	Next        string // the next line, if any
+	Artist      string
+	Title       string
+	Album       string
+	Elapsed     string // playback position as m:ss
+	Index       int    // index of the line in the lyrics, from 0
+}
+
+// ParseFormat parses a pipe mode or output file format template, e.g.
+// "{{.Artist}} – {{.Line}}". Templates referring to fields PipeLine
+// lacks are rejected here rather than when the first line is printed.
+func ParseFormat(spec string) (*template.Template, error) {
+	t, err := template.New("format").Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Execute(io.Discard, PipeLine{}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// pipeLine returns the template data for the current line of u.
+func pipeLine(u pool.Update, translation string) PipeLine {
+	original, translated := lineTexts(u.Lines[u.Index], translation)
+	var next string
+	if u.Index+1 < len(u.Lines) {
+		next, _ = lineTexts(u.Lines[u.Index+1], translation)
+	}
+	return PipeLine{
+		Line:        original,
+		Translation: translated,
+		Next:        next,
+		Artist:      u.Track.Artist,
+		Title:       u.Track.Title,
+		Album:       u.Track.Album,
+		Elapsed:     formatTime(u.Position),
+		Index:       u.Index,
+	}
+}
+
+// printLine writes the current line of u in pipe mode: through format when
+// set, otherwise the line and its translation, one per row. Each row is
+// cut to opts.MaxWidth.
+func printLine(w io.Writer, u pool.Update, opts Options) error {
+	line := pipeLine(u, opts.Translation)
+	if opts.Format == nil {
+		_, err := fmt.Fprintln(w, opts.width().Cut(line.Line))
+		if err == nil && line.Translation != "" {
+			_, err = fmt.Fprintln(w, opts.width().Cut(line.Translation))
+		}
+		return err
+	}
+	var b strings.Builder
+	if err := opts.Format.Execute(&b, line); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w, opts.width().Cut(b.String()))
+	return err
+}
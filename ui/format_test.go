@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+func TestPrintLineFormat(t *testing.T) {
+	u := pool.Update{
+		State:    pool.StateReady,
+		Lines:    []lyrics.LyricLine{{Time: 60, Text: "first"}, {Time: 83, Text: "second"}, {Time: 90, Text: "third"}},
+		Index:    1,
+		Playing:  true,
+		Position: 83.4,
+		Track:    mpris.TrackMetadata{Title: "Song", Artist: "Band"},
+	}
+	tests := []struct {
+		format string // "" for the default
+		want   string
+	}{
+		{"", "second\n"},
+		{"{{.Line}}", "second\n"},
+		{"{{.Artist}} – {{.Line}}", "Band – second\n"},
+		{`"{{.Line}}"`, "\"second\"\n"},
+		{"{{.Index}} {{.Elapsed}} {{.Title}}: {{.Line}} → {{.Next}}", "1 1:23 Song: second → third\n"},
+		{"[{{.Album}}]{{.Translation}}|{{.Line}}", "[]|second\n"}, // missing fields render empty
+	}
+	for _, tt := range tests {
+		var opts Options
+		if tt.format != "" {
+			f, err := ParseFormat(tt.format)
+			if err != nil {
+				t.Fatalf("ParseFormat(%q): %v", tt.format, err)
+			}
+			opts.Format = f
+		}
+		var b strings.Builder
+		if err := printLine(&b, u, opts); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("format %q printed %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestParseFormatErrors(t *testing.T) {
+	for _, spec := range []string{"{{.Line", "{{.Lyric}}", "{{.Line.Text}}"} {
+		if _, err := ParseFormat(spec); err == nil {
+			t.Errorf("ParseFormat(%q): no error", spec)
+		}
+	}
+}
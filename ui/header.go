@@ -0,0 +1,16 @@
+package ui
+
+import "github.com/best8oy/LyricsMPRIS/mpris"
+
+// renderHeader draws the "Artist — Title" row for the given track, followed by
+// the player name when known. It returns "" when no track is playing.
+func (m *Model) renderHeader(width int, track mpris.TrackMetadata) string {
+	if track.Title == "" && track.Artist == "" {
+		return ""
+	}
+	text := track.Artist + " — " + track.Title
+	if track.Player != "" {
+		text += " · " + track.Player
+	}
+	return m.styleHeader.Width(width).Align(m.hAlignment).Render(truncate(text, width))
+}
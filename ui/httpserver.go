@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// overlayPage is the page GET / serves: the lyrics, styled for a browser
+// source in OBS.
+//
+//go:embed overlay.html
+var overlayPage []byte
+
+// httpServer serves the JSON events of a hub over HTTP:
+//
+//	GET /         the overlay page
+//	GET /events   the events as Server-Sent Events, starting with a snapshot
+//	GET /current  the snapshot of the current state
+//	GET /ws       the events over a WebSocket, which takes commands back
+//
+// A client too far behind on /events or /ws loses its oldest events.
+type httpServer struct {
+	srv *http.Server
+}
+
+// listenHTTP listens on addr, on localhost when addr names no host, e.g.
+// ":8277".
+func listenHTTP(addr string, h *hub, opts Options) (*httpServer, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(overlayPage)
+	})
+	mux.HandleFunc("GET /current", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(encodeJSON(h.snapshot()))
+	})
+	mux.HandleFunc("GET /events", func(w http.ResponseWriter, r *http.Request) {
+		serveEvents(w, r, h)
+	})
+	mux.HandleFunc("GET /ws", func(w http.ResponseWriter, r *http.Request) {
+		serveWebSocket(w, r, h, opts)
+	})
+	s := &httpServer{srv: &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}}
+	go func() {
+		if err := s.srv.Serve(ln); err != http.ErrServerClosed {
+			log.Printf("listen-http: %v", err)
+		}
+	}()
+	if opts.Verbose {
+		log.Printf("listen-http: serving http://%s/", ln.Addr())
+	}
+	return s, nil
+}
+
+// serveEvents streams the events of h to one client until it goes or the
+// hub lets go of it.
+func serveEvents(w http.ResponseWriter, r *http.Request, h *hub) {
+	sub := h.subscribe(true)
+	if sub == nil {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.unsubscribe(sub)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	rc := http.NewResponseController(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg, ok := <-sub.events:
+			if !ok {
+				return
+			}
+			rc.SetWriteDeadline(time.Now().Add(clientWriteTimeout))
+			if _, err := w.Write(sseEvent(msg)); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// sseEvent frames a JSON line as a Server-Sent Event. JSON lines hold no
+// newline but the last, so one data field carries it.
+func sseEvent(msg []byte) []byte {
+	return append(append([]byte("data: "), bytes.TrimRight(msg, "\n")...), "\n\n"...)
+}
+
+// close shuts the server down. The /events streams end with the hub, which
+// must be closed first.
+func (s *httpServer) close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	s.srv.Shutdown(ctx)
+}
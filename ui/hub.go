@@ -0,0 +1,184 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/best8oy/LyricsMPRIS/output"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// subscriberBacklog is how many events a subscriber may fall behind.
+const subscriberBacklog = 64
+
+// hub fans the JSON events out to the subscribers of the socket and HTTP
+// servers. A subscriber starts with an output.Snapshot and gets the events
+// from there on, each a JSON line of its own.
+type hub struct {
+	mu      sync.Mutex
+	opts    Options
+	tracker output.Tracker
+	last    pool.Update
+	pool    *pool.Pool // the snapshots' source once set; see follow
+	subs    map[*subscriber]struct{}
+	closed  bool
+	clock   progressClock
+	done    chan struct{} // closed with the hub
+}
+
+// subscriber is a queue of events for one client. Its channel is closed
+// when the hub lets go of it.
+type subscriber struct {
+	events chan []byte
+	// dropOldest makes a full queue lose its oldest event for the new one
+	// rather than the subscriber being dropped.
+	dropOldest bool
+}
+
+func newHub(opts Options) *hub {
+	h := &hub{
+		opts:    opts,
+		tracker: output.Tracker{Pause: opts.PauseText},
+		last:    pool.Update{Index: -1},
+		subs:    make(map[*subscriber]struct{}),
+		clock:   progressClock{lead: opts.Lead},
+		done:    make(chan struct{}),
+	}
+	go h.tickProgress(opts)
+	return h
+}
+
+// tickProgress sends the progress events until the hub is closed.
+func (h *hub) tickProgress(opts Options) {
+	ticks, stop := progressTicks(opts)
+	defer stop()
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-ticks:
+			h.mu.Lock()
+			if e, ok := h.clock.event(); ok {
+				h.broadcast(encodeJSON(e))
+			}
+			h.mu.Unlock()
+		}
+	}
+}
+
+// subscribe returns a new subscriber with the snapshot queued, or nil once
+// the hub is closed.
+func (h *hub) subscribe(dropOldest bool) *subscriber {
+	s := &subscriber{events: make(chan []byte, subscriberBacklog), dropOldest: dropOldest}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	// queued under the lock, so no event slips in ahead of it
+	s.events <- encodeJSON(h.snapshotLocked())
+	h.subs[s] = struct{}{}
+	return s
+}
+
+// unsubscribe lets go of s; it is a no-op for one already let go.
+func (h *hub) unsubscribe(s *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.unsubscribeLocked(s)
+}
+
+func (h *hub) unsubscribeLocked(s *subscriber) {
+	if _, ok := h.subs[s]; !ok {
+		return
+	}
+	delete(h.subs, s)
+	close(s.events)
+}
+
+// follow takes the snapshots from p from now on.
+func (h *hub) follow(p *pool.Pool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pool = p
+}
+
+// snapshot returns the snapshot of the current state.
+func (h *hub) snapshot() output.Snapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.snapshotLocked()
+}
+
+// snapshotLocked returns the snapshot of the pool's state now, as the
+// outputs see it, or of the latest update without a pool; the lock must
+// be held.
+func (h *hub) snapshotLocked() output.Snapshot {
+	if h.pool == nil {
+		return output.NewSnapshot(h.last)
+	}
+	return output.NewSnapshot(emitEarly(h.pool.Snapshot(), h.opts))
+}
+
+// update queues the events u brings about for every subscriber, never
+// waiting for one.
+func (h *hub) update(u pool.Update) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.last = u
+	h.clock.update(u)
+	for _, e := range h.tracker.Events(u) {
+		h.broadcast(encodeJSON(e))
+	}
+}
+
+// broadcast queues msg for every subscriber; the lock must be held.
+func (h *hub) broadcast(msg []byte) {
+	for s := range h.subs {
+		if !h.offer(s, msg) {
+			h.unsubscribeLocked(s)
+		}
+	}
+}
+
+// offer queues msg for s, reporting false when s is full and must go.
+func (h *hub) offer(s *subscriber, msg []byte) bool {
+	select {
+	case s.events <- msg:
+		return true
+	default:
+	}
+	if !s.dropOldest {
+		return false
+	}
+	select {
+	case <-s.events:
+	default:
+	}
+	select {
+	case s.events <- msg:
+	default:
+	}
+	return true
+}
+
+// close lets go of every subscriber.
+func (h *hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	close(h.done)
+	for s := range h.subs {
+		h.unsubscribeLocked(s)
+	}
+}
+
+// encodeJSON returns v as a JSON line.
+func encodeJSON(v any) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+	return buf.Bytes()
+}
@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+
+	"github.com/best8oy/LyricsMPRIS/output"
+)
+
+// i3blocks mouse buttons.
+const (
+	buttonLeft       = 1
+	buttonRight      = 3
+	buttonScrollUp   = 4
+	buttonScrollDown = 5
+)
+
+// readClicks runs the control of player for each i3blocks click event read
+// from r, until r ends or ctx is done: left click plays/pauses, scrolling
+// seeks and right click skips to the next track. Blocks without click
+// events just never send any. Malformed events are skipped, and logged
+// when verbose.
+func readClicks(ctx context.Context, r io.Reader, player Player, verbose bool) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() && ctx.Err() == nil {
+		var click output.I3Click
+		if err := json.Unmarshal(scanner.Bytes(), &click); err != nil {
+			if verbose {
+				log.Printf("i3blocks: ignoring click event %q: %v", scanner.Text(), err)
+			}
+			continue
+		}
+		var err error
+		switch click.Button {
+		case buttonLeft:
+			err = player.PlayPause(ctx)
+		case buttonRight:
+			err = player.Next(ctx)
+		case buttonScrollUp:
+			err = player.Seek(ctx, seekStep)
+		case buttonScrollDown:
+			err = player.Seek(ctx, -seekStep)
+		}
+		if err != nil && verbose {
+			log.Printf("i3blocks: button %d: %v", click.Button, err)
+		}
+	}
+}
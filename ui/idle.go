@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"time"
+
+	gloss "github.com/charmbracelet/lipgloss"
+)
+
+// DefaultIdleTimeout is how long playback stays paused before the display dims.
+const DefaultIdleTimeout = 60 * time.Second
+
+// trackPause records when playback paused, given the next state. The
+// timer restarts on track changes and stops while playing.
+func (m *Model) trackPause(playing, trackChanged bool) {
+	switch {
+	case playing:
+		m.pausedSince = time.Time{}
+	case m.state.Playing || trackChanged || m.pausedSince.IsZero():
+		m.pausedSince = time.Now()
+	}
+}
+
+// idle reports whether playback has been paused long enough to dim the display.
+func (m *Model) idle() bool {
+	return m.opts.IdleTimeout > 0 &&
+		m.state.Track.Title != "" &&
+		!m.pausedSince.IsZero() &&
+		time.Since(m.pausedSince) >= m.opts.IdleTimeout
+}
+
+// wake restores the full display and restarts the idle timer.
+func (m *Model) wake() {
+	if !m.pausedSince.IsZero() {
+		m.pausedSince = time.Now()
+	}
+}
+
+// idleView renders the dimmed display: a single faint line naming the
+// paused track, or nothing with BlankOnPause.
+func (m *Model) idleView() string {
+	text := ""
+	if t := m.state.Track; !m.opts.BlankOnPause {
+		text = truncate("paused — "+t.Artist+" – "+t.Title, m.w)
+	}
+	return gloss.Place(m.w, m.h, gloss.Center, gloss.Center, m.styleHeader.Render(text))
+}
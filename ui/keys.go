@@ -0,0 +1,218 @@
+package ui
+
+import (
+	"math"
+	"strings"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	tea "github.com/charmbracelet/bubbletea"
+	gloss "github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// binding ties keys to an action. The keymap is the single source of both
+// key handling and the help overlay.
+type binding struct {
+	keys   []string // key names as reported by tea.KeyMsg.String
+	label  string   // keys as shown in the help
+	help   string
+	action func(m *Model) tea.Cmd
+}
+
+var keymap = []binding{
+	{[]string{"q", "ctrl+c"}, "q", "quit", func(m *Model) tea.Cmd {
+		return tea.Quit
+	}},
+	{[]string{"esc"}, "esc", "clear search, or quit", func(m *Model) tea.Cmd {
+		if m.query != "" {
+			m.clearSearch()
+			return nil
+		}
+		return tea.Quit
+	}},
+	{[]string{"?"}, "?", "show this help", func(m *Model) tea.Cmd {
+		m.help = true
+		return nil
+	}},
+	{[]string{" "}, "space", "play/pause", func(m *Model) tea.Cmd {
+		return m.playerCmd(Player.PlayPause)
+	}},
+	{[]string{"n"}, "n", "next track, or next match", func(m *Model) tea.Cmd {
+		if m.query != "" {
+			m.findMatch(1)
+			return nil
+		}
+		return m.playerCmd(Player.Next)
+	}},
+	{[]string{"N"}, "N", "previous match", func(m *Model) tea.Cmd {
+		m.findMatch(-1)
+		return nil
+	}},
+	{[]string{"p"}, "p", "previous track", func(m *Model) tea.Cmd {
+		return m.playerCmd(Player.Previous)
+	}},
+	{[]string{"left"}, "←/→", "seek 5s", func(m *Model) tea.Cmd {
+		return m.seekBy(-seekStep)
+	}},
+	{[]string{"right"}, "", "", func(m *Model) tea.Cmd {
+		return m.seekBy(seekStep)
+	}},
+	{[]string{"enter"}, "enter", "seek to the shown line", func(m *Model) tea.Cmd {
+		return m.seekTo(m.viewIndex())
+	}},
+	// "=" is "+" unshifted on most layouts
+	{[]string{"+", "="}, "-/+", "lyric offset ±0.1s", func(m *Model) tea.Cmd {
+		m.adjustOffset(offsetStep)
+		return nil
+	}},
+	{[]string{"-"}, "", "", func(m *Model) tea.Cmd {
+		m.adjustOffset(-offsetStep)
+		return nil
+	}},
+	{[]string{"]"}, "[/]", "lyric offset ±1s", func(m *Model) tea.Cmd {
+		m.adjustOffset(offsetBigStep)
+		return nil
+	}},
+	{[]string{"["}, "", "", func(m *Model) tea.Cmd {
+		m.adjustOffset(-offsetBigStep)
+		return nil
+	}},
+	{[]string{"up"}, "↑/↓", "scroll", func(m *Model) tea.Cmd {
+		m.scroll(-1)
+		return nil
+	}},
+	{[]string{"down"}, "", "", func(m *Model) tea.Cmd {
+		m.scroll(1)
+		return nil
+	}},
+	{[]string{"pgup"}, "pgup/pgdn", "scroll a page", func(m *Model) tea.Cmd {
+		m.scroll(-m.lyricsHeight)
+		return nil
+	}},
+	{[]string{"pgdown"}, "", "", func(m *Model) tea.Cmd {
+		m.scroll(m.lyricsHeight)
+		return nil
+	}},
+	{[]string{"home"}, "home/end", "scroll to the start or end", func(m *Model) tea.Cmd {
+		m.scroll(-math.MaxInt32)
+		return nil
+	}},
+	{[]string{"end"}, "", "", func(m *Model) tea.Cmd {
+		m.scroll(math.MaxInt32)
+		return nil
+	}},
+	{[]string{"v"}, "v", "toggle the full lyrics view", func(m *Model) tea.Cmd {
+		m.viewport = !m.viewport
+		m.follow()
+		return nil
+	}},
+	{[]string{"/"}, "/", "search", func(m *Model) tea.Cmd {
+		m.searching = true
+		m.query = ""
+		return nil
+	}},
+	{[]string{"y"}, "y", "copy the shown line", func(m *Model) tea.Cmd {
+		index := m.viewIndex()
+		if index < 0 || index >= len(m.state.Lines) {
+			return nil
+		}
+		return m.copyText(m.state.Lines[index].Text, "line")
+	}},
+	{[]string{"Y"}, "Y", "copy the lyrics as LRC", func(m *Model) tea.Cmd {
+		if len(m.state.Lines) == 0 {
+			return nil
+		}
+		return m.copyText(lyrics.FormatLRC(m.state.Lines), "lyrics")
+	}},
+	{[]string{"s"}, "s", "save the lyrics to a file", func(m *Model) tea.Cmd {
+		return m.saveLyrics()
+	}},
+	{[]string{"T"}, "T", "cycle translation display", func(m *Model) tea.Cmd {
+		m.toggleTranslation()
+		return nil
+	}},
+	{[]string{"t"}, "t", "toggle timestamps", func(m *Model) tea.Cmd {
+		m.timestamps = !m.timestamps
+		return nil
+	}},
+	{[]string{"i"}, "i", "toggle the status row", func(m *Model) tea.Cmd {
+		m.status = !m.status
+		return nil
+	}},
+	{[]string{"r"}, "r", "retry fetching lyrics", func(m *Model) tea.Cmd {
+		m.retryFetch()
+		return nil
+	}},
+	{[]string{"shift+left"}, "shift+←/→", "align lines left/right", func(m *Model) tea.Cmd {
+		m.hAlignment = max(0, m.hAlignment-0.5)
+		return nil
+	}},
+	{[]string{"shift+right"}, "", "", func(m *Model) tea.Cmd {
+		m.hAlignment = min(1, m.hAlignment+0.5)
+		return nil
+	}},
+}
+
+// handleKey runs the binding for a key press.
+func (m *Model) handleKey(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case m.help:
+		m.help = false // any key closes the help
+		return nil
+	case m.searching:
+		m.searchKey(msg)
+		return nil
+	}
+	key := msg.String()
+	for _, b := range keymap {
+		for _, k := range b.keys {
+			if k == key {
+				return b.action(m)
+			}
+		}
+	}
+	return nil
+}
+
+// helpBox renders the keymap as a bordered box.
+func (m *Model) helpBox() string {
+	var labels, helps []string
+	for _, b := range keymap {
+		if b.help != "" {
+			labels = append(labels, b.label)
+			helps = append(helps, b.help)
+		}
+	}
+	body := gloss.JoinHorizontal(gloss.Top,
+		m.styleCurrent.Render(strings.Join(labels, "\n")),
+		"  ",
+		strings.Join(helps, "\n"))
+	return gloss.NewStyle().
+		Border(gloss.RoundedBorder()).
+		Padding(0, 1).
+		Render(body)
+}
+
+// overlayHelp draws the help box centered over view, dimming what is behind it.
+func (m *Model) overlayHelp(view string) string {
+	help := m.helpBox()
+	box := strings.Split(help, "\n")
+	rows := strings.Split(view, "\n")
+	boxW := gloss.Width(help)
+	if len(box) > len(rows) || boxW > m.w {
+		return view // no room: leave the screen as is
+	}
+	top, left := (len(rows)-len(box))/2, (m.w-boxW)/2
+	for i, row := range rows {
+		plain := ansi.Strip(row)
+		plain += strings.Repeat(" ", max(0, m.w-measure(plain)))
+		if i < top || i >= top+len(box) {
+			rows[i] = m.styleHeader.Render(plain)
+			continue
+		}
+		rows[i] = m.styleHeader.Render(ansi.Truncate(plain, left, "")) +
+			box[i-top] +
+			m.styleHeader.Render(ansi.TruncateLeft(plain, left+boxW, ""))
+	}
+	return strings.Join(rows, "\n")
+}
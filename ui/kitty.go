@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// The kitty graphics protocol shows the cover art through Unicode
+// placeholders: the image is transmitted once under an ID, and the art in
+// the view is ordinary text, a placeholder character per cell with the ID
+// as its foreground color. The renderer diffs and lays it out like any
+// other text, and the terminal draws the image over it.
+// See https://sw.kovidgoyal.net/kitty/graphics-protocol/#unicode-placeholders
+
+// kittyPlaceholder is the character of every cell of a placed image.
+const kittyPlaceholder = '\U0010EEEE'
+
+// kittyDiacritics are the combining marks numbering placeholder rows and
+// columns, the first of the protocol's table. Only the first cell of a row
+// carries them; the terminal numbers the rest of the row on from it.
+var kittyDiacritics = []rune{
+	0x0305, 0x030D, 0x030E, 0x0310, 0x0312, 0x033D, 0x033E, 0x033F,
+	0x0346, 0x034A, 0x034B, 0x034C, 0x0350, 0x0351, 0x0352, 0x0357,
+	0x035B, 0x0363, 0x0364, 0x0365, 0x0366, 0x0367, 0x0368, 0x0369,
+	0x036A, 0x036B, 0x036C, 0x036D, 0x036E, 0x036F, 0x0483, 0x0484,
+}
+
+// kittyChunk is the most base64 payload one escape sequence carries.
+const kittyChunk = 4096
+
+// kittyImageID is the ID the art is transmitted under; every cover
+// replaces the last. It comes from the process ID, so that two instances
+// in one terminal keep apart, and fits a 24-bit color.
+var kittyImageID = max(uint32(os.Getpid())&0xffffff, 1)
+
+// kittySupported reports whether the terminal draws kitty graphics
+// through Unicode placeholders, as far as the environment tells: kitty and
+// Ghostty do. Inside tmux the transmission would need passing through and
+// the placeholders tmux's support, so it says no there.
+func kittySupported() bool {
+	if os.Getenv("TMUX") != "" {
+		return false
+	}
+	term := os.Getenv("TERM")
+	return term == "xterm-kitty" || os.Getenv("KITTY_WINDOW_ID") != "" ||
+		term == "xterm-ghostty" || os.Getenv("TERM_PROGRAM") == "ghostty"
+}
+
+// kittyTransmit returns the escape sequences transmitting img as a PNG
+// under id, with a virtual placement of cols×rows cells for placeholders
+// to show. The terminal answers nothing (q=2).
+func kittyTransmit(img image.Image, id uint32, cols, rows int) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	payload := base64.StdEncoding.EncodeToString(buf.Bytes())
+	var b strings.Builder
+	for first := true; first || payload != ""; first = false {
+		chunk := payload[:min(kittyChunk, len(payload))]
+		payload = payload[len(chunk):]
+		more := 0
+		if payload != "" {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(&b, "\x1b_Ga=T,U=1,f=100,q=2,i=%d,c=%d,r=%d,m=%d;%s\x1b\\", id, cols, rows, more, chunk)
+		} else {
+			fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	return b.String(), nil
+}
+
+// kittyDelete returns the escape sequence deleting the image id and its
+// placements, freeing the terminal's memory of it.
+func kittyDelete(id uint32) string {
+	return fmt.Sprintf("\x1b_Ga=d,d=I,q=2,i=%d\x1b\\", id)
+}
+
+// kittyPlaceholders returns the cols×rows placeholder cells showing the
+// image id. rows is at most len(kittyDiacritics).
+func kittyPlaceholders(id uint32, cols, rows int) string {
+	color := fmt.Sprintf("\x1b[38;2;%d;%d;%dm", id>>16&0xff, id>>8&0xff, id&0xff)
+	rest := strings.Repeat(string(kittyPlaceholder), cols-1)
+	lines := make([]string, rows)
+	for y := range lines {
+		lines[y] = color + string(kittyPlaceholder) + string(kittyDiacritics[y]) + string(kittyDiacritics[0]) + rest + "\x1b[39m"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// termOutput is the terminal the modern UI draws on. Writes are
+// serialized, so that sequences written beside the renderer, such as the
+// art's transmission, never land in the middle of a frame; bubbletea
+// writes each frame with a single Write. The embedded file keeps it a
+// terminal to bubbletea.
+type termOutput struct {
+	*os.File
+	mu sync.Mutex
+}
+
+func (t *termOutput) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.File.Write(p)
+}
+
+func (t *termOutput) WriteString(s string) (int, error) {
+	return t.Write([]byte(s))
+}
+
+var _ io.StringWriter = (*termOutput)(nil)
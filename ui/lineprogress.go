@@ -0,0 +1,44 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/output"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// DefaultProgressRate is how many progress events a second the structured
+// outputs send by default.
+const DefaultProgressRate = 10
+
+// progressClock interpolates the playback position between pool updates
+// with the wall clock, so progress events cost no player queries.
+type progressClock struct {
+	last     pool.Update
+	received time.Time
+	lead     time.Duration
+}
+
+func (c *progressClock) update(u pool.Update) {
+	c.last, c.received = u, time.Now()
+}
+
+// event returns the progress event for now, reporting false while paused
+// or without a line to show progress in.
+func (c *progressClock) event() (output.Event, bool) {
+	if !c.last.Playing {
+		return output.Event{}, false
+	}
+	position := c.last.PositionAfter(time.Since(c.received)) + c.lead.Seconds() + c.last.Offset
+	return output.ProgressEvent(c.last, position)
+}
+
+// progressTicks returns a channel ticking at opts.ProgressRate, and a stop
+// function. The channel is nil, never ticking, when the rate is 0.
+func progressTicks(opts Options) (<-chan time.Time, func()) {
+	if opts.ProgressRate <= 0 {
+		return nil, func() {}
+	}
+	t := time.NewTicker(time.Duration(float64(time.Second) / opts.ProgressRate))
+	return t.C, t.Stop
+}
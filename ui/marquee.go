@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/textwidth"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DefaultMarqueeSpeed is the default marquee scroll speed in cells per second.
+const DefaultMarqueeSpeed = 8
+
+// marqueePause is how long the marquee rests at each end of the line.
+const marqueePause = 1500 * time.Millisecond
+
+// marqueeMsg advances the marquee.
+type marqueeMsg struct{}
+
+// marqueeSpeed returns the scroll speed in cells per second.
+func (m *Model) marqueeSpeed() float64 {
+	if m.opts.MarqueeSpeed <= 0 {
+		return DefaultMarqueeSpeed
+	}
+	return m.opts.MarqueeSpeed
+}
+
+// marqueeOverflow returns how many cells the current line is wider than
+// the lyric area, or 0 when the marquee is off or the line fits.
+func (m *Model) marqueeOverflow() int {
+	if m.opts.Overflow != OverflowMarquee || m.state.Index < 0 || m.state.Index >= len(m.state.Lines) {
+		return 0
+	}
+	original, _ := lineTexts(m.state.Lines[m.state.Index], m.translation)
+	return max(0, measure(original)-m.textWidth())
+}
+
+// marqueeOffset returns how many cells the current line is scrolled by:
+// it rests at the start, scrolls to the end, rests there and starts over.
+func (m *Model) marqueeOffset() int {
+	overflow := m.marqueeOverflow()
+	if overflow == 0 {
+		return 0
+	}
+	scroll := time.Duration(float64(overflow) / m.marqueeSpeed() * float64(time.Second))
+	t := time.Since(m.marqueeStart) % (2*marqueePause + scroll)
+	switch {
+	case t < marqueePause:
+		return 0
+	case t < marqueePause+scroll:
+		return int((t - marqueePause).Seconds() * m.marqueeSpeed())
+	}
+	return overflow
+}
+
+// marquee returns the visible part of the current line's text s.
+func (m *Model) marquee(s string) string {
+	return textwidth.Slice(s, m.marqueeOffset(), m.textWidth())
+}
+
+// restartMarquee scrolls the current line from its start again.
+func (m *Model) restartMarquee() {
+	m.marqueeStart = time.Now()
+}
+
+// stepMarquee schedules the next marquee step while the current line
+// overflows, unless one is scheduled already.
+func (m *Model) stepMarquee() tea.Cmd {
+	if m.marqueeing || m.marqueeOverflow() == 0 {
+		return nil
+	}
+	m.marqueeing = true
+	step := time.Duration(float64(time.Second) / m.marqueeSpeed())
+	return tea.Tick(step, func(time.Time) tea.Msg { return marqueeMsg{} })
+}
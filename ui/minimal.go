@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"math"
+
+	"github.com/best8oy/LyricsMPRIS/pool"
+	gloss "github.com/charmbracelet/lipgloss"
+)
+
+// minimalView renders the minimal layout: the current line centered on
+// both axes, followed by the next line when there is room below it, with
+// no header, progress bar or notices.
+func (m *Model) minimalView() string {
+	m.lyricsTop = 0
+	if m.state.State != pool.StateReady {
+		return m.lyricsView(m.w, m.h)
+	}
+	m.rowLines = m.rowLines[:0]
+	m.lyricsWidth, m.lyricsHeight = m.w, m.h
+
+	remaining, span, intro, waiting := m.countdown()
+	m.waiting, m.intro = waiting, intro
+	var rows []string
+	next := m.state.Index + 1
+	if waiting || m.state.Index < 0 {
+		text := ""
+		if waiting {
+			text = m.countdownText(remaining, span)
+		}
+		rows = []string{m.styleHeader.
+			Width(m.lyricsWidth).
+			Align(m.hAlignment).
+			Render(text)}
+		next = int(math.Ceil(m.cursor()))
+	} else {
+		rows = m.renderLine(m.state.Index)
+	}
+	// center the current line by its rows, then fit the next line beneath
+	top := max(0, (m.h-len(rows))/2)
+	if next < len(m.state.Lines) {
+		if nextRows := m.renderLine(next); top+len(rows)+len(nextRows) <= m.h {
+			rows = append(rows, nextRows...)
+		}
+	}
+	lines := make([]string, m.h)
+	copy(lines[top:], rows)
+	return gloss.JoinVertical(m.hAlignment, lines...)
+}
+
+// twoLineView renders the two-line layout: the current line on the first
+// row and the next line, or the countdown during a wait, on the second,
+// each cut to a single row. At height 1 only the first row is drawn.
+func (m *Model) twoLineView() string {
+	m.lyricsTop = 0
+	if m.state.State != pool.StateReady {
+		return m.lyricsView(m.w, m.h)
+	}
+	m.rowLines = make([]int, m.h)
+	m.lyricsWidth, m.lyricsHeight = m.w, m.h
+
+	remaining, span, intro, waiting := m.countdown()
+	m.waiting, m.intro = waiting, intro
+	rows := make([]string, m.h)
+	for i := range m.rowLines {
+		m.rowLines[i] = -1
+	}
+	row := func(style gloss.Style, text string) string {
+		return style.Width(m.w).Align(m.hAlignment).Render(truncate(text, m.w))
+	}
+
+	current, next := m.state.Index, m.state.Index+1
+	if current >= 0 && current < len(m.state.Lines) && !(waiting && intro) {
+		original, _ := lineTexts(m.state.Lines[current], m.translation)
+		rows[0], m.rowLines[0] = row(m.lineStyle(current), original), current
+	} else {
+		rows[0] = row(m.styleHeader, "")
+	}
+	if m.h > 1 {
+		switch {
+		case waiting:
+			rows[1] = row(m.styleHeader, m.countdownText(remaining, span))
+		case next < len(m.state.Lines):
+			original, _ := lineTexts(m.state.Lines[next], m.translation)
+			rows[1], m.rowLines[1] = row(m.lineStyle(next), original), next
+		default:
+			rows[1] = row(m.styleHeader, "") // the last line has no successor
+		}
+	}
+	return gloss.JoinVertical(m.hAlignment, rows...)
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"encoding/json"
+
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/mqtt"
+	"github.com/best8oy/LyricsMPRIS/output"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// MQTT topics, under the configured prefix.
+const (
+	mqttTopicTrack = "track" // retained track metadata as JSON
+	mqttTopicLine  = "line"  // each lyric line; empty clears the display
+)
+
+// mqttTrack is the payload of the track topic.
+type mqttTrack struct {
+	Artist string `json:"artist"`
+	Title  string `json:"title"`
+	Album  string `json:"album,omitempty"`
+}
+
+// mqttPublisher publishes the track and lines to an MQTT broker. An empty
+// line is the will, clearing displays when the app dies.
+type mqttPublisher struct {
+	client *mqtt.Client
+	track  mpris.TrackMetadata
+	lines  output.LineTracker
+	ended  bool // the line was cleared at the end of the track
+}
+
+func newMQTTPublisher(cfg mqtt.Config, opts Options) *mqttPublisher {
+	cfg.WillTopic, cfg.Will = cfg.Prefix+"/"+mqttTopicLine, ""
+	return &mqttPublisher{client: mqtt.Start(cfg, opts.Verbose)}
+}
+
+func (p *mqttPublisher) update(u pool.Update) {
+	if u.Track != p.track {
+		p.track = u.Track
+		payload, _ := json.Marshal(mqttTrack{Artist: u.Track.Artist, Title: u.Track.Title, Album: u.Track.Album})
+		p.client.Publish(mqttTopicTrack, payload, true)
+		p.client.Publish(mqttTopicLine, nil, false)
+	}
+	if u.State == pool.StateEnded && !p.ended {
+		p.client.Publish(mqttTopicLine, nil, false) // the last line is over
+	}
+	p.ended = u.State == pool.StateEnded
+	if u.Err == nil && !u.Loading && p.lines.Next(u) {
+		p.client.Publish(mqttTopicLine, []byte(u.Lines[u.Index].Text), false)
+	}
+}
+
+func (p *mqttPublisher) close() {
+	p.client.Close()
+}
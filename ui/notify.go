@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/notify"
+	"github.com/best8oy/LyricsMPRIS/output"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// What desktop notifications are sent for.
+const (
+	NotifyLine  = "line"  // every new lyric line
+	NotifyTrack = "track" // track changes, with the first lines
+)
+
+// notifyTrackLines is how many lines a track change notification shows.
+const notifyTrackLines = 3
+
+// NotifyModeContext shows the lyrics as desktop notifications instead of
+// in the terminal, until ctx is done. Without a notification server it
+// warns on stderr and carries on, in case one starts later.
+func NotifyModeContext(ctx context.Context, pollInterval time.Duration, opts Options) {
+	n := &notify.Notifier{
+		AppName: "LyricsMPRIS",
+		Urgency: opts.NotifyUrgency,
+		Timeout: opts.NotifyTimeout,
+	}
+	defer n.Close()
+	ch := listen(ctx, pollInterval, nil, opts)
+	var (
+		track   mpris.TrackMetadata
+		lines   output.LineTracker
+		sent    bool // the track change was notified
+		warning string
+	)
+	for {
+		var summary, body string
+		select {
+		case <-ctx.Done():
+			return
+		case u, ok := <-ch:
+			if !ok {
+				return
+			}
+			if u.Track != track {
+				track, sent = u.Track, false
+			}
+			if u.Track.Title == "" || u.State == pool.StateFetching || u.State == pool.StateEnded {
+				continue
+			}
+			summary = u.Track.Artist + " – " + u.Track.Title
+			switch {
+			case opts.NotifyOn == NotifyTrack:
+				if sent {
+					continue
+				}
+				sent = true
+				body = trackNotification(u)
+			case u.State != pool.StateReady || !lines.Next(u):
+				continue
+			default:
+				body, _ = lineTexts(u.Lines[u.Index], opts.Translation)
+			}
+		}
+		if err := n.Send(summary, body); err != nil {
+			if err.Error() != warning {
+				log.Printf("desktop notifications unavailable: %v", err)
+				warning = err.Error()
+			}
+			n.Close() // reconnect next time
+		} else {
+			warning = ""
+		}
+	}
+}
+
+// trackNotification returns the body of a track change notification: the
+// first lines of the lyrics, or why there are none.
+func trackNotification(u pool.Update) string {
+	if u.State != pool.StateReady {
+		return "No lyrics found"
+	}
+	var lines []string
+	for _, l := range u.Lines[:min(notifyTrackLines, len(u.Lines))] {
+		lines = append(lines, l.Text)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/output"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// OnceContext prints the whole lyric sheet of the track opts.Player plays
+// and returns, without following playback: as plain text, LRC with
+// opts.Timestamps, a JSON output.Sheet with OutputJSON, or subtitles with
+// opts.Subtitles. It returns lyrics.ErrNotFound when the track has no
+// lyrics.
+func OnceContext(ctx context.Context, opts Options) error {
+	u, err := waitReady(ctx, opts)
+	if err != nil {
+		return err
+	}
+	lyric := &lyrics.Lyric{Lines: u.Lines, Source: u.Source}
+	switch u.State {
+	case pool.StateIdle:
+		return errors.New("nothing is playing")
+	case pool.StateEnded:
+		// the pool lets go of the lines at the end of the track
+		lyric, err = opts.Lyrics.FetchLyrics(u.Track.Title, u.Track.Artist, u.Track.Album, u.Duration)
+		if err != nil {
+			return err
+		}
+	case pool.StateReady:
+	default:
+		return u.Err
+	}
+	if lyric == nil || len(lyric.Lines) == 0 {
+		return lyrics.ErrNotFound
+	}
+
+	signal.Ignore(syscall.SIGPIPE) // a pager quitting early is no error
+	switch {
+	case opts.Subtitles != "":
+		var text string
+		if _, text, err = subtitles(lyric.Lines, u.Duration, opts); err == nil {
+			_, err = io.WriteString(os.Stdout, text)
+		}
+	case opts.Output == OutputJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(output.NewSheet(u.Track, lyric))
+	case opts.Timestamps:
+		_, err = io.WriteString(os.Stdout, lyrics.FormatLRC(lyric.Lines))
+	default:
+		_, err = io.WriteString(os.Stdout, lyrics.FormatText(lyric.Lines))
+	}
+	if errors.Is(err, syscall.EPIPE) {
+		return nil
+	}
+	return err
+}
+
+// waitReady runs a pool on opts.Player and opts.Lyrics until it is ready,
+// and returns its snapshot then, for the modes printing the state once;
+// see pool.Pool.WaitReady. Without a player it returns the player's error
+// rather than waiting for one.
+func waitReady(ctx context.Context, opts Options) (pool.Update, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	p := pool.New(opts.Player, opts.Lyrics, pool.Config{PollInterval: time.Second, Lead: opts.Lead})
+	ch := make(chan pool.Update, 1)
+	go p.Run(ctx, ch)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for u := range ch {
+			if u.State == pool.StateWaitingForPlayer {
+				cancel(u.Err)
+			}
+		}
+	}()
+	u, err := p.WaitReady(ctx)
+	cancel(nil)
+	<-done // the pool stopped
+	if err != nil {
+		return pool.Update{}, context.Cause(ctx)
+	}
+	return u, nil
+}
@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+)
+
+func TestOnce(t *testing.T) {
+	song := mpris.TrackMetadata{Title: "Song", Artist: "Band"}
+	lines := []lyrics.LyricLine{{Time: 1, Text: "one"}, {Time: 62.5, Text: "two"}}
+	tests := []struct {
+		name    string
+		track   mpris.TrackMetadata
+		opts    Options
+		want    string
+		wantErr error
+	}{
+		{name: "text", track: song, want: "one\ntwo\n"},
+		{name: "lrc", track: song, opts: Options{Timestamps: true}, want: "[00:01.00]one\n[01:02.50]two\n"},
+		{name: "no lyrics", track: mpris.TrackMetadata{Title: "Other", Artist: "Band"}, wantErr: lyrics.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := pipeStdout(t)
+			stdout := os.Stdout
+			opts := tt.opts
+			opts.Player = newFakePlayer(tt.track, 120, 30, true)
+			opts.Lyrics = fakeLyrics{byTitle: map[string][]lyrics.LyricLine{"Song": lines}}
+			err := OnceContext(context.Background(), opts)
+			stdout.Close()
+			got, _ := io.ReadAll(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// fileWriter keeps opts.OutputFile holding the current line, for OBS text
+// sources and desktop widgets that follow a file.
+type fileWriter struct {
+	opts Options
+
+	mu      sync.Mutex
+	content string
+	written bool
+}
+
+// update rewrites the file when u changes its content: the current line,
+// through opts.OutputFileFormat when set, or nothing while paused or
+// without lyrics. Write errors are logged; the lyrics go on regardless.
+func (f *fileWriter) update(u pool.Update) {
+	content := f.render(u)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.written && content == f.content {
+		return
+	}
+	if err := writeFileAtomic(f.opts.OutputFile, content); err != nil {
+		log.Printf("output file: %v", err)
+		return
+	}
+	f.content, f.written = content, true
+}
+
+// rewrite writes the file again with its content, e.g. after something
+// removed it.
+func (f *fileWriter) rewrite() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.written {
+		return
+	}
+	if err := writeFileAtomic(f.opts.OutputFile, f.content); err != nil {
+		log.Printf("output file: %v", err)
+	}
+}
+
+func (f *fileWriter) render(u pool.Update) string {
+	if !u.Playing || u.Err != nil || u.Loading || u.Index < 0 || u.Index >= len(u.Lines) {
+		return ""
+	}
+	line := pipeLine(u, f.opts.Translation)
+	if f.opts.OutputFileFormat == nil {
+		return line.Line
+	}
+	var b strings.Builder
+	if err := f.opts.OutputFileFormat.Execute(&b, line); err != nil {
+		log.Printf("output file: %v", err)
+		return line.Line
+	}
+	return b.String()
+}
+
+// writeFileAtomic replaces path with content through a temporary file in
+// the same directory, so readers never see it half written.
+func writeFileAtomic(path, content string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.WriteString(content)
+	if err == nil {
+		err = tmp.Chmod(0o644) // CreateTemp makes it private
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,247 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/output"
+	"github.com/best8oy/LyricsMPRIS/pool"
+	"golang.org/x/term"
+)
+
+// PipeModeContext prints lyrics line-by-line to stdout for pipe mode, or
+// in the structured format opts.Output names. It runs until ctx is done
+// or stdout is closed (e.g. by a restarting status bar), and returns any
+// other write error.
+func PipeModeContext(ctx context.Context, pollInterval time.Duration, opts Options) error {
+	// report writes to a closed pipe as EPIPE instead of dying of SIGPIPE
+	signal.Ignore(syscall.SIGPIPE)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch := listen(ctx, pollInterval, nil, opts)
+	if opts.Output == OutputI3Blocks {
+		go readClicks(ctx, os.Stdin, opts.Player, opts.Verbose)
+	}
+
+	out := &recordWriter{w: os.Stdout}
+	stream := newPipeStream(opts)
+	// progress is for the JSON consumers, never plain lines
+	var ticks <-chan time.Time
+	clock := progressClock{lead: opts.Lead}
+	if opts.Output == OutputJSON {
+		var stop func()
+		ticks, stop = progressTicks(opts)
+		defer stop()
+	}
+	var rewritten *string
+	// overwriting only makes sense on a terminal
+	overwrite := opts.Overwrite && opts.Output == OutputText && term.IsTerminal(int(os.Stdout.Fd()))
+	if opts.Overwrite && !overwrite {
+		log.Print("-overwrite needs text output to a terminal; printing lines")
+	}
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticks:
+			if e, ok := clock.event(); ok {
+				err = out.record(func(w io.Writer) error {
+					return output.WriteJSON(w, []output.Event{e})
+				})
+			}
+		case upd, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			upd = emitEarly(upd, opts)
+			clock.update(upd)
+			if overwrite {
+				line := overwriteLine(upd, opts)
+				if rewritten == nil || line != *rewritten {
+					err = out.record(func(w io.Writer) error {
+						_, err := io.WriteString(w, "\r\x1b[K"+line)
+						return err
+					})
+					rewritten = &line
+				}
+			} else {
+				err = out.record(func(w io.Writer) error {
+					return stream.write(w, upd)
+				})
+			}
+		}
+		if errors.Is(err, syscall.EPIPE) {
+			return nil // the reader went away
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// recordWriter writes records, each everything one update brings about,
+// to w with a single write: a reader sees a record whole and at once,
+// never in bursts or interleaved with another writer's.
+type recordWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+// record writes what write produces as one record; nothing is written
+// when write fails or produces nothing.
+func (r *recordWriter) record(write func(io.Writer) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf.Reset()
+	if err := write(&r.buf); err != nil || r.buf.Len() == 0 {
+		return err
+	}
+	_, err := r.w.Write(r.buf.Bytes())
+	return err
+}
+
+// pipeStream writes the pipe mode stream in the format opts.Output names,
+// keeping what it needs to write each change only once.
+type pipeStream struct {
+	opts    Options
+	track   mpris.TrackMetadata
+	lines   output.LineTracker
+	tracker output.Tracker
+	paused  bool // the pause placeholder was printed
+	waybar  *output.Waybar
+	polybar *string
+	bar     *string
+	block   *output.I3Block
+}
+
+func newPipeStream(opts Options) *pipeStream {
+	return &pipeStream{opts: opts, tracker: output.Tracker{Pause: opts.PauseText}}
+}
+
+// write writes to w what u changes in the stream, if anything.
+func (p *pipeStream) write(w io.Writer, upd pool.Update) error {
+	opts := p.opts
+	var err error
+	switch opts.Output {
+	case OutputJSON:
+		err = output.WriteJSON(w, p.tracker.Events(upd))
+	case OutputWaybar:
+		// every state is written, an empty text included, so the
+		// module clears rather than keeping a stale line
+		wb := output.NewWaybar(upd, opts.width(), opts.PauseText)
+		if p.waybar == nil || wb != *p.waybar {
+			err = output.WriteWaybar(w, wb)
+			p.waybar = &wb
+		}
+	case OutputPolybar:
+		line := polybarLine(upd, opts)
+		if p.polybar == nil || line != *p.polybar {
+			_, err = io.WriteString(w, line+"\n")
+			p.polybar = &line
+		}
+	case OutputLemonbar, OutputDzen2:
+		line := output.BarLine(opts.Output, upd, opts.width(), opts.PauseText, opts.Bar)
+		if p.bar == nil || line != *p.bar {
+			_, err = io.WriteString(w, line+"\n")
+			p.bar = &line
+		}
+	case OutputI3Blocks:
+		b := output.NewI3Block(upd, opts.width(), opts.PauseText)
+		if p.block == nil || b != *p.block {
+			err = output.WriteI3Block(w, b)
+			p.block = &b
+		}
+	default:
+		if upd.Track != p.track || upd.Restarted {
+			// until the new song's lyrics are fetched there is
+			// nothing to print
+			p.track, p.paused = upd.Track, false
+			if opts.TrackMarker && p.track.Title != "" {
+				_, err = io.WriteString(w, "== "+p.track.Artist+" – "+p.track.Title+" ==\n")
+			}
+		}
+		if err != nil || upd.Err != nil {
+			break
+		}
+		// the last line is over before the next track comes: the
+		// placeholder shows the gap as a pause does
+		ended := upd.State == pool.StateEnded && opts.PauseText != nil
+		if len(upd.Lines) == 0 && !ended {
+			break
+		}
+		if opts.PauseText != nil && (!upd.Playing || ended) {
+			if !p.paused {
+				_, err = io.WriteString(w, *opts.PauseText+"\n")
+				p.paused = true
+				p.lines.Reset() // print the line again on resume
+			}
+			break
+		}
+		p.paused = false
+		if p.lines.Next(upd) {
+			err = printLine(w, upd, opts)
+		}
+	}
+	return err
+}
+
+// width returns the limit MaxWidth puts on pipe mode output.
+func (opts Options) width() output.Width {
+	return output.Width{Max: opts.MaxWidth, Ellipsis: opts.Ellipsis}
+}
+
+// ParseOnPause parses what pipe mode shows while paused: "keep" the line
+// (nil), "blank" it (an empty text) or "text:…" for a placeholder.
+func ParseOnPause(spec string) (*string, error) {
+	switch {
+	case spec == "keep":
+		return nil, nil
+	case spec == "blank":
+		return new(string), nil
+	case strings.HasPrefix(spec, "text:"):
+		text := strings.TrimPrefix(spec, "text:")
+		return &text, nil
+	}
+	return nil, fmt.Errorf("unknown value %q: want keep, blank or text:PLACEHOLDER", spec)
+}
+
+// overwriteLine returns the text overwrite mode shows for u: the current
+// line, cut to the terminal width and opts.MaxWidth so it never wraps, or
+// nothing while paused or without a line.
+func overwriteLine(u pool.Update, opts Options) string {
+	if !u.Playing || u.Err != nil || u.Loading || u.Index < 0 || u.Index >= len(u.Lines) {
+		return ""
+	}
+	text, _ := lineTexts(u.Lines[u.Index], opts.Translation)
+	// leave the last column free: writing there wraps on some terminals
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err == nil {
+		text = truncate(text, width-1)
+	}
+	return opts.width().Cut(text)
+}
+
+// polybarLine returns the polybar line for u: the current line, or the
+// pause placeholder, cut to opts.MaxWidth, padded and between the prefix
+// and suffix, or an empty line when there is nothing to show.
+func polybarLine(u pool.Update, opts Options) string {
+	text, ok := output.CurrentText(u, opts.PauseText)
+	if !ok || text == "" {
+		return ""
+	}
+	text = opts.width().Cut(text)
+	return opts.Prefix + opts.Padding + text + opts.Padding + opts.Suffix
+}
@@ -0,0 +1,277 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+func TestPipeModeStopsWhilePaused(t *testing.T) {
+	player := newFakePlayer(mpris.TrackMetadata{Title: "Song", Artist: "Band"}, 200, 5, false)
+	opts := Options{
+		Player: player,
+		Lyrics: fakeLyrics{lines: []lyrics.LyricLine{{Time: 10, Text: "one"}, {Time: 40, Text: "two"}}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- PipeModeContext(ctx, time.Second, opts) }()
+	time.Sleep(100 * time.Millisecond) // settle on the paused track
+	cancel()
+	start := time.Now()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(start); d > 50*time.Millisecond {
+			t.Errorf("pipe mode took %v to stop", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pipe mode still running a second after cancelling")
+	}
+}
+
+func TestPipeStreamWaitsForFirstLine(t *testing.T) {
+	track := mpris.TrackMetadata{Title: "Song", Artist: "Band"}
+	lines := []lyrics.LyricLine{{Time: 5, Text: "one"}, {Time: 8, Text: "two"}}
+	s := newPipeStream(Options{})
+	var w strings.Builder
+	for _, pos := range []float64{0, 4.9} {
+		s.write(&w, pool.Update{State: pool.StateReady, Track: track, Lines: lines, Index: -1, Playing: true, Position: pos})
+	}
+	if w.Len() != 0 {
+		t.Errorf("printed %q before the first line", w.String())
+	}
+	s.write(&w, pool.Update{State: pool.StateReady, Track: track, Lines: lines, Index: 0, Playing: true, Position: 5})
+	if got := w.String(); got != "one\n" {
+		t.Errorf("printed %q at the first line, want %q", got, "one\n")
+	}
+}
+
+func TestPipeStreamFollowsTracks(t *testing.T) {
+	one := mpris.TrackMetadata{Title: "One", Artist: "Band"}
+	two := mpris.TrackMetadata{Title: "Two", Artist: "Band"}
+	linesOne := []lyrics.LyricLine{{Time: 1, Text: "one a"}, {Time: 2, Text: "one b"}, {Time: 3, Text: "one c"}}
+	linesTwo := []lyrics.LyricLine{{Time: 1, Text: "two a"}, {Time: 2, Text: "two b"}}
+	ready := func(track mpris.TrackMetadata, lines []lyrics.LyricLine, index int) pool.Update {
+		return pool.Update{State: pool.StateReady, Track: track, Lines: lines, Index: index, Playing: true, Position: lines[index].Time}
+	}
+	fetching := func(track mpris.TrackMetadata) pool.Update {
+		return pool.Update{State: pool.StateFetching, Track: track, Index: -1, Playing: true, Loading: true}
+	}
+	tests := []struct {
+		name    string
+		marker  bool
+		updates []pool.Update
+		want    string
+	}{
+		{
+			name:    "next track",
+			updates: []pool.Update{ready(one, linesOne, 0), ready(one, linesOne, 1), fetching(two), ready(two, linesTwo, 0), ready(two, linesTwo, 1)},
+			want:    "one a\none b\ntwo a\ntwo b\n",
+		},
+		{
+			name:    "nothing while fetching",
+			updates: []pool.Update{ready(one, linesOne, 0), fetching(two), fetching(two)},
+			want:    "one a\n",
+		},
+		{
+			// the new track's lyrics at an index printed for the old one
+			name:    "same index",
+			updates: []pool.Update{ready(one, linesOne, 1), ready(two, linesTwo, 1)},
+			want:    "one b\ntwo b\n",
+		},
+		{
+			name:    "back to the first track",
+			updates: []pool.Update{ready(one, linesOne, 0), ready(two, linesTwo, 0), ready(one, linesOne, 0)},
+			want:    "one a\ntwo a\none a\n",
+		},
+		{
+			name:    "track markers",
+			marker:  true,
+			updates: []pool.Update{fetching(one), ready(one, linesOne, 0), fetching(two), ready(two, linesTwo, 0)},
+			want:    "== Band – One ==\none a\n== Band – Two ==\ntwo a\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newPipeStream(Options{TrackMarker: tt.marker})
+			var w strings.Builder
+			for _, u := range tt.updates {
+				if err := s.write(&w, u); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := w.String(); got != tt.want {
+				t.Errorf("printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipeFollowsPlayer(t *testing.T) {
+	one := mpris.TrackMetadata{Title: "One", Artist: "Band"}
+	player := newFakePlayer(one, 60, 0, true)
+	opts := Options{
+		Player: player,
+		Lyrics: fakeLyrics{byTitle: map[string][]lyrics.LyricLine{
+			"One": {{Time: 0.05, Text: "one a"}, {Time: 0.15, Text: "one b"}},
+			"Two": {{Time: 0.05, Text: "two a"}, {Time: 0.15, Text: "two b"}},
+		}},
+		TrackMarker: true,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := listen(ctx, time.Second, nil, opts)
+	time.AfterFunc(300*time.Millisecond, func() {
+		player.setTrack(mpris.TrackMetadata{Title: "Two", Artist: "Band"})
+	})
+	time.AfterFunc(600*time.Millisecond, cancel)
+	s := newPipeStream(opts)
+	var w strings.Builder
+	for u := range ch {
+		if err := s.write(&w, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "== Band – One ==\none a\none b\n== Band – Two ==\ntwo a\ntwo b\n"
+	if got := w.String(); got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestPipeStreamReplays(t *testing.T) {
+	track := mpris.TrackMetadata{Title: "Song", Artist: "Band"}
+	lines := []lyrics.LyricLine{{Time: 10, Text: "one"}, {Time: 20, Text: "two"}, {Time: 30, Text: "three"}}
+	s := newPipeStream(Options{})
+	var w strings.Builder
+	// plays through, seeks back to the second line, and loops
+	for _, pos := range []float64{10, 20, 30, 21, 30, 39, 0, 10} {
+		u := pool.Update{State: pool.StateReady, Track: track, Lines: lines, Index: pool.IndexAt(lines, pos), Playing: true, Position: pos}
+		if err := s.write(&w, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "one\ntwo\nthree\ntwo\nthree\none\n"
+	if got := w.String(); got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+// pipeStdout points os.Stdout at a pipe for the test, returning its read
+// end.
+func pipeStdout(t *testing.T) *os.File {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	t.Cleanup(func() {
+		os.Stdout = stdout
+		w.Close()
+		r.Close()
+	})
+	return r
+}
+
+func TestPipeModeDeliversEachLineOnTime(t *testing.T) {
+	r := pipeStdout(t)
+	lines := []lyrics.LyricLine{{Time: 0.2, Text: "one"}, {Time: 0.4, Text: "two"}, {Time: 0.6, Text: "three"}}
+	player := newFakePlayer(mpris.TrackMetadata{Title: "Song", Artist: "Band"}, 60, 0, true)
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 800*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- PipeModeContext(ctx, time.Second, Options{Player: player, Lyrics: fakeLyrics{lines: lines}})
+	}()
+
+	buf := make([]byte, 256)
+	for _, line := range lines {
+		r.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := r.Read(buf)
+		if err != nil {
+			t.Fatalf("reading %q: %v", line.Text, err)
+		}
+		// every line is a write of its own, flushed when it is due
+		if got := string(buf[:n]); got != line.Text+"\n" {
+			t.Errorf("read %q, want %q alone", got, line.Text+"\n")
+		}
+		due := time.Duration(line.Time * float64(time.Second))
+		if late := time.Since(start) - due; late < -10*time.Millisecond || late > 50*time.Millisecond {
+			t.Errorf("%q arrived %v after it was due", line.Text, late)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipeModeStopsWhenReaderGoes(t *testing.T) {
+	r := pipeStdout(t)
+	r.Close()
+	player := newFakePlayer(mpris.TrackMetadata{Title: "Song", Artist: "Band"}, 60, 1, true)
+	opts := Options{Player: player, Lyrics: fakeLyrics{lines: []lyrics.LyricLine{{Time: 0, Text: "one"}}}}
+	done := make(chan error, 1)
+	go func() { done <- PipeModeContext(context.Background(), time.Second, opts) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("pipe mode with its reader gone: %v, want a clean stop", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pipe mode still running a second after its reader went")
+	}
+}
+
+func TestRecordWriterKeepsRecordsWhole(t *testing.T) {
+	r := pipeStdout(t)
+	stdout := os.Stdout
+	out := &recordWriter{w: stdout}
+	const writers, records = 8, 50
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range records {
+				out.record(func(w io.Writer) error {
+					// a record in pieces, as the formats write them
+					for _, part := range []string{"writer ", fmt.Sprint(i), " record ", fmt.Sprint(j), "\n"} {
+						io.WriteString(w, part)
+					}
+					return nil
+				})
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		stdout.Close()
+	}()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs := strings.Split(strings.TrimSuffix(string(got), "\n"), "\n")
+	if len(recs) != writers*records {
+		t.Fatalf("read %d records, want %d", len(recs), writers*records)
+	}
+	for _, rec := range recs {
+		var i, j int
+		if n, err := fmt.Sscanf(rec, "writer %d record %d", &i, &j); n != 2 || err != nil {
+			t.Errorf("record %q is interleaved", rec)
+		}
+	}
+}
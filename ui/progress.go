@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// minProgressBarWidth is the narrowest bar drawn; when less room is left
+// beside the elapsed/total time only the time is shown.
+const minProgressBarWidth = 10
+
+// renderProgress draws the playback progress for pos out of dur seconds,
+// sized to width cells: a bar followed by the elapsed and total time. With
+// an unknown duration only the elapsed time is shown, and it is prefixed
+// with "~" when estimated is set.
+func (m *Model) renderProgress(width int, pos, dur float64, estimated bool) string {
+	if width < 1 {
+		return ""
+	}
+	if pos < 0 {
+		pos = 0
+	}
+	if dur > 0 && pos > dur {
+		pos = dur
+	}
+	var text string
+	if !m.opts.NoTime {
+		text = progressText(pos, dur, estimated)
+	}
+	if dur <= 0 {
+		if text == "" {
+			return ""
+		}
+		return m.styleBefore.Width(width).Align(1).MaxWidth(width).Render(text)
+	}
+	barWidth := width
+	if text != "" {
+		barWidth -= len([]rune(text)) + 1
+		if barWidth < minProgressBarWidth {
+			return m.styleBefore.Width(width).Align(0.5).MaxWidth(width).Render(text)
+		}
+		text = " " + text
+	}
+	filled := int(float64(barWidth) * pos / dur)
+	return m.styleCurrent.Render(strings.Repeat("━", filled)) +
+		m.styleBefore.Render(strings.Repeat("─", barWidth-filled)+text)
+}
+
+// progressText formats "elapsed / total", or just the elapsed time when
+// dur is unknown, prefixed with "~" when the position is estimated.
+func progressText(pos, dur float64, estimated bool) string {
+	text := formatTime(pos)
+	if dur > 0 {
+		text += " / " + formatTime(dur)
+	}
+	if estimated {
+		text = "~" + text
+	}
+	return text
+}
+
+// formatTime formats seconds as m:ss, or h:mm:ss from an hour up.
+func formatTime(sec float64) string {
+	s := int(sec)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
@@ -0,0 +1,25 @@
+package ui
+
+import "testing"
+
+func TestProgressText(t *testing.T) {
+	tests := []struct {
+		pos, dur  float64
+		estimated bool
+		want      string
+	}{
+		{0, 0, false, "0:00"},
+		{7.9, 45, false, "0:07 / 0:45"},
+		{83, 225, false, "1:23 / 3:45"},
+		{83, 0, false, "1:23"},
+		{83, 225, true, "~1:23 / 3:45"},
+		{59.99, 3599, false, "0:59 / 59:59"},
+		{3600, 5025, false, "1:00:00 / 1:23:45"},
+		{4000, 0, true, "~1:06:40"},
+	}
+	for _, tt := range tests {
+		if got := progressText(tt.pos, tt.dur, tt.estimated); got != tt.want {
+			t.Errorf("progressText(%v, %v, %v) = %q, want %q", tt.pos, tt.dur, tt.estimated, got, tt.want)
+		}
+	}
+}
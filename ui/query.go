@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/output"
+)
+
+// queryTimeout bounds a query, connecting included, so a status line
+// never waits on a missing or wedged instance.
+const queryTimeout = 100 * time.Millisecond
+
+// DefaultSocketPath is where -query looks for an instance's socket when
+// -listen-unix names none: lyricsmpris.sock in $XDG_RUNTIME_DIR, or in
+// the temporary directory without one.
+func DefaultSocketPath() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return filepath.Join(os.TempDir(), fmt.Sprintf("lyricsmpris-%d.sock", os.Getuid()))
+	}
+	return filepath.Join(dir, "lyricsmpris.sock")
+}
+
+// Query prints the current line of the instance serving the Unix socket
+// opts.ListenUnix and returns, for status lines that run a command every
+// few seconds, such as tmux's. The line is cut to opts.MaxWidth, and while
+// paused replaced by opts.PauseText when set. With no instance answering
+// within queryTimeout it prints nothing, and that is no error.
+func Query(opts Options) error {
+	s, ok := querySnapshot(opts)
+	if !ok {
+		return nil
+	}
+	return printSnapshotLine(os.Stdout, s, opts)
+}
+
+// querySnapshot returns the snapshot the instance serving opts.ListenUnix
+// sends on connect, reporting false when none answers within queryTimeout.
+func querySnapshot(opts Options) (output.Snapshot, bool) {
+	path := opts.ListenUnix
+	if path == "" {
+		path = DefaultSocketPath()
+	}
+	conn, err := net.DialTimeout("unix", path, queryTimeout)
+	if err != nil {
+		return output.Snapshot{}, false
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(queryTimeout))
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return output.Snapshot{}, false
+	}
+	var s output.Snapshot
+	if err := json.Unmarshal(line, &s); err != nil || s.Type != output.EventSnapshot {
+		return output.Snapshot{}, false
+	}
+	return s, true
+}
+
+func printSnapshotLine(w io.Writer, s output.Snapshot, opts Options) error {
+	var text string
+	switch {
+	case s.Index < 0 || s.Index >= len(s.Lines):
+		return nil
+	case !s.Playing && opts.PauseText != nil:
+		text = *opts.PauseText
+	default:
+		text = s.Lines[s.Index].Text
+	}
+	_, err := fmt.Fprintln(w, opts.width().Cut(stripControl(text)))
+	return err
+}
@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/pool"
+	gloss "github.com/charmbracelet/lipgloss"
+)
+
+// errorView renders the current error in place of the lyrics, with a
+// retry hint when it is the lyric fetch that failed.
+func (m *Model) errorView(height int) string {
+	text := m.state.Err.Error()
+	if errors.Is(m.state.Err, lyrics.ErrUnreachable) {
+		text = lyrics.ErrUnreachable.Error()
+	}
+	rows := []string{m.styleCurrent.
+		Width(m.lyricsWidth).
+		Align(gloss.Center).
+		Render(text)}
+	if m.retry != nil && m.state.Track.Title != "" {
+		hint := "press r to retry"
+		if m.state.Failures > 1 {
+			hint += fmt.Sprintf(" · %d attempts", m.state.Failures)
+		}
+		rows = append(rows, m.styleHeader.
+			Width(m.lyricsWidth).
+			Align(gloss.Center).
+			Render(truncate(hint, m.lyricsWidth)))
+	}
+	return gloss.PlaceVertical(height, m.vPosition(), gloss.JoinVertical(gloss.Center, rows...))
+}
+
+// retryFetch asks the pool to fetch the current track's lyrics again. It
+// never blocks: a retry already pending makes another one pointless.
+func (m *Model) retryFetch() {
+	if m.retry == nil || m.state.State != pool.StateError && m.state.State != pool.StateNotFound {
+		return
+	}
+	select {
+	case m.retry <- struct{}{}:
+	default:
+	}
+}
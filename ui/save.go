@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Subtitle formats for Options.Subtitles.
+const (
+	SubtitlesSRT = "srt"
+	SubtitlesVTT = "vtt"
+)
+
+// saveLyrics writes the shown lyrics into the save directory off the UI
+// goroutine, as subtitles with opts.Subtitles, otherwise as .lrc when
+// synced and .txt when not, and reports the written path or the failure
+// as a notice.
+func (m *Model) saveLyrics() tea.Cmd {
+	if len(m.state.Lines) == 0 {
+		return nil
+	}
+	ext, text := ".txt", lyrics.FormatText(m.state.Lines)
+	if m.opts.Subtitles != "" {
+		var err error
+		if ext, text, err = subtitles(m.state.Lines, m.state.Duration, m.opts); err != nil {
+			m.setNotice("save failed: " + err.Error())
+			return nil
+		}
+	} else if m.synced() {
+		ext, text = ".lrc", lyrics.FormatLRC(m.state.Lines)
+	}
+	name := fileName(m.state.Track.Artist + " - " + m.state.Track.Title)
+	dir := m.opts.SaveDir
+	return func() tea.Msg {
+		path, err := saveFile(dir, name, ext, text)
+		if err != nil {
+			return noticeMsg("save failed: " + err.Error())
+		}
+		return noticeMsg("saved " + path)
+	}
+}
+
+// subtitles formats lines in opts.Subtitles, returning the file extension
+// too. The last cue ends at duration, the track's length in seconds, when
+// known.
+func subtitles(lines []lyrics.LyricLine, duration float64, opts Options) (ext, text string, err error) {
+	switch opts.Subtitles {
+	case SubtitlesSRT:
+		text, err = lyrics.FormatSRT(lines, duration, opts.SubtitlesBOM)
+	case SubtitlesVTT:
+		text, err = lyrics.FormatVTT(lines, duration)
+	default:
+		return "", "", fmt.Errorf("unknown subtitle format %q", opts.Subtitles)
+	}
+	return "." + opts.Subtitles, text, err
+}
+
+// saveFile writes text to name+ext in dir, which defaults to
+// $XDG_DATA_HOME/lyricsmpris/saved. An existing file is never overwritten:
+// a numeric suffix is added instead.
+func saveFile(dir, name, ext, text string) (string, error) {
+	if dir == "" {
+		dir = filepath.Join(dataHome(), "lyricsmpris", "saved")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	for n := 1; ; n++ {
+		path := filepath.Join(dir, name+ext)
+		if n > 1 {
+			path = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", name, n, ext))
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := f.WriteString(text); err != nil {
+			f.Close()
+			return "", err
+		}
+		return path, f.Close()
+	}
+}
+
+// dataHome returns $XDG_DATA_HOME, defaulting to ~/.local/share.
+func dataHome() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share")
+}
+
+// fileName makes s safe to use as a file name.
+func fileName(s string) string {
+	s = strings.ReplaceAll(stripControl(s), "/", "_")
+	if s = strings.TrimSpace(s); s == "" || s == "-" {
+		s = "lyrics"
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// searchKey handles a key press while the search input is open.
+func (m *Model) searchKey(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.clearSearch()
+	case tea.KeyEnter:
+		m.searching = false
+		if m.query == "" {
+			m.follow()
+		}
+	case tea.KeyBackspace:
+		if r := []rune(m.query); len(r) > 0 {
+			m.query = string(r[:len(r)-1])
+			m.findMatch(0)
+		}
+	case tea.KeySpace:
+		m.query += " "
+		m.findMatch(0)
+	case tea.KeyRunes:
+		m.query += string(msg.Runes)
+		m.findMatch(0)
+	}
+}
+
+// clearSearch closes the search input, forgets the query and resumes following.
+func (m *Model) clearSearch() {
+	m.searching = false
+	m.query = ""
+	m.follow()
+}
+
+// matches returns the indexes of the lines containing the query, ignoring case.
+func (m *Model) matches() []int {
+	if m.query == "" {
+		return nil
+	}
+	query := strings.ToLower(m.query)
+	var indexes []int
+	for i, line := range m.state.Lines {
+		if strings.Contains(strings.ToLower(line.Text), query) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// matching reports whether line index contains the query.
+func (m *Model) matching(index int) bool {
+	return m.query != "" &&
+		strings.Contains(strings.ToLower(m.state.Lines[index].Text), strings.ToLower(m.query))
+}
+
+// findMatch moves the window to the next match after the shown line when
+// dir is positive, the previous one when negative, or the first match at or
+// after the shown line when zero. The search wraps around.
+func (m *Model) findMatch(dir int) {
+	matches := m.matches()
+	if len(matches) == 0 {
+		return
+	}
+	from := m.viewIndex()
+	target := matches[0]
+	if dir < 0 {
+		target = matches[len(matches)-1]
+		for i := len(matches) - 1; i >= 0; i-- {
+			if matches[i] < from {
+				target = matches[i]
+				break
+			}
+		}
+	} else {
+		for _, index := range matches {
+			if index > from || dir == 0 && index == from {
+				target = index
+				break
+			}
+		}
+	}
+	m.jumpTo(target)
+}
+
+// jumpTo shows line index, detaching the window from the playing line.
+func (m *Model) jumpTo(index int) {
+	m.scrollIndex = index
+	m.scrolled = true
+	m.scrollUntil = time.Now().Add(scrollTimeout)
+	m.vpJump = true
+}
+
+// searchLine returns the search bar text, or "" when no search is active.
+func (m *Model) searchLine() string {
+	if m.searching {
+		return "/" + m.query + "▏"
+	}
+	if m.query == "" {
+		return ""
+	}
+	matches := m.matches()
+	current := 0
+	for i, index := range matches {
+		if index == m.viewIndex() {
+			current = i + 1
+		}
+	}
+	return fmt.Sprintf("/%s  %d/%d · n/N next/prev · esc clear", m.query, current, len(matches))
+}
@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"time"
+)
+
+// clientWriteTimeout is how long a write to a socket or HTTP client may
+// take before the client counts as gone.
+const clientWriteTimeout = 5 * time.Second
+
+// socketServer streams the JSON events of a hub to clients of the Unix
+// socket opts.ListenUnix. A client too far behind is dropped rather than
+// holding up the others.
+type socketServer struct {
+	ln      *net.UnixListener
+	hub     *hub
+	verbose bool
+}
+
+// listenUnix listens on the socket at path, readable by the user only. A
+// stale socket left at path by a crashed run is replaced.
+func listenUnix(path string, h *hub, opts Options) (*socketServer, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	// made private before any connection is accepted; the umask is the
+	// whole process's, and the outputs may be creating files meanwhile
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	s := &socketServer{ln: ln, hub: h, verbose: opts.Verbose}
+	go s.accept()
+	return s, nil
+}
+
+// removeStaleSocket removes the socket at path unless a server answers on
+// it. Anything but a socket is left alone, and listening then fails.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+	conn, err := net.Dial("unix", path)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another server", path)
+	}
+	return os.Remove(path)
+}
+
+func (s *socketServer) accept() {
+	for {
+		conn, err := s.ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			log.Printf("listen-unix: %v", err)
+			continue
+		}
+		sub := s.hub.subscribe(false)
+		if sub == nil {
+			conn.Close()
+			return
+		}
+		if s.verbose {
+			log.Print("listen-unix: client connected")
+		}
+		go s.serve(conn, sub)
+	}
+}
+
+// serve writes the events queued for sub to conn until the hub lets go of
+// sub or a write fails. A write to a client that stopped reading times out
+// after clientWriteTimeout.
+func (s *socketServer) serve(conn net.Conn, sub *subscriber) {
+	defer conn.Close()
+	for msg := range sub.events {
+		conn.SetWriteDeadline(time.Now().Add(clientWriteTimeout))
+		if _, err := conn.Write(msg); err != nil {
+			s.hub.unsubscribe(sub)
+			break
+		}
+	}
+	if s.verbose {
+		log.Print("listen-unix: client gone")
+	}
+}
+
+// close stops listening and removes the socket file. The clients go with
+// the hub.
+func (s *socketServer) close() {
+	s.ln.Close()
+}
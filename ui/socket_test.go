@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/output"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+func TestSocketSnapshotMidSong(t *testing.T) {
+	track := mpris.TrackMetadata{Title: "Song", Artist: "Band", Album: "Record"}
+	lines := []lyrics.LyricLine{{Time: 1, Text: "one"}, {Time: 2, Text: "two", Translation: "deux"}, {Time: 3, Text: "three"}}
+	h := newHub(Options{})
+	defer h.close()
+	h.update(pool.Update{State: pool.StateFetching, Track: track, Index: -1, Playing: true, Loading: true})
+	for i := range 2 {
+		h.update(pool.Update{State: pool.StateReady, Track: track, Lines: lines, Index: i, Playing: true, Position: lines[i].Time})
+	}
+
+	path := filepath.Join(t.TempDir(), "lyrics.sock")
+	mask := syscall.Umask(0o022)
+	syscall.Umask(mask)
+	s, err := listenUnix(path, h, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the outputs create files meanwhile, under the process's umask
+	if got := syscall.Umask(mask); got != mask {
+		t.Errorf("umask %#o after listening, want %#o", got, mask)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("socket mode %v (%v), want 0600", info.Mode().Perm(), err)
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	r := bufio.NewReader(conn)
+
+	var snap output.Snapshot
+	if err := readJSON(r, &snap); err != nil {
+		t.Fatal(err)
+	}
+	if snap.Type != output.EventSnapshot || snap.Title != "Song" || snap.Artist != "Band" || snap.Album != "Record" || !snap.Playing {
+		t.Errorf("snapshot %+v", snap)
+	}
+	if snap.Index != 1 || len(snap.Lines) != 3 {
+		t.Fatalf("snapshot at line %d of %d, want 1 of 3", snap.Index, len(snap.Lines))
+	}
+	for i, l := range snap.Lines {
+		want := output.Line{Text: lines[i].Text, Translation: lines[i].Translation, Index: i, Time: lines[i].Time}
+		if l != want {
+			t.Errorf("snapshot line %d is %+v, want %+v", i, l, want)
+		}
+	}
+
+	// the stream goes on from the snapshot
+	h.update(pool.Update{State: pool.StateReady, Track: track, Lines: lines, Index: 2, Playing: true, Position: 3})
+	var e output.Event
+	if err := readJSON(r, &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != output.EventLine || e.Line == nil || e.Index != 2 || e.Text != "three" {
+		t.Errorf("event after the snapshot %+v, want line 2", e)
+	}
+
+	s.close()
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("socket left behind: %v", err)
+	}
+}
+
+// readJSON decodes the next JSON line of r into v.
+func readJSON(r *bufio.Reader, v any) error {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(line, v)
+}
+
+func TestHubSnapshotFollowsPool(t *testing.T) {
+	lines := []lyrics.LyricLine{{Time: 0.1, Text: "one"}, {Time: 0.2, Text: "two"}}
+	opts := Options{
+		Player: newFakePlayer(mpris.TrackMetadata{Title: "Song", Artist: "Band"}, 60, 0, true),
+		Lyrics: fakeLyrics{lines: lines},
+	}
+	h := newHub(opts)
+	defer h.close()
+	p := pool.New(opts.Player, opts.Lyrics, pool.Config{PollInterval: time.Second})
+	h.follow(p)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan pool.Update, 1)
+	go p.Run(ctx, ch)
+	if _, err := p.WaitReady(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// the hub takes no update, and its snapshot moves on with the pool
+	time.Sleep(250 * time.Millisecond)
+	if s := h.snapshot(); s.Title != "Song" || len(s.Lines) != 2 || s.Index != 1 {
+		t.Errorf("snapshot of %q at line %d of %d, want line 1 of 2", s.Title, s.Index, len(s.Lines))
+	}
+}
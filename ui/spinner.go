@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// spinnerFrames are the frames of the fetching spinner.
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// spinnerInterval is the delay between spinner frames.
+const spinnerInterval = 100 * time.Millisecond
+
+// spinnerMsg advances the spinner.
+type spinnerMsg struct{}
+
+// spin starts the spinner unless it is already running.
+func (m *Model) spin() tea.Cmd {
+	if m.spinning {
+		return nil
+	}
+	m.spinning = true
+	return tea.Tick(spinnerInterval, func(time.Time) tea.Msg { return spinnerMsg{} })
+}
+
+// stepSpinner advances the spinner while lyrics are loading, and lets it
+// stop once they are not.
+func (m *Model) stepSpinner() tea.Cmd {
+	m.spinning = false
+	if !m.state.Loading {
+		return nil
+	}
+	m.spinnerFrame = (m.spinnerFrame + 1) % len(spinnerFrames)
+	return m.spin()
+}
+
+// loadingText returns the spinner line shown while lyrics are fetched.
+func (m *Model) loadingText() string {
+	text := spinnerFrames[m.spinnerFrame] + " Searching lyrics"
+	if t := m.state.Track; t.Title != "" {
+		text += " for " + t.Artist + " – " + t.Title
+	}
+	return text + "…"
+}
@@ -0,0 +1,98 @@
+package ui
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/output"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+// stateFileInterval is the least time between two writes of the state
+// file; a change within it is written when it is up.
+const stateFileInterval = 500 * time.Millisecond
+
+// stateFile keeps opts.StateFile holding the output.State of the latest
+// update, for widgets like eww that read a whole document.
+type stateFile struct {
+	path string
+
+	mu      sync.Mutex
+	pending []byte      // the document waiting for its write, if any
+	written []byte      // the document last written
+	last    time.Time   // when the file was last written
+	timer   *time.Timer // writes pending once the interval is up
+	closed  bool
+}
+
+func newStateFile(path string) *stateFile {
+	return &stateFile{path: path}
+}
+
+// update writes the state of u, at once unless the file was written less
+// than stateFileInterval ago. Write errors are logged.
+func (s *stateFile) update(u pool.Update) {
+	doc := encodeJSON(output.NewState(u, time.Now()))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.pending = doc
+	if s.timer != nil {
+		return // the pending write takes doc along
+	}
+	if wait := time.Until(s.last.Add(stateFileInterval)); wait > 0 {
+		s.timer = time.AfterFunc(wait, s.flush)
+		return
+	}
+	s.flushLocked()
+}
+
+func (s *stateFile) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timer = nil
+	if !s.closed {
+		s.flushLocked()
+	}
+}
+
+func (s *stateFile) flushLocked() {
+	if s.pending == nil {
+		return
+	}
+	if err := writeFileAtomic(s.path, string(s.pending)); err != nil {
+		log.Printf("state file: %v", err)
+	}
+	s.pending, s.written, s.last = nil, s.pending, time.Now()
+}
+
+// rewrite writes the file again at once, with the pending document or
+// else the one last written, e.g. after something removed it.
+func (s *stateFile) rewrite() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if s.pending == nil {
+		s.pending = s.written
+	}
+	s.flushLocked()
+}
+
+// close removes the file: a widget finding none knows nothing is running.
+func (s *stateFile) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		log.Printf("state file: %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+)
+
+// renderStatus draws the status row: the lyric source, how far the line
+// shown can be trusted, and the active offset. The line is "estimated"
+// while the pool goes by the wall clock rather than a position the player
+// reported (see pool.Update.Estimated), and "synced" or "unsynced" after
+// the lyrics' own timings otherwise.
+func (m *Model) renderStatus(width int) string {
+	var parts []string
+	if m.state.Source != "" {
+		parts = append(parts, m.state.Source)
+	}
+	switch {
+	case len(m.state.Lines) == 0:
+	case m.state.Estimated:
+		parts = append(parts, "estimated")
+	case lyrics.Timesynced(m.state.Lines):
+		parts = append(parts, "synced")
+	default:
+		parts = append(parts, "unsynced")
+	}
+	parts = append(parts, fmt.Sprintf("offset %+.1fs", m.offset))
+	return m.styleHeader.Width(width).Render(truncate(strings.Join(parts, " · "), width))
+}
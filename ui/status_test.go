@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/pool"
+)
+
+func TestStatusSync(t *testing.T) {
+	synced := []lyrics.LyricLine{{Time: 1, Text: "one"}, {Time: 2, Text: "two"}}
+	plain := []lyrics.LyricLine{{Text: "one"}, {Text: "two"}}
+	tests := []struct {
+		lines     []lyrics.LyricLine
+		estimated bool
+		want      string
+	}{
+		{synced, false, "lrclib · synced · offset +0.0s"},
+		{synced, true, "lrclib · estimated · offset +0.0s"},
+		{plain, false, "lrclib · unsynced · offset +0.0s"},
+		{plain, true, "lrclib · estimated · offset +0.0s"},
+		{nil, true, "lrclib · offset +0.0s"},
+	}
+	for _, tt := range tests {
+		m := newTestModel(60, 16, Options{}, pool.Update{
+			State:     pool.StateReady,
+			Lines:     tt.lines,
+			Index:     0,
+			Source:    "lrclib",
+			Estimated: tt.estimated,
+			Track:     mpris.TrackMetadata{Title: "Song", Artist: "Band"},
+		})
+		if got := strings.TrimSpace(m.renderStatus(60)); got != tt.want {
+			t.Errorf("%d lines, estimated %v: status %q, want %q", len(tt.lines), tt.estimated, got, tt.want)
+		}
+	}
+}
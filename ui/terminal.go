@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runProgram runs the modern UI for m. It is the one place that sets up
+// and tears down terminal state: bubbletea owns raw mode, the alternate
+// screen and the cursor, restoring them when the program quits, panics or
+// receives SIGINT/SIGTERM, and setupTerminal covers what we add on top.
+// Pipe mode never comes here, so it emits none of these sequences.
+func runProgram(ctx context.Context, m *Model, opts Options) error {
+	return runOn(ctx, m, opts, os.Stdout)
+}
+
+// runOn runs the modern UI for m on the terminal out, with the extra
+// program options. Every sequence, the title's included, goes to out, so
+// the state saved is restored where it was changed.
+func runOn(ctx context.Context, m *Model, opts Options, out *os.File, extra ...tea.ProgramOption) error {
+	m.out = &termOutput{File: out}
+	restore := setupTerminal(m.out, opts)
+	defer restore()
+	if m.artMode() == ArtKitty {
+		defer m.out.WriteString(kittyDelete(kittyImageID))
+	}
+	options := append(programOptions(ctx, opts), tea.WithOutput(m.out))
+	_, err := tea.NewProgram(m, append(options, extra...)...).Run()
+	switch {
+	case errors.Is(err, tea.ErrProgramKilled) && ctx.Err() != nil:
+		return nil // cancelled by the caller: a normal shutdown
+	case errors.Is(err, tea.ErrInterrupted):
+		return nil // SIGINT: the user quitting
+	}
+	return err
+}
+
+// Frame rates of the renderer: enough for smooth scrolling when animated,
+// and far more than lyric changes need otherwise.
+const (
+	animatedFPS = 60
+	staticFPS   = 20
+)
+
+// programOptions returns the bubbletea program options for the given UI options.
+func programOptions(ctx context.Context, opts Options) []tea.ProgramOption {
+	fps := animatedFPS
+	if opts.NoAnimation {
+		fps = staticFPS
+	}
+	options := []tea.ProgramOption{tea.WithContext(ctx), tea.WithAltScreen(), tea.WithFPS(fps)}
+	if opts.Mouse {
+		options = append(options, tea.WithMouseCellMotion())
+	}
+	return options
+}
+
+// setupTerminal prepares the terminal state the UI changes outside
+// bubbletea and returns a function restoring it. The restore function is
+// safe to call more than once, and runs on panics too when deferred.
+func setupTerminal(w io.Writer, opts Options) (restore func()) {
+	if opts.SetTitle {
+		pushTitle(w)
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if opts.SetTitle {
+				popTitle(w)
+			}
+			// reset attributes and show the cursor in case the program
+			// exited before bubbletea could
+			io.WriteString(w, "\x1b[0m\x1b[?25h")
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/pool"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSetupTerminalRestoresOnPanic(t *testing.T) {
+	var w bytes.Buffer
+	func() {
+		defer func() { recover() }()
+		restore := setupTerminal(&w, Options{SetTitle: true})
+		defer restore()
+		panic("render failed")
+	}()
+	out := w.String()
+	for _, seq := range []string{"\x1b[22;2t", "\x1b[23;2t", "\x1b[0m", "\x1b[?25h"} {
+		if !strings.Contains(out, seq) {
+			t.Errorf("output %q lacks %q", out, seq)
+		}
+	}
+}
+
+func TestTitleSequencesShareTheTerminal(t *testing.T) {
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+	f, err := os.CreateTemp(t.TempDir(), "tty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	opts := Options{SetTitle: true}
+	m := newTestModel(30, 10, opts, pool.Update{
+		State:   pool.StateReady,
+		Lines:   []lyrics.LyricLine{{Time: 0, Text: "one"}},
+		Index:   0,
+		Playing: true,
+		Track:   mpris.TrackMetadata{Title: "Song", Artist: "Band"},
+	})
+	m.out = &termOutput{File: f}
+	restore := setupTerminal(m.out, opts)
+	m.updateTitle()
+	m.updateTitle() // unchanged, so not sent again
+	restore()
+
+	out, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// saved, set and restored on the one terminal, each through tmux
+	want := "\x1bPtmux;\x1b\x1b[22;2t\x1b\\" +
+		"\x1bPtmux;\x1b\x1b]2;Band – one\x07\x1b\\" +
+		"\x1bPtmux;\x1b\x1b[23;2t\x1b\\" +
+		"\x1b[0m\x1b[?25h"
+	if string(out) != want {
+		t.Errorf("wrote %q, want %q", out, want)
+	}
+}
+
+func TestRunProgramRestoresOnPanic(t *testing.T) {
+	t.Setenv("TMUX", "") // the sequences unwrapped
+	f, err := os.CreateTemp(t.TempDir(), "tty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// bubbletea reports the panic on stdout and stderr
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = null, null
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+
+	opts := Options{SetTitle: true, NoAnimation: true}
+	m := newModel(context.Background(), make(chan pool.Update), opts)
+	// a filter runs in the event loop, just before Update
+	panics := tea.WithFilter(func(tea.Model, tea.Msg) tea.Msg { panic("update failed") })
+	runOn(context.Background(), m, opts, f, tea.WithInput(nil), panics)
+	os.Stdout, os.Stderr = stdout, stderr
+
+	out, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(out), "\x1b[22;2t") {
+		t.Errorf("output %q does not start by saving the title", out)
+	}
+	if want := "\x1b[23;2t\x1b[0m\x1b[?25h"; !strings.HasSuffix(string(out), want) {
+		t.Errorf("output %q does not end restoring the title, attributes and cursor", out)
+	}
+}
+
+func TestSetupTerminalRestoresOnce(t *testing.T) {
+	var w bytes.Buffer
+	restore := setupTerminal(&w, Options{})
+	restore()
+	n := w.Len()
+	restore()
+	if w.Len() != n {
+		t.Errorf("second restore wrote %q", w.String()[n:])
+	}
+}
+
+func TestPipeStreamWritesNoEscapes(t *testing.T) {
+	track := mpris.TrackMetadata{Title: "Song", Artist: "Band"}
+	lines := []lyrics.LyricLine{{Time: 1, Text: "one"}, {Time: 2, Text: "two"}}
+	pause := "paused"
+	s := newPipeStream(Options{TrackMarker: true, PauseText: &pause})
+	var w bytes.Buffer
+	for _, u := range []pool.Update{
+		{State: pool.StateFetching, Track: track, Index: -1, Loading: true},
+		{State: pool.StateReady, Track: track, Lines: lines, Index: 0, Playing: true},
+		{State: pool.StateReady, Track: track, Lines: lines, Index: 1},
+	} {
+		if err := s.write(&w, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Contains(w.String(), "\x1b") {
+		t.Errorf("pipe output %q holds escape sequences", w.String())
+	}
+}
@@ -0,0 +1,27 @@
+package ui
+
+import "github.com/best8oy/LyricsMPRIS/textwidth"
+
+// ellipsis is appended to text cut short to fit the terminal.
+const ellipsis = "…"
+
+// measure returns the display width of s in cells.
+func measure(s string) int {
+	return textwidth.Measure(s)
+}
+
+// truncate shortens s to at most width display cells, ending it with an
+// ellipsis when anything was cut. Grapheme clusters are never split.
+func truncate(s string, width int) string {
+	return textwidth.Truncate(s, width, ellipsis)
+}
+
+// fit prepares lyric text for a row of the lyric window: with the ellipsis
+// and marquee overflows it is cut to the width, otherwise it is left for
+// lipgloss to wrap.
+func (m *Model) fit(s string) string {
+	if m.opts.Overflow == OverflowWrap || m.opts.Overflow == "" {
+		return s
+	}
+	return truncate(s, m.textWidth())
+}
@@ -0,0 +1,130 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	gloss "github.com/charmbracelet/lipgloss"
+)
+
+// Theme configures how lyric lines are styled.
+type Theme struct {
+	// PastFade and FutureFade are the foreground colors of lines 1, 2, …
+	// before and after the current one; the last entry also applies to all
+	// farther lines. Empty ladders leave the lines in their base style.
+	PastFade   []gloss.TerminalColor
+	FutureFade []gloss.TerminalColor
+	// Past, Current and Future set the text attributes of lines before, at
+	// and after the playing one. Nil keeps the default: faint italic past
+	// lines, a bold current line and plain future lines.
+	Past, Current, Future *Attrs
+	// Background is BackgroundColor (the default) or BackgroundNone, which
+	// never paints a background so transparent terminals show through.
+	Background string
+}
+
+// Background modes.
+const (
+	BackgroundColor = "color" // backgrounds may be painted, e.g. reverse video
+	BackgroundNone  = "none"  // no background is ever painted
+)
+
+// Attrs is a set of text attributes.
+type Attrs struct {
+	Bold, Faint, Italic, Underline, Reverse, Strikethrough bool
+}
+
+// ParseAttrs parses a comma-separated list of attribute names
+// (e.g. "bold,underline"). An empty list means plain text.
+func ParseAttrs(spec string) (*Attrs, error) {
+	var a Attrs
+	for _, field := range strings.Split(spec, ",") {
+		switch name := strings.TrimSpace(field); name {
+		case "":
+		case "bold":
+			a.Bold = true
+		case "faint", "dim":
+			a.Faint = true
+		case "italic":
+			a.Italic = true
+		case "underline":
+			a.Underline = true
+		case "reverse":
+			a.Reverse = true
+		case "strikethrough":
+			a.Strikethrough = true
+		default:
+			return nil, fmt.Errorf("unknown attribute %q: want bold, faint, italic, underline, reverse or strikethrough", name)
+		}
+	}
+	return &a, nil
+}
+
+// style returns base with the attributes applied, or def when a is nil.
+// Without background, reverse video is left out since it paints one.
+func (a *Attrs) style(base, def gloss.Style, background bool) gloss.Style {
+	if a == nil {
+		return def
+	}
+	return base.
+		Bold(a.Bold).
+		Faint(a.Faint).
+		Italic(a.Italic).
+		Underline(a.Underline).
+		Reverse(a.Reverse && background).
+		Strikethrough(a.Strikethrough)
+}
+
+// ParseFade parses a comma-separated list of brightness percentages
+// (e.g. "85,70,55") into a fade ladder of grey levels. On light terminal
+// backgrounds the levels are mirrored so that fading still means receding.
+func ParseFade(spec string) ([]gloss.TerminalColor, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	var ladder []gloss.TerminalColor
+	for _, field := range strings.Split(spec, ",") {
+		p, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || p < 0 || p > 100 {
+			return nil, fmt.Errorf("invalid fade step %q: want a brightness between 0 and 100", field)
+		}
+		ladder = append(ladder, gloss.CompleteAdaptiveColor{
+			Dark:  grey(p),
+			Light: grey(100 - p),
+		})
+	}
+	return ladder, nil
+}
+
+// grey returns the grey of the given brightness percentage in every color profile.
+func grey(percent int) gloss.CompleteColor {
+	level := percent * 255 / 100
+	// the 256-color grey ramp (232-255) spans levels 8 to 238 in steps of 10
+	ansi256 := 232 + (level-8+5)/10
+	ansi256 = max(232, min(255, ansi256))
+	ansi := "7"
+	if percent < 50 {
+		ansi = "8"
+	}
+	return gloss.CompleteColor{
+		TrueColor: fmt.Sprintf("#%02x%02x%02x", level, level, level),
+		ANSI256:   strconv.Itoa(ansi256),
+		ANSI:      ansi,
+	}
+}
+
+// fade returns the ladder color for a line distance lines away from the
+// current one, or nil if the ladder is empty.
+func fade(ladder []gloss.TerminalColor, distance int) gloss.TerminalColor {
+	if len(ladder) == 0 || distance < 1 {
+		return nil
+	}
+	return ladder[min(distance, len(ladder))-1]
+}
+
+// background reports whether the UI may paint backgrounds.
+func (m *Model) background() bool {
+	return m.opts.Theme.Background != BackgroundNone
+}
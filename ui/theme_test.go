@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"image"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/pool"
+	gloss "github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+var sgr = regexp.MustCompile(`\x1b\[([0-9;:]*)m`)
+
+// paintsBackground reports whether s holds an SGR sequence setting a
+// background color (48, 40-47, 100-107) or reverse video (7).
+func paintsBackground(s string) bool {
+	for _, m := range sgr.FindAllStringSubmatch(s, -1) {
+		params := strings.Split(m[1], ";")
+		for i := 0; i < len(params); i++ {
+			p := params[i]
+			switch {
+			case p == "38" && i+1 < len(params) && params[i+1] == "5":
+				i += 2 // a 256-color foreground
+			case p == "38" && i+1 < len(params) && params[i+1] == "2":
+				i += 4 // a truecolor foreground
+			case p == "48", p == "7", len(p) == 2 && p >= "40" && p <= "47", len(p) == 3 && p >= "100" && p <= "107":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestBackgroundNone(t *testing.T) {
+	profile := gloss.ColorProfile()
+	gloss.SetColorProfile(termenv.TrueColor)
+	defer gloss.SetColorProfile(profile)
+
+	cover := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range cover.Pix {
+		cover.Pix[i] = byte(i * 37)
+	}
+	for _, background := range []string{BackgroundColor, BackgroundNone} {
+		opts := Options{
+			Art:   ArtMosaic,
+			Theme: Theme{Current: &Attrs{Bold: true, Reverse: true}, Background: background},
+		}
+		u := pool.Update{
+			State:    pool.StateReady,
+			Lines:    wrappingLines(6),
+			Index:    2,
+			Playing:  true,
+			Position: 19,
+			Duration: 200,
+			Track:    mpris.TrackMetadata{Title: "Song", Artist: "Band", ArtURL: "file:///cover.png"},
+		}
+		m := newTestModel(60, 16, opts, u)
+		m.artURL = u.Track.ArtURL
+		m.setArt(artMsg{url: u.Track.ArtURL, img: cover})
+		view := m.View()
+		if got, want := paintsBackground(view), background == BackgroundColor; got != want {
+			t.Errorf("background %s: paints a background %v, want %v", background, got, want)
+		}
+	}
+}
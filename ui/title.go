@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// maxTitleWidth caps the terminal title, in cells.
+const maxTitleWidth = 80
+
+// titleText returns the terminal title for the current state: the artist
+// and the playing line, or the track title before the first line.
+func (m *Model) titleText() string {
+	track := m.state.Track
+	if track.Title == "" && track.Artist == "" {
+		return ""
+	}
+	text := track.Title
+	if m.state.Index >= 0 && m.state.Index < len(m.state.Lines) {
+		text = m.state.Lines[m.state.Index].Text
+	}
+	if track.Artist != "" {
+		text = track.Artist + " – " + text
+	}
+	return truncate(stripControl(text), maxTitleWidth)
+}
+
+// updateTitle sets the terminal title if it changed. It goes to the
+// terminal the UI draws on, as pushTitle and popTitle do around it, and
+// never lands inside a frame; see termOutput.
+func (m *Model) updateTitle() {
+	title := m.titleText()
+	if !m.opts.SetTitle || m.out == nil || title == "" || title == m.title {
+		return
+	}
+	m.title = title
+	writeTitleSeq(m.out, "\x1b]2;"+title+"\x07")
+}
+
+// pushTitle saves the terminal title so popTitle can restore it on exit.
+func pushTitle(w io.Writer) {
+	writeTitleSeq(w, "\x1b[22;2t")
+}
+
+// popTitle restores the title saved by pushTitle.
+func popTitle(w io.Writer) {
+	writeTitleSeq(w, "\x1b[23;2t")
+}
+
+// writeTitleSeq writes a title escape sequence, wrapped in tmux's
+// pass-through so it reaches the outer terminal inside tmux sessions.
+func writeTitleSeq(w io.Writer, seq string) {
+	if os.Getenv("TMUX") != "" {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	io.WriteString(w, seq)
+}
+
+// stripControl removes control characters, which could end the title
+// sequence early or inject escapes of their own.
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,41 @@
+package ui
+
+import "github.com/best8oy/LyricsMPRIS/lyrics"
+
+// Translation display modes.
+const (
+	TranslationOff     = "off"     // original text only
+	TranslationOnly    = "only"    // translation only, falling back to the original
+	TranslationStacked = "stacked" // original with the translation beneath
+)
+
+// translationModes is the cycle order of the translation toggle key.
+var translationModes = []string{TranslationStacked, TranslationOnly, TranslationOff}
+
+// lineTexts returns the texts to show for line in the given translation
+// mode, in display order. Lines without a translation show their original.
+func lineTexts(line lyrics.LyricLine, mode string) (original, translation string) {
+	switch {
+	case line.Translation == "" || mode == TranslationOff:
+		return line.Text, ""
+	case mode == TranslationOnly:
+		return line.Translation, ""
+	}
+	return line.Text, line.Translation
+}
+
+// toggleTranslation switches to the next translation mode.
+func (m *Model) toggleTranslation() {
+	current := m.translation
+	if current == "" {
+		current = TranslationStacked
+	}
+	next := translationModes[0]
+	for i, mode := range translationModes {
+		if mode == current {
+			next = translationModes[(i+1)%len(translationModes)]
+		}
+	}
+	m.translation = next
+	m.setNotice("translation: " + next)
+}
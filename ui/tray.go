@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/pool"
+	"github.com/best8oy/LyricsMPRIS/tray"
+)
+
+// trayCallTimeout bounds a player call made from the tray menu.
+const trayCallTimeout = 2 * time.Second
+
+// trayIcon shows the track and current line in the tooltip of a tray
+// icon. Clicking it toggles playback; its menu skips tracks and copies
+// the line.
+type trayIcon struct {
+	icon    *tray.Tray
+	verbose bool
+
+	mu   sync.Mutex
+	line string // the current line, for "Copy line"
+}
+
+// newTrayIcon returns nil without an error when the desktop has no tray
+// (no StatusNotifierWatcher), so -tray can be left on everywhere.
+func newTrayIcon(opts Options) (*trayIcon, error) {
+	t := &trayIcon{verbose: opts.Verbose}
+	icon, err := tray.Start(tray.Actions{
+		PlayPause: t.call("play/pause", opts.Player.PlayPause),
+		Next:      t.call("next", opts.Player.Next),
+		Previous:  t.call("previous", opts.Player.Previous),
+		CopyLine:  t.copyLine,
+	})
+	if errors.Is(err, tray.ErrNoWatcher) {
+		if opts.Verbose {
+			log.Printf("tray: %v, no icon shown", err)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.icon = icon
+	return t, nil
+}
+
+// call returns an action running a player method, logging its failure
+// when verbose.
+func (t *trayIcon) call(what string, method func(context.Context) error) func() {
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), trayCallTimeout)
+		defer cancel()
+		if err := method(ctx); err != nil && t.verbose {
+			log.Printf("tray: %s: %v", what, err)
+		}
+	}
+}
+
+func (t *trayIcon) copyLine() {
+	t.mu.Lock()
+	line := t.line
+	t.mu.Unlock()
+	if line == "" {
+		return
+	}
+	if err := copyCommand(capBytes(line, maxClipboardBytes)); err != nil && t.verbose {
+		log.Printf("tray: copy line: %v", err)
+	}
+}
+
+func (t *trayIcon) update(u pool.Update) {
+	title := "lyricsmpris"
+	if u.Track.Title != "" {
+		title = u.Track.Artist + " – " + u.Track.Title
+	}
+	line := ""
+	if u.Err == nil && !u.Loading && u.Index >= 0 && u.Index < len(u.Lines) {
+		line = u.Lines[u.Index].Text
+	}
+	t.mu.Lock()
+	t.line = line
+	t.mu.Unlock()
+	t.icon.SetToolTip(title, line)
+}
+
+// close removes the icon from the tray.
+func (t *trayIcon) close() {
+	t.icon.Close()
+}
@@ -4,124 +4,761 @@ package ui
 import (
 	"context"
 	"fmt"
+	"log"
+	"math"
 	"os"
 	"runtime"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/best8oy/LyricsMPRIS/lyrics"
 	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/mqtt"
+	"github.com/best8oy/LyricsMPRIS/output"
 	"github.com/best8oy/LyricsMPRIS/pool"
 	tea "github.com/charmbracelet/bubbletea"
 	gloss "github.com/charmbracelet/lipgloss"
 	"golang.org/x/term"
 )
 
-// DisplayLyricsContext handles lyric fetching and UI display for a given track and position.
-func DisplayLyricsContext(ctx context.Context, mode string, meta mpris.TrackMetadata, pos float64, pollInterval time.Duration) {
-	if mode == "pipe" {
-		PipeModeContext(ctx, pollInterval)
-	} else {
-		TerminalLyricsContext(ctx, pollInterval)
+// Vertical alignments of the current line within the lyric window.
+const (
+	VAlignTop    = "top"
+	VAlignCenter = "center"
+	VAlignBottom = "bottom"
+)
+
+// Layouts of the modern UI.
+const (
+	LayoutWindow  = "window"   // a window of lines around the current one
+	LayoutMinimal = "minimal"  // just the current line, and the next if it fits
+	LayoutTwoLine = "two-line" // the current line over the next, one row each
+)
+
+// Ways of fitting lyric lines wider than the terminal.
+const (
+	OverflowWrap     = "wrap"     // wrap onto more rows
+	OverflowEllipsis = "ellipsis" // cut short with an ellipsis
+	OverflowMarquee  = "marquee"  // ellipsis, but the current line scrolls
+)
+
+// Pipe mode output formats.
+const (
+	OutputText    = "text"    // the lyric lines as plain text
+	OutputJSON    = "json"    // one JSON event object per line; see output.Event
+	OutputWaybar  = "waybar"  // a Waybar custom module object per change; see output.Waybar
+	OutputPolybar = "polybar" // a plain line per change, for polybar's tail scripts
+	// OutputI3Blocks writes an i3blocks JSON block per change, and takes
+	// click events on stdin to control the player.
+	OutputI3Blocks = "i3blocks"
+	// OutputLemonbar and OutputDzen2 write a line per change in the
+	// inline color markup of lemonbar and dzen2; see output.BarLine.
+	OutputLemonbar = output.BarLemonbar
+	OutputDzen2    = output.BarDzen2
+	// OutputConky prints a few lines around the current one in conky
+	// color variables once, for ${execpi}; see ConkyContext.
+	OutputConky = "conky"
+)
+
+// Options configures the modern terminal UI.
+type Options struct {
+	// NoProgress hides the track progress bar.
+	NoProgress bool
+	// Mouse enables mouse wheel scrolling. It is opt-in because mouse
+	// reporting interferes with text selection in most terminals.
+	Mouse bool
+	// NoAnimation disables the scroll animation between lines.
+	NoAnimation bool
+	// NoHeader hides the "Artist — Title" header row.
+	NoHeader bool
+	// PausedText is the marker shown over the dimmed lyrics while paused.
+	PausedText string
+	// Theme configures the styling of lyric lines.
+	Theme Theme
+	// VAlign places the current line vertically: VAlignTop, VAlignCenter or VAlignBottom.
+	VAlign string
+	// Countdown is the style of the countdown shown during intros and long
+	// instrumental gaps: CountdownSeconds, CountdownDots or CountdownOff.
+	Countdown string
+	// CountdownThreshold is the gap length, in seconds, that counts as an
+	// instrumental break. Zero means DefaultCountdownThreshold.
+	CountdownThreshold float64
+	// NoOSC52 copies through wl-copy or xclip instead of OSC 52 escape
+	// sequences, for terminals that ignore them.
+	NoOSC52 bool
+	// Translation shows line translations, where the lyrics have them:
+	// TranslationOff, TranslationOnly or TranslationStacked (the default).
+	// In pipe mode it chooses the printed text; stacked prints both.
+	Translation string
+	// Status shows a row with the lyric source, sync quality and offset.
+	Status bool
+	// SetTitle shows the artist and the current line in the terminal title.
+	SetTitle bool
+	// Layout is LayoutWindow (the default), LayoutMinimal or LayoutTwoLine.
+	Layout string
+	// NoTime hides the elapsed and total time beside the progress bar.
+	NoTime bool
+	// Overflow fits lines wider than the terminal: OverflowWrap (the
+	// default), OverflowEllipsis or OverflowMarquee.
+	Overflow string
+	// MarqueeSpeed is the marquee scroll speed in cells per second. Zero
+	// means DefaultMarqueeSpeed.
+	MarqueeSpeed float64
+	// Art shows the cover art beside or above the lyrics: ArtOff (the
+	// default), ArtAuto, ArtKitty or ArtMosaic.
+	Art string
+	// ArtSize is the cover art width in cells. Zero means DefaultArtSize.
+	ArtSize int
+	// Timestamps shows each line's LRC timestamp beside it.
+	Timestamps bool
+	// SaveDir is where the save key writes lyrics. Empty means
+	// $XDG_DATA_HOME/lyricsmpris/saved.
+	SaveDir string
+	// IdleTimeout is how long playback stays paused before the display
+	// dims to a single line. Zero never dims.
+	IdleTimeout time.Duration
+	// BlankOnPause blanks the dimmed display entirely.
+	BlankOnPause bool
+	// Subtitles makes the s key and OnceContext write SubtitlesSRT or
+	// SubtitlesVTT subtitles, a cue per line, rather than lyrics.
+	Subtitles string
+	// SubtitlesBOM starts SubtitlesSRT files with a UTF-8 byte order
+	// mark, for players that need one.
+	SubtitlesBOM bool
+	// Output is the pipe mode format: OutputText (the default), OutputJSON,
+	// OutputWaybar, OutputPolybar, OutputI3Blocks, OutputLemonbar,
+	// OutputDzen2 or OutputConky.
+	Output string
+	// Bar styles the lines of OutputLemonbar and OutputDzen2.
+	Bar output.BarStyle
+	// ConkyContext is how many lines OutputConky shows before and after
+	// the current one.
+	ConkyContext int
+	// ConkyCurrent and ConkyOther name the conky color variables of the
+	// current line and the others; empty means DefaultConkyCurrent and
+	// DefaultConkyOther.
+	ConkyCurrent, ConkyOther string
+	// MaxWidth cuts the lines pipe mode writes to this many cells, after
+	// any Format; for OutputI3Blocks it makes the short text. OutputJSON
+	// is left whole. Zero or less means no limit.
+	MaxWidth int
+	// Ellipsis ends lines cut to MaxWidth.
+	Ellipsis string
+	// TrackMarker prints an "== Artist – Title ==" line at each track
+	// change in text pipe mode.
+	TrackMarker bool
+	// PauseText, when set, replaces the current line in pipe mode and the
+	// structured outputs while playback is paused; see ParseOnPause. Nil
+	// keeps showing the line.
+	PauseText *string
+	// Overwrite rewrites a single terminal line in pipe mode, with a
+	// carriage return and clear-to-end, instead of printing a line per
+	// lyric. It falls back to printing lines when stdout is no terminal.
+	Overwrite bool
+	// Padding goes around the line, inside Prefix and Suffix, in
+	// OutputPolybar.
+	Padding string
+	// Prefix and Suffix go before and after the line in OutputPolybar,
+	// e.g. for icons.
+	Prefix, Suffix string
+	// OutputFile, when set, is kept holding the current line beside the
+	// display, e.g. for an OBS text source. It is empty while paused or
+	// without lyrics.
+	OutputFile string
+	// OutputFileFormat renders the OutputFile content; see PipeLine for
+	// its fields. Nil writes the line alone.
+	OutputFileFormat *template.Template
+	// StateFile, when set, is kept holding the output.State of the latest
+	// update, written atomically and at most every stateFileInterval. It
+	// is removed on exit.
+	StateFile string
+	state     *stateFile
+	// Rewrite, when set, writes OutputFile and StateFile again on each
+	// signal, with their content even if unchanged, e.g. for SIGHUP after
+	// something removed them.
+	Rewrite <-chan os.Signal
+	// FIFO, when set, is a named pipe, created if missing, that gets the
+	// pipe mode stream beside the display, in the format Output names.
+	// Lines are dropped while no reader is attached.
+	FIFO string
+	fifo *fifoWriter
+	// ListenUnix, when set, is the path of a Unix socket streaming the
+	// JSON events to any number of clients, each starting with an
+	// output.Snapshot.
+	ListenUnix string
+	// ListenHTTP, when set, is the address of an HTTP server for browser
+	// overlays: the JSON events as Server-Sent Events on /events, the
+	// current state on /current and an overlay page on /. An address
+	// without a host, e.g. ":8277", listens on localhost only.
+	ListenHTTP string
+	// HTTPToken, when set, must be the token query parameter of
+	// ListenHTTP's WebSocket, and lets it control the player; without
+	// one the WebSocket only streams the events, to local pages.
+	HTTPToken string
+	hub       *hub
+	offsets   chan float64 // lyric offset changes for the pool
+	// ProgressRate is how many EventProgress events a second the JSON
+	// output and the socket and HTTP streams send while a line plays; 0
+	// sends none. The plain outputs never get them.
+	ProgressRate float64
+	// DBusExport exports the current lyrics on the session bus as the
+	// lyricsbus service.
+	DBusExport bool
+	bus        *busExporter
+	// Tray shows a tray icon with the current line as its tooltip, on
+	// desktops with a StatusNotifierItem tray.
+	Tray bool
+	tray *trayIcon
+	// DiscordPresence, when set, is the Discord application ID the track
+	// and current line are shown as, as the Rich Presence of the local
+	// Discord client.
+	DiscordPresence string
+	discord         *discordPresence
+	// MQTT, when set, is the broker the track and lines are published to.
+	MQTT *mqtt.Config
+	mqtt *mqttPublisher
+	// NotifyOn is what notify mode sends notifications for: NotifyLine
+	// (the default) or NotifyTrack.
+	NotifyOn string
+	// NotifyUrgency is the urgency of notifications: notify.UrgencyLow,
+	// notify.UrgencyNormal (the default) or notify.UrgencyCritical.
+	NotifyUrgency string
+	// NotifyTimeout is how long notifications stay up. Zero leaves it to
+	// the notification server.
+	NotifyTimeout time.Duration
+	// Lead selects lines this far ahead of the playback position, to make
+	// up for audio latency (e.g. Bluetooth); negative values delay them.
+	// Unlike the lyric offset it applies to every mode.
+	Lead time.Duration
+	// FetchDebounce is how long a new track must stay current before its
+	// lyrics are fetched; see pool.Config.
+	FetchDebounce time.Duration
+	// Player is the player the pool follows, and Lyrics where it fetches
+	// the lyrics from; main passes mpris.NewClient and lyrics.NewFetcher.
+	Player Player
+	Lyrics pool.LyricProvider
+	// EmitEarly sends lines to pipe mode and the outputs beside the
+	// display this much before they are due, e.g. for a display slow to
+	// render, but never before the line they follow; see emitEarly. It
+	// adds to Lead, and the terminal UI, a11y and notify modes stay exact.
+	EmitEarly time.Duration
+	// Verbose logs diagnostics, e.g. ignored input, to stderr.
+	Verbose bool
+	// Format renders each line printed in pipe mode; see PipeLine for its
+	// fields. Nil prints the line and its translation as they are.
+	Format *template.Template
+	// Pulse briefly brightens each new current line. It needs animations
+	// and a 256-color or truecolor terminal, and is off otherwise.
+	Pulse bool
+}
+
+// DisplayLyricsContext handles lyric fetching and UI display, following whichever track the player is on.
+// It returns the terminal UI's error, if any; pipe, a11y and notify modes run until ctx is done
+// or, for pipe mode, stdout is closed. Once mode prints the lyric sheet and returns.
+func DisplayLyricsContext(ctx context.Context, mode string, pollInterval time.Duration, opts Options) error {
+	if mode != "once" && mode != "query" && mode != "conky" {
+		stop, err := startOutputs(&opts)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+	switch mode {
+	case "pipe":
+		return PipeModeContext(ctx, pollInterval, opts)
+	case "a11y":
+		A11yModeContext(ctx, pollInterval, opts)
+		return nil
+	case "notify":
+		NotifyModeContext(ctx, pollInterval, opts)
+		return nil
+	case "once":
+		return OnceContext(ctx, opts)
+	case "query":
+		return Query(opts)
+	case "conky":
+		return ConkyContext(ctx, opts)
 	}
+	_, err := TerminalLyricsContext(ctx, pollInterval, opts)
+	return err
 }
 
-// PipeModeContext prints lyrics line-by-line to stdout for pipe mode.
-func PipeModeContext(ctx context.Context, pollInterval time.Duration) {
-	ch := make(chan pool.Update)
-	go pool.Listen(ctx, ch, pollInterval)
-	lastLineIdx := -1
-	printed := make(map[int]bool)
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case upd := <-ch:
-			if upd.Err != nil || len(upd.Lines) == 0 {
-				continue
-			}
-			if upd.Index != lastLineIdx && !printed[upd.Index] {
-				fmt.Println(upd.Lines[upd.Index].Text)
-				lastLineIdx = upd.Index
-				printed[upd.Index] = true
-			}
+// startOutputs opens the FIFO and state file and starts the servers opts asks for,
+// setting them in opts for listen to feed. stop closes them all.
+func startOutputs(opts *Options) (stop func(), err error) {
+	// the keys and WebSocket clients shift the lyric offset through it
+	opts.offsets = make(chan float64, 8)
+	var stops []func()
+	closeAll := func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+	defer func() {
+		if err != nil {
+			closeAll()
+		}
+	}()
+	if opts.FIFO != "" {
+		f, err := openFIFO(opts.FIFO, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("fifo: %w", err)
+		}
+		stops = append(stops, f.close)
+		opts.fifo = f
+	}
+	if opts.StateFile != "" {
+		f := newStateFile(opts.StateFile)
+		stops = append(stops, f.close)
+		opts.state = f
+	}
+	if opts.DBusExport {
+		b, err := newBusExporter(*opts)
+		if err != nil {
+			return nil, fmt.Errorf("dbus-export: %w", err)
+		}
+		stops = append(stops, b.close)
+		opts.bus = b
+	}
+	if opts.Tray {
+		t, err := newTrayIcon(*opts)
+		if err != nil {
+			return nil, fmt.Errorf("tray: %w", err)
+		}
+		if t != nil {
+			stops = append(stops, t.close)
+			opts.tray = t
+		}
+	}
+	if opts.DiscordPresence != "" {
+		d := newDiscordPresence(opts.DiscordPresence, *opts)
+		stops = append(stops, d.close)
+		opts.discord = d
+	}
+	if opts.MQTT != nil {
+		p := newMQTTPublisher(*opts.MQTT, *opts)
+		stops = append(stops, p.close)
+		opts.mqtt = p
+	}
+	if opts.ListenUnix == "" && opts.ListenHTTP == "" {
+		return closeAll, nil
+	}
+	h := newHub(*opts)
+	if opts.ListenUnix != "" {
+		s, err := listenUnix(opts.ListenUnix, h, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("listen-unix: %w", err)
 		}
+		stops = append(stops, s.close)
 	}
+	if opts.ListenHTTP != "" {
+		s, err := listenHTTP(opts.ListenHTTP, h, *opts)
+		if err != nil {
+			return nil, fmt.Errorf("listen-http: %w", err)
+		}
+		stops = append(stops, s.close)
+	}
+	// closed first, ending the streams the servers wait for
+	stops = append(stops, h.close)
+	opts.hub = h
+	return closeAll, nil
 }
 
 // Model is the terminal UI model for displaying lyrics.
 type Model struct {
+	ctx          context.Context
 	ch           chan pool.Update
+	opts         Options
 	state        pool.Update
+	received     time.Time           // when state was received, for position interpolation
+	offset       float64             // lyric offset in seconds, added to the playback position
+	offsetUntil  time.Time           // the offset label is shown until this time
+	notice       string              // transient message shown at the bottom of the lyrics
+	noticeUntil  time.Time           // when the notice disappears
+	scrolled     bool                // the window is detached from the playing line
+	scrollIndex  int                 // the line centered while scrolled
+	scrollUntil  time.Time           // when a scrolled window snaps back to the playing line
+	rowLines     []int               // lyric line index of each rendered lyric row, -1 for padding
+	lyricsTop    int                 // screen row of the first lyric row
+	lyricsHeight int                 // number of lyric rows in the last frame
+	lyricsWidth  int                 // width of the lyric area in the last frame
+	out          *termOutput         // the terminal drawn on, nil outside runProgram
+	artURL       string              // cover art being shown or loaded
+	artView      string              // rendered cover art, "" when none
+	spinning     bool                // a spinner frame is scheduled
+	retry        chan<- struct{}     // asks the pool to refetch the lyrics; may be nil
+	help         bool                // show the help overlay
+	timestamps   bool                // show each line's timestamp
+	pausedSince  time.Time           // when playback paused; zero while playing
+	spinnerFrame int                 // current spinner frame
+	animFrom     int                 // rows the window was offset by when the animation started
+	animStart    time.Time           // when the scroll animation started
+	pulse        bool                // pulse new current lines
+	pulseLine    int                 // line pulsing since pulseStart
+	pulseStart   time.Time           // when the pulse started
+	marqueeStart time.Time           // when the current line's marquee started
+	marqueeing   bool                // a marquee step is scheduled
+	viewport     bool                // show all lines in a scrollable viewport
+	vpTop        int                 // first row shown by the viewport
+	vpJump       bool                // bring the scrolled-to line into the viewport
+	searching    bool                // the search input is open
+	query        string              // search query; empty when not searching
+	translation  string              // translation mode, toggled at runtime
+	status       bool                // show the status row
+	title        string              // last terminal title set
+	caps         *mpris.Capabilities // player controls, nil until known
+	waiting      bool                // counting down to the next line in the last frame
+	intro        bool                // the countdown is for the first line
+	clickLine    int                 // line under the pointer at the last click
+	clickAt      time.Time           // time of the last click, for double-click detection
 	w, h         int
 	styleBefore  gloss.Style
 	styleCurrent gloss.Style
 	styleAfter   gloss.Style
+	styleHeader  gloss.Style
+	stylePaused  gloss.Style
 	hAlignment   gloss.Position
 }
 
-func newModel(ch chan pool.Update) *Model {
-	m := &Model{ch: ch}
-	m.styleBefore = gloss.NewStyle().Faint(true).Italic(true)
-	m.styleCurrent = gloss.NewStyle().Bold(true).Foreground(gloss.Color("2"))
-	m.styleAfter = gloss.NewStyle()
+// tickMsg triggers a redraw of time-dependent parts of the view.
+type tickMsg time.Time
+
+func tick(d time.Duration) tea.Cmd {
+	return tea.Tick(d, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+// maxTickInterval bounds the delay between redraws of the time-dependent view.
+const maxTickInterval = time.Second
+
+// nextTick schedules the next redraw for the next lyric line boundary, or
+// after maxTickInterval if that comes first.
+func (m *Model) nextTick() tea.Cmd {
+	d := maxTickInterval
+	if m.state.Playing && m.synced() {
+		pos := m.lyricPosition()
+		next := m.state.Index + 1
+		if pos < m.state.Lines[0].Time {
+			next = 0 // still in the intro
+		}
+		if next < len(m.state.Lines) {
+			until := time.Duration((m.state.Lines[next].Time - pos) * float64(time.Second))
+			if until >= 0 && until < d {
+				d = until + 10*time.Millisecond
+			}
+		}
+	}
+	return tick(d)
+}
+
+// noticeDuration is how long transient messages stay on screen.
+const noticeDuration = 5 * time.Second
+
+// doubleClickInterval is the maximum delay between the clicks of a double-click.
+const doubleClickInterval = 400 * time.Millisecond
+
+// noticeMsg shows a transient message.
+type noticeMsg string
+
+// offsetStep and offsetBigStep are the lyric offset changes per press of
+// -/+ and [/], in seconds.
+const (
+	offsetStep    = 0.1
+	offsetBigStep = 1.0
+)
+
+// offsetLabelDuration is how long the offset stays on screen after a change.
+const offsetLabelDuration = 2 * time.Second
+
+// scrollStep is the number of lines one mouse wheel notch scrolls; arrow keys scroll by one.
+const scrollStep = 3
+
+// scrollTimeout is how long a scrolled window stays detached from playback.
+// Unsynced lyrics have no playing line to return to and stay where scrolled.
+const scrollTimeout = 5 * time.Second
+
+func newModel(ctx context.Context, ch chan pool.Update, opts Options) *Model {
+	m := &Model{
+		ctx:         ctx,
+		ch:          ch,
+		opts:        opts,
+		translation: opts.Translation,
+		status:      opts.Status,
+		timestamps:  opts.Timestamps,
+		pulse:       pulseEnabled(opts),
+	}
+	if m.opts.CountdownThreshold <= 0 {
+		m.opts.CountdownThreshold = DefaultCountdownThreshold
+	}
+	m.setNotice(m.keyHint())
+	theme, bg := m.opts.Theme, m.background()
+	m.styleBefore = theme.Past.style(gloss.NewStyle(), gloss.NewStyle().Faint(true).Italic(true), bg)
+	current := gloss.NewStyle().Foreground(gloss.Color("2"))
+	m.styleCurrent = theme.Current.style(current, current.Bold(true), bg)
+	m.styleAfter = theme.Future.style(gloss.NewStyle(), gloss.NewStyle(), bg)
+	m.styleHeader = gloss.NewStyle().Faint(true)
+	m.stylePaused = gloss.NewStyle().Bold(true)
 	m.hAlignment = 0.5 // center
 	return m
 }
 
 func (m *Model) Init() tea.Cmd {
-	return tea.Batch(waitForUpdate(m.ch), tea.HideCursor)
+	return tea.Batch(waitForUpdate(m.ch), tea.HideCursor, tick(maxTickInterval))
+}
+
+// position returns the playback position interpolated from the last update.
+func (m *Model) position() float64 {
+	return m.state.PositionAfter(time.Since(m.received))
+}
+
+// synced reports whether the loaded lyrics carry timestamps.
+func (m *Model) synced() bool {
+	return lyrics.Timesynced(m.state.Lines)
+}
+
+// syncIndex re-derives the highlighted line from the interpolated position so
+// the highlight follows playback between pool updates. Unsynced lyrics keep
+// the pool's index.
+func (m *Model) syncIndex() {
+	if !m.synced() {
+		return
+	}
+	m.state.Index = pool.IndexAt(m.state.Lines, m.lyricPosition())
+}
+
+// lyricPosition returns the position in the lyrics: the playback position
+// shifted by the lyric offset and the lead.
+func (m *Model) lyricPosition() float64 {
+	return m.position() + m.offset + m.opts.Lead.Seconds()
+}
+
+// viewIndex returns the line the lyric window is centered on.
+func (m *Model) viewIndex() int {
+	if m.scrolled {
+		return m.scrollIndex
+	}
+	return m.state.Index
+}
+
+// scroll moves the window by delta lines, detaching it from the playing
+// line. The viewport scrolls by rows instead.
+func (m *Model) scroll(delta int) {
+	if len(m.state.Lines) == 0 {
+		return
+	}
+	if m.viewport {
+		m.scrollViewport(delta)
+		return
+	}
+	m.scrollIndex = m.viewIndex() + delta
+	if m.scrollIndex < 0 {
+		m.scrollIndex = 0
+	}
+	if m.scrollIndex >= len(m.state.Lines) {
+		m.scrollIndex = len(m.state.Lines) - 1
+	}
+	m.scrolled = true
+	m.scrollUntil = time.Now().Add(scrollTimeout)
+}
+
+// adjustOffset shifts the lyric offset by delta seconds. The pool applies
+// it, for the outputs as well, and its next update brings it back; a model
+// fed updates from elsewhere applies it itself. Either way the highlighted
+// line is re-synced and the new offset briefly shown.
+func (m *Model) adjustOffset(delta float64) {
+	if m.opts.offsets != nil {
+		select {
+		case m.opts.offsets <- delta:
+		default:
+			m.setNotice("too many offset changes queued")
+		}
+		return
+	}
+	// round to the step size so repeated presses don't accumulate float error
+	m.setOffset(math.Round((m.offset+delta)/offsetStep) * offsetStep)
+}
+
+// setOffset makes offset the lyric offset, re-syncing the highlighted
+// line and briefly showing it.
+func (m *Model) setOffset(offset float64) {
+	m.offset = offset
+	m.offsetUntil = time.Now().Add(offsetLabelDuration)
+	m.syncIndex()
+}
+
+// setNotice shows text at the bottom of the lyrics for a few seconds.
+func (m *Model) setNotice(text string) {
+	m.notice = text
+	m.noticeUntil = time.Now().Add(noticeDuration)
+}
+
+// lineAt returns the lyric line rendered at screen row y, or -1.
+func (m *Model) lineAt(y int) int {
+	y -= m.lyricsTop
+	if y < 0 || y >= len(m.rowLines) {
+		return -1
+	}
+	return m.rowLines[y]
+}
+
+// seekTo seeks the player to the start of the given line, compensating for
+// the lyric offset and lead as the pool adds them, and resumes following
+// playback.
+func (m *Model) seekTo(index int) tea.Cmd {
+	if index < 0 || index >= len(m.state.Lines) || !m.synced() {
+		return nil
+	}
+	m.follow()
+	pos := max(m.state.Lines[index].Time-m.offset-m.opts.Lead.Seconds(), 0)
+	return m.playerCmd(func(player Player, ctx context.Context) error {
+		return player.SetPosition(ctx, pos)
+	})
+}
+
+// follow snaps the window back to the playing line.
+func (m *Model) follow() {
+	m.scrolled = false
+}
+
+// playerCmd runs a control of opts.Player off the UI goroutine, reporting
+// failures as a notice. Any resulting track change arrives through the
+// pool.
+func (m *Model) playerCmd(action func(Player, context.Context) error) tea.Cmd {
+	ctx, player := m.ctx, m.opts.Player
+	return func() tea.Msg {
+		if err := action(player, ctx); err != nil {
+			return noticeMsg(err.Error())
+		}
+		return nil
+	}
+}
+
+// seekBy seeks the player by delta seconds.
+func (m *Model) seekBy(delta float64) tea.Cmd {
+	return m.playerCmd(func(player Player, ctx context.Context) error {
+		return player.Seek(ctx, delta)
+	})
 }
 
 func (m *Model) Update(message tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
+	prevCenter, prevTrack, prevIndex := m.viewIndex(), m.state.Track, m.state.Index
 
 	switch msg := message.(type) {
 	case tea.WindowSizeMsg:
-		m.w, m.h = msg.Width, msg.Height
+		if msg.Width != m.w || msg.Height != m.h {
+			m.w, m.h = msg.Width, msg.Height
+			// the layout is recomputed from the new size on every View, but
+			// rows wrapped for the old width may be left behind on screen, so
+			// repaint from scratch. The renderer draws at most once per
+			// frame, which coalesces resize storms.
+			m.animFrom = 0
+			cmd = tea.ClearScreen
+		}
+
+	case tickMsg:
+		m.syncIndex()
+		if m.scrolled && time.Now().After(m.scrollUntil) && m.synced() {
+			m.follow()
+		}
+		cmd = m.nextTick()
+
+	case tea.MouseMsg:
+		if msg.Action != tea.MouseActionPress {
+			break
+		}
+		if m.idle() {
+			m.wake()
+			break
+		}
+		switch msg.Button {
+		case tea.MouseButtonWheelUp:
+			m.scroll(-scrollStep)
+		case tea.MouseButtonWheelDown:
+			m.scroll(scrollStep)
+		case tea.MouseButtonLeft:
+			if time.Since(m.clickAt) < doubleClickInterval {
+				cmd = m.seekTo(m.clickLine)
+				m.clickAt = time.Time{}
+				break
+			}
+			m.clickLine, m.clickAt = m.lineAt(msg.Y), time.Now()
+			m.follow()
+		default:
+			m.follow()
+		}
+
+	case noticeMsg:
+		m.setNotice(string(msg))
+
+	case artMsg:
+		m.setArt(msg)
+
+	case spinnerMsg:
+		cmd = m.stepSpinner()
+
+	case capsMsg:
+		hint := m.keyHint()
+		caps := mpris.Capabilities(msg)
+		m.caps = &caps
+		if m.notice == hint {
+			m.notice = m.keyHint()
+		}
+
+	case marqueeMsg:
+		m.marqueeing = false
+
+	case frameMsg:
+		if m.animating() || m.pulsing(m.pulseLine) {
+			cmd = frame()
+		}
 
 	case pool.Update:
+		m.trackPause(msg.Playing, msg.Track != m.state.Track)
+		if msg.Track != m.state.Track {
+			m.clearSearch()
+			cmd = tea.Batch(m.capabilitiesCmd(), m.artCmd(msg.Track.ArtURL))
+		}
 		m.state = msg
+		m.received = time.Now()
+		if m.opts.offsets != nil && msg.Offset != m.offset {
+			m.setOffset(msg.Offset)
+		}
+		m.syncIndex()
 		if runtime.GOOS == "windows" {
 			w, h, err := term.GetSize(int(os.Stdout.Fd()))
 			if err == nil {
 				m.w, m.h = w, h
 			}
 		}
-		cmd = waitForUpdate(m.ch)
+		if m.state.Loading {
+			cmd = tea.Batch(cmd, m.spin())
+		}
+		cmd = tea.Batch(cmd, waitForUpdate(m.ch))
 
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "q", "esc", "ctrl+c":
-			cmd = tea.Quit
-		case "left":
-			m.hAlignment -= 0.5
-			if m.hAlignment < 0 {
-				m.hAlignment = 0
-			}
-		case "right":
-			m.hAlignment += 0.5
-			if m.hAlignment > 1 {
-				m.hAlignment = 1
-			}
-		case "up":
-			if m.state.Playing && lyrics.Timesynced(m.state.Lines) {
-				break
-			}
-			m.state.Index -= 1
-			if m.state.Index < 0 {
-				m.state.Index = 0
-			}
-		case "down":
-			if m.state.Playing && lyrics.Timesynced(m.state.Lines) {
-				break
-			}
-			m.state.Index += 1
-			if m.state.Index >= len(m.state.Lines) {
-				m.state.Index = len(m.state.Lines) - 1
-			}
+		if m.idle() {
+			m.wake() // the key only wakes the display
+			break
 		}
+		cmd = m.handleKey(msg)
+	}
+
+	if m.state.Track != prevTrack {
+		m.animFrom = 0
+	} else if center := m.viewIndex(); center != prevCenter {
+		cmd = tea.Batch(cmd, m.animate(prevCenter, center))
 	}
+	if m.state.Track == prevTrack && m.state.Index != prevIndex {
+		cmd = tea.Batch(cmd, m.startPulse(m.state.Index))
+	}
+	if m.state.Track != prevTrack || m.state.Index != prevIndex {
+		m.restartMarquee()
+	}
+	cmd = tea.Batch(cmd, m.stepMarquee())
+	m.updateTitle()
 	return m, cmd
 }
 
@@ -129,103 +766,423 @@ func (m *Model) View() string {
 	if m.w < 1 || m.h < 1 {
 		return ""
 	}
-	if m.state.Err != nil {
-		return gloss.PlaceVertical(
-			m.h, gloss.Center,
-			m.styleCurrent.
-				Align(gloss.Center).
+	if m.idle() {
+		return m.idleView()
+	}
+	if m.help {
+		return m.overlayHelp(m.render())
+	}
+	return m.render()
+}
+
+// render draws the screen for the current layout.
+func (m *Model) render() string {
+	switch m.opts.Layout {
+	case LayoutMinimal:
+		return m.minimalView()
+	case LayoutTwoLine:
+		return m.twoLineView()
+	}
+	var header, progress string
+	var label string
+	if time.Now().Before(m.offsetUntil) {
+		label = " " + truncate(fmt.Sprintf("offset %+.1fs", m.offset), m.w-1)
+	}
+	labelWidth := gloss.Width(label)
+	if !m.opts.NoHeader && m.h > 2 {
+		header = m.renderHeader(m.w-labelWidth, m.state.Track)
+	}
+	if !m.opts.NoProgress && m.h > 1 && m.state.Track != (mpris.TrackMetadata{}) {
+		progress = m.renderProgress(m.w, m.position(), m.state.Duration, m.state.Estimated)
+	}
+	var status string
+	if m.status && m.h > 3 {
+		status = m.renderStatus(m.w)
+	}
+
+	var rows []string
+	height := m.h
+	m.lyricsTop = 0
+	if header != "" {
+		rows = append(rows, header)
+		height--
+		m.lyricsTop = 1
+	}
+	if progress != "" {
+		height--
+	}
+	if status != "" {
+		height--
+	}
+	lyricRows := m.lyricsArea(height)
+	if search := m.searchLine(); search != "" && height > 1 {
+		// the search bar replaces the last lyric row
+		if i := strings.LastIndex(lyricRows, "\n"); i >= 0 {
+			lyricRows = lyricRows[:i+1] + m.styleCurrent.
 				Width(m.w).
-				Render(m.state.Err.Error()),
-		)
+				Render(truncate(search, m.w))
+		}
+	} else if time.Now().Before(m.noticeUntil) && height > 1 {
+		// overlay the notice on the last lyric row
+		if i := strings.LastIndex(lyricRows, "\n"); i >= 0 {
+			lyricRows = lyricRows[:i+1] + m.styleHeader.
+				Width(m.w).
+				Align(gloss.Center).
+				Render(truncate(m.notice, m.w))
+		}
 	}
-	if len(m.state.Lines) == 0 {
-		return ""
+	rows = append(rows, lyricRows)
+	if progress != "" {
+		rows = append(rows, progress)
+	}
+	if status != "" {
+		rows = append(rows, status)
 	}
+	if label != "" {
+		// show the offset in the top-right corner, beside the header if any
+		view := strings.Split(gloss.JoinVertical(gloss.Left, rows...), "\n")
+		left := header
+		if left == "" {
+			left = strings.Repeat(" ", m.w-labelWidth)
+		}
+		view[0] = left + m.styleHeader.Render(label)
+		return strings.Join(view, "\n")
+	}
+	return gloss.JoinVertical(gloss.Left, rows...)
+}
 
-	curLine := m.styleCurrent.
-		Width(m.w).
-		Align(m.hAlignment).
-		Render(m.state.Lines[m.state.Index].Text)
-	curLines := strings.Split(curLine, "\n")
-
-	curLen := len(curLines)
-	beforeLen := (m.h - curLen) / 2
-	afterLen := m.h - beforeLen - curLen
-
-	lines := make([]string, beforeLen+curLen+afterLen)
-
-	// fill lines before current
-	var filledBefore int
-	var beforeIndex = m.state.Index - 1
-	for filledBefore < beforeLen {
-		index := beforeLen - filledBefore - 1
-		if index < 0 || beforeIndex < 0 {
-			filledBefore += 1
-			continue
-		}
-		line := m.styleBefore.
-			Width(m.w).
+// lyricsView renders the lyric window into width columns and height rows.
+func (m *Model) lyricsView(width, height int) string {
+	m.rowLines = m.rowLines[:0]
+	m.lyricsWidth, m.lyricsHeight = width, height
+	switch m.state.State {
+	case pool.StateIdle, pool.StateEnded, pool.StateStopped:
+		return gloss.PlaceVertical(height, gloss.Center, "")
+	case pool.StateWaitingForPlayer:
+		return m.messageView(height, m.styleHeader, "Waiting for a player…")
+	case pool.StateFetching:
+		return m.messageView(height, m.styleHeader, truncate(m.loadingText(), width))
+	case pool.StateNotFound:
+		return m.messageView(height, m.styleHeader, "No lyrics found")
+	case pool.StateError:
+		return m.errorView(height)
+	}
+	if m.viewport {
+		m.waiting = false
+		return m.viewportView(height)
+	}
+
+	// the centered block is the playing (or scrolled-to) line, or the
+	// countdown when waiting for the next line
+	center := m.viewIndex()
+	above, below := center-1, center+1
+	var curLines []string
+	curOwner := center
+	remaining, span, intro, waiting := m.countdown()
+	m.waiting, m.intro = waiting, intro
+	if (waiting || center < 0) && !m.scrolled {
+		// between lines: the countdown, or a blank row before the first line
+		above, below = int(math.Floor(m.cursor())), int(math.Ceil(m.cursor()))
+		text := ""
+		if waiting {
+			text = m.countdownText(remaining, span)
+		}
+		curLines = []string{m.styleHeader.
+			Width(m.lyricsWidth).
 			Align(m.hAlignment).
-			Render(m.state.Lines[beforeIndex].Text)
-		beforeIndex -= 1
-		beforeLines := strings.Split(line, "\n")
-		for i := len(beforeLines) - 1; i >= 0; i-- {
-			lineIndex := index - i
-			if lineIndex >= 0 {
-				lines[lineIndex] = beforeLines[len(beforeLines)-1-i]
-			}
-			filledBefore += 1
+			Render(text)}
+		curOwner = -1
+	} else {
+		curLines = m.renderLine(center)
+	}
+
+	// top is the window's first row relative to the centered block's first
+	// row; the scroll animation shifts it while gliding to a new line.
+	top := m.blockTop(height, len(curLines)) - m.animationOffset()
+
+	// collect enough rows above and below the centered block to fill the window
+	var rows []string
+	var owners []int
+	for index := above; index >= 0 && len(rows) < -top; index-- {
+		lineRows := m.renderLine(index)
+		rows = append(lineRows, rows...)
+		owners = append(repeatIndex(index, len(lineRows)), owners...)
+	}
+	first := len(rows) + top
+	rows = append(rows, curLines...)
+	owners = append(owners, repeatIndex(curOwner, len(curLines))...)
+	for index := below; index < len(m.state.Lines) && len(rows) < first+height; index++ {
+		lineRows := m.renderLine(index)
+		rows = append(rows, lineRows...)
+		owners = append(owners, repeatIndex(index, len(lineRows))...)
+	}
+
+	lines := make([]string, height)
+	m.rowLines = make([]int, height)
+	for i := range lines {
+		m.rowLines[i] = -1
+		if row := first + i; row >= 0 && row < len(rows) {
+			lines[i] = rows[row]
+			m.rowLines[i] = owners[row]
 		}
 	}
 
-	// fill current lines
-	var curIndex = beforeLen
-	for i, line := range curLines {
-		index := curIndex + i
-		if index >= 0 && index < len(lines) {
-			lines[index] = line
+	// overlay the paused marker just above the current line
+	if !m.state.Playing && m.opts.PausedText != "" && height > len(curLines) {
+		index := -top - 1
+		if index < 0 || index >= height {
+			index = min(-top+len(curLines), height-1)
 		}
+		if index >= 0 {
+			lines[index] = m.stylePaused.
+				Width(m.lyricsWidth).
+				Align(gloss.Center).
+				Render(truncate(m.opts.PausedText, m.lyricsWidth))
+		}
+	}
+
+	return gloss.JoinVertical(m.hAlignment, lines...)
+}
+
+// cursor returns the playhead in line space: the playing line's index, or
+// halfway between two lines while counting down to the next one or before
+// the first.
+func (m *Model) cursor() float64 {
+	switch {
+	case m.waiting && m.intro, m.state.Index < 0:
+		return -0.5
+	case m.waiting:
+		return float64(m.state.Index) + 0.5
 	}
+	return float64(m.state.Index)
+}
 
-	// fill lines after current
-	var filledAfter int
-	var afterIndex = m.state.Index + 1
-	for filledAfter < afterLen {
-		index := beforeLen + curLen + filledAfter
-		if index >= len(lines) || afterIndex >= len(m.state.Lines) {
-			filledAfter += 1
-			continue
+// lineStyle returns the style of lyric line index, by its position
+// relative to the playhead.
+func (m *Model) lineStyle(index int) gloss.Style {
+	style := m.styleAfter
+	switch d := float64(index) - m.cursor(); {
+	case d < 0:
+		style = m.styleBefore
+		if c := fade(m.opts.Theme.PastFade, int(math.Ceil(-d))); c != nil {
+			style = style.Faint(false).Foreground(c)
+		}
+	case d == 0:
+		style = m.styleCurrent
+		if m.pulsing(index) {
+			style = style.Foreground(pulseColor)
+		}
+	default:
+		if c := fade(m.opts.Theme.FutureFade, int(math.Ceil(d))); c != nil {
+			style = style.Foreground(c)
 		}
-		line := m.styleAfter.
-			Width(m.w).
+	}
+	if !m.state.Playing {
+		style = style.Faint(true)
+	}
+	if m.matching(index) {
+		if m.background() {
+			style = style.Reverse(true)
+		} else {
+			style = style.Underline(true)
+		}
+	}
+	return style
+}
+
+// renderLine renders lyric line index, styled by its position relative to the
+// playhead, and returns its wrapped rows.
+func (m *Model) renderLine(index int) []string {
+	style := m.lineStyle(index)
+	original, translation := lineTexts(m.state.Lines[index], m.translation)
+	text := m.fit(original)
+	if index == m.state.Index && m.opts.Overflow == OverflowMarquee {
+		text = m.marquee(original)
+	}
+	rows := strings.Split(style.
+		Width(m.textWidth()).
+		Align(m.hAlignment).
+		Render(text), "\n")
+	if translation != "" {
+		// the translation sits dimmed beneath its original, wrapped on its own
+		rows = append(rows, strings.Split(style.
+			Faint(true).
+			Bold(false).
+			Width(m.textWidth()).
 			Align(m.hAlignment).
-			Render(m.state.Lines[afterIndex].Text)
-		afterIndex += 1
-		afterLines := strings.Split(line, "\n")
-		for i, line := range afterLines {
-			lineIndex := index + i
-			if lineIndex < len(lines) {
-				lines[lineIndex] = line
+			Render(m.fit(translation)), "\n")...)
+	}
+	if m.timestamps {
+		// a faint timestamp column, blank below the line's first row
+		for i := range rows {
+			stamp := strings.Repeat(" ", timestampWidth)
+			if i == 0 {
+				stamp = lyrics.FormatTimestamp(m.state.Lines[index].Time) + " "
 			}
-			filledAfter += 1
+			rows[i] = m.styleHeader.Render(stamp) + rows[i]
 		}
 	}
+	return rows
+}
 
-	return gloss.JoinVertical(m.hAlignment, lines...)
+// blockTop returns the first row of a window of height rows relative to the
+// first row of an n-row centered block, placing the block per the vertical
+// alignment option. Blocks taller than the window show their beginning.
+func (m *Model) blockTop(height, n int) int {
+	switch m.opts.VAlign {
+	case VAlignTop:
+		return 0
+	case VAlignBottom:
+		return min(0, n-height)
+	}
+	return -(height - n) / 2
+}
+
+// vPosition returns the lipgloss placement matching the vertical alignment option.
+func (m *Model) vPosition() gloss.Position {
+	switch m.opts.VAlign {
+	case VAlignTop:
+		return gloss.Top
+	case VAlignBottom:
+		return gloss.Bottom
+	}
+	return gloss.Center
+}
+
+// messageView renders a single message in place of the lyrics.
+func (m *Model) messageView(height int, style gloss.Style, text string) string {
+	return gloss.PlaceVertical(
+		height, m.vPosition(),
+		style.
+			Align(gloss.Center).
+			Width(m.lyricsWidth).
+			Render(text),
+	)
+}
+
+// repeatIndex returns a slice of n copies of index.
+func repeatIndex(index, n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = index
+	}
+	return s
 }
 
 func waitForUpdate(ch chan pool.Update) tea.Cmd {
 	return func() tea.Msg {
-		return <-ch
+		u, ok := <-ch
+		if !ok {
+			return nil // the pool stopped
+		}
+		return u
+	}
+}
+
+// listen starts the pool and returns its updates, after passing each to
+// the outputs opts enables beside the display. Like the pool's, the
+// channel is closed after a pool.StateStopped update once ctx is done.
+// The display and every output have a mailbox of their own (see
+// pool.Offer), so a slow one only misses updates superseded meanwhile and
+// holds up none of the others.
+func listen(ctx context.Context, pollInterval time.Duration, retry <-chan struct{}, opts Options) chan pool.Update {
+	ch := make(chan pool.Update, 1)
+	if opts.Lead != 0 && opts.Verbose {
+		log.Printf("lead: lines selected %v ahead of the playback position", opts.Lead)
+	}
+	p := pool.New(opts.Player, opts.Lyrics, pool.Config{
+		PollInterval: pollInterval,
+		Retry:        retry,
+		Offsets:      opts.offsets,
+		Lead:         opts.Lead,
+		Debounce:     opts.FetchDebounce,
+		Early:        opts.EmitEarly,
+		Verbose:      opts.Verbose,
+	})
+	if opts.hub != nil {
+		opts.hub.follow(p)
+	}
+	go p.Run(ctx, ch)
+	var sinks []func(pool.Update)
+	var rewrites []func() // the files' rewrite, on opts.Rewrite
+	if opts.OutputFile != "" {
+		f := &fileWriter{opts: opts}
+		sinks = append(sinks, f.update)
+		rewrites = append(rewrites, f.rewrite)
 	}
+	if opts.state != nil {
+		sinks = append(sinks, opts.state.update)
+		rewrites = append(rewrites, opts.state.rewrite)
+	}
+	if opts.fifo != nil {
+		sinks = append(sinks, opts.fifo.update)
+	}
+	if opts.hub != nil {
+		sinks = append(sinks, opts.hub.update)
+	}
+	if opts.bus != nil {
+		sinks = append(sinks, opts.bus.update)
+	}
+	if opts.mqtt != nil {
+		sinks = append(sinks, opts.mqtt.update)
+	}
+	if opts.tray != nil {
+		sinks = append(sinks, opts.tray.update)
+	}
+	if opts.discord != nil {
+		sinks = append(sinks, opts.discord.update)
+	}
+	if len(sinks) == 0 {
+		return ch
+	}
+	// the mailboxes share the pool's lifecycle: each gets the stopped
+	// update and is closed after the pool's channel
+	mailboxes := make([]chan pool.Update, len(sinks))
+	for i, sink := range sinks {
+		mailboxes[i] = make(chan pool.Update, 1)
+		go func(mailbox chan pool.Update) {
+			for u := range mailbox {
+				sink(u)
+			}
+		}(mailboxes[i])
+	}
+	out := make(chan pool.Update, 1)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case u, ok := <-ch:
+				if !ok {
+					for _, mailbox := range mailboxes {
+						close(mailbox)
+					}
+					return
+				}
+				early := emitEarly(u, opts)
+				for _, mailbox := range mailboxes {
+					pool.Offer(mailbox, early)
+				}
+				pool.Offer(out, u)
+			case <-opts.Rewrite:
+				if opts.Verbose && len(rewrites) > 0 {
+					log.Print("rewriting the output files")
+				}
+				for _, rewrite := range rewrites {
+					rewrite()
+				}
+			}
+		}
+	}()
+	return out
 }
 
 // TerminalLyricsUI starts the terminal UI and listens for updates from the pool.
-func TerminalLyricsUI(ctx context.Context, pollInterval time.Duration) (userQuit bool, err error) {
-	ch := make(chan pool.Update)
-	go pool.Listen(ctx, ch, pollInterval)
-	p := tea.NewProgram(newModel(ch), tea.WithContext(ctx), tea.WithAltScreen())
-	_, err = p.Run()
+func TerminalLyricsUI(ctx context.Context, pollInterval time.Duration, opts Options) (userQuit bool, err error) {
+	retry := make(chan struct{}, 1)
+	ch := listen(ctx, pollInterval, retry, opts)
+	m := newModel(ctx, ch, opts)
+	m.retry = retry
+	err = runProgram(ctx, m, opts)
 	select {
 	case <-ctx.Done():
 		return false, err
@@ -235,13 +1192,11 @@ func TerminalLyricsUI(ctx context.Context, pollInterval time.Duration) (userQuit
 }
 
 // TerminalLyricsContext runs the terminal UI for lyrics display and returns when the UI is quit.
-func TerminalLyricsContext(ctx context.Context, pollInterval time.Duration) (userQuit bool, err error) {
-	return TerminalLyricsUI(ctx, pollInterval)
+func TerminalLyricsContext(ctx context.Context, pollInterval time.Duration, opts Options) (userQuit bool, err error) {
+	return TerminalLyricsUI(ctx, pollInterval, opts)
 }
 
 // TerminalLyricsContextWithChannel starts the terminal UI with a provided update channel.
-func TerminalLyricsContextWithChannel(ctx context.Context, updateCh chan pool.Update) error {
-	p := tea.NewProgram(newModel(updateCh), tea.WithContext(ctx), tea.WithAltScreen())
-	_, err := p.Run()
-	return err
+func TerminalLyricsContextWithChannel(ctx context.Context, updateCh chan pool.Update, opts Options) error {
+	return runProgram(ctx, newModel(ctx, updateCh, opts), opts)
 }
@@ -0,0 +1,210 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/best8oy/LyricsMPRIS/lyrics"
+	"github.com/best8oy/LyricsMPRIS/mpris"
+	"github.com/best8oy/LyricsMPRIS/pool"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// wrappingLines returns lyrics whose lines wrap to one, two or three rows
+// at a width of 30.
+func wrappingLines(n int) []lyrics.LyricLine {
+	lines := make([]lyrics.LyricLine, n)
+	for i := range lines {
+		lines[i] = lyrics.LyricLine{
+			Time: float64(10 + 4*i),
+			Text: fmt.Sprintf("line %d %s", i, strings.Repeat("word ", i%3*6)),
+		}
+	}
+	return lines
+}
+
+// newTestModel returns a model of the given size showing u.
+func newTestModel(width, height int, opts Options, u pool.Update) *Model {
+	opts.NoAnimation = true
+	m := newModel(context.Background(), make(chan pool.Update), opts)
+	m.Update(tea.WindowSizeMsg{Width: width, Height: height})
+	m.Update(u)
+	return m
+}
+
+func TestCurrentLineCentered(t *testing.T) {
+	lines := wrappingLines(12)
+	track := mpris.TrackMetadata{Title: "Song", Artist: "Band"}
+	for _, height := range []int{9, 12, 16, 25} {
+		for index := range lines {
+			u := pool.Update{
+				State:    pool.StateReady,
+				Lines:    lines,
+				Index:    index,
+				Playing:  true,
+				Position: lines[index].Time + 1,
+				Duration: 200,
+				Track:    track,
+			}
+			m := newTestModel(30, height, Options{}, u)
+			m.View()
+			first, last := -1, -1
+			for row, line := range m.rowLines {
+				if line == index {
+					if first < 0 {
+						first = row
+					}
+					last = row
+				}
+			}
+			if first < 0 {
+				t.Errorf("height %d, line %d: not rendered", height, index)
+				continue
+			}
+			center := (len(m.rowLines) - 1) / 2
+			if mid := (first + last) / 2; mid < center-1 || mid > center+1 {
+				t.Errorf("height %d, line %d: rows %d-%d of %d, not within a row of %d", height, index, first, last, len(m.rowLines), center)
+			}
+		}
+	}
+}
+
+func TestIntroHighlightsNoLine(t *testing.T) {
+	lines := wrappingLines(6)
+	u := pool.Update{
+		State:    pool.StateReady,
+		Lines:    lines,
+		Index:    -1,
+		Playing:  true,
+		Position: 0,
+		Duration: 200,
+		Track:    mpris.TrackMetadata{Title: "Song", Artist: "Band"},
+	}
+	m := newTestModel(30, 16, Options{}, u)
+	m.View()
+	center := (len(m.rowLines) - 1) / 2
+	first := slices.Index(m.rowLines, 0)
+	if m.rowLines[center] != -1 || first <= center {
+		t.Errorf("rows %v: want a blank center row with line 0 below it", m.rowLines)
+	}
+}
+
+func TestPlaybackRate(t *testing.T) {
+	lines := []lyrics.LyricLine{{Time: 10, Text: "one"}, {Time: 11, Text: "two"}, {Time: 12, Text: "three"}, {Time: 20, Text: "four"}}
+	tests := []struct {
+		rate     float64
+		index    int
+		progress float64 // into the line, a second after the update
+	}{
+		{1, 1, 0.25},
+		{2, 2, 0.03125},
+		{0.5, 0, 0.75},
+		{0, 1, 0.25}, // an update without a rate advances at 1
+	}
+	for _, tt := range tests {
+		u := pool.Update{
+			State:    pool.StateReady,
+			Lines:    lines,
+			Index:    0,
+			Playing:  true,
+			Position: 10.25,
+			Rate:     tt.rate,
+			Duration: 200,
+			Track:    mpris.TrackMetadata{Title: "Song", Artist: "Band"},
+		}
+		second := time.Now().Add(-time.Second)
+		m := newTestModel(30, 16, Options{}, u)
+		m.received = second
+		m.syncIndex()
+		if m.state.Index != tt.index {
+			t.Errorf("rate %v: line %d a second on, want %d", tt.rate, m.state.Index, tt.index)
+		}
+
+		u.Index = tt.index
+		c := progressClock{}
+		c.update(u)
+		c.received = second
+		e, ok := c.event()
+		if !ok {
+			t.Errorf("rate %v: no progress event", tt.rate)
+		} else if math.Abs(*e.Progress-tt.progress) > 0.01 {
+			t.Errorf("rate %v: progress %v a second on, want %v", tt.rate, *e.Progress, tt.progress)
+		}
+	}
+}
+
+func TestListenLeavesNoGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for range 5 {
+		opts := Options{
+			Player:     newFakePlayer(mpris.TrackMetadata{Title: "Song", Artist: "Band"}, 60, 0, true),
+			Lyrics:     fakeLyrics{lines: []lyrics.LyricLine{{Time: 0, Text: "one"}}},
+			OutputFile: filepath.Join(t.TempDir(), "line"),
+		}
+		opts.hub = newHub(opts)
+		sub := opts.hub.subscribe(false)
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := listen(ctx, time.Second, nil, opts)
+		for u := range ch {
+			if u.State == pool.StateReady {
+				cancel()
+			}
+		}
+		cancel()
+		opts.hub.close()
+		for range sub.events { // closed with the hub
+		}
+	}
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines after 5 runs, %d before", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRewriteOutputFiles(t *testing.T) {
+	dir := t.TempDir()
+	outputFile, stateFile := filepath.Join(dir, "line"), filepath.Join(dir, "state.json")
+	rewrite := make(chan os.Signal, 1)
+	player := newFakePlayer(mpris.TrackMetadata{Title: "Song", Artist: "Band"}, 60, 0, true)
+	opts := Options{
+		Player:     player,
+		Lyrics:     fakeLyrics{lines: []lyrics.LyricLine{{Time: 0, Text: "one"}, {Time: 100, Text: "two"}}},
+		OutputFile: outputFile,
+		state:      newStateFile(stateFile),
+		Rewrite:    rewrite,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := listen(ctx, time.Second, nil, opts)
+	defer func() {
+		cancel()
+		for range ch {
+		}
+	}()
+	written := func(what string) {
+		t.Helper()
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			line, err := os.ReadFile(outputFile)
+			state, err2 := os.ReadFile(stateFile)
+			if err == nil && err2 == nil && string(line) == "one" && strings.Contains(string(state), `"one"`) {
+				return
+			}
+		}
+		t.Fatalf("%s: the files do not hold the line", what)
+	}
+	written("first")
+	os.Remove(outputFile)
+	os.Remove(stateFile)
+	rewrite <- syscall.SIGHUP
+	written("rewritten")
+}
@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	gloss "github.com/charmbracelet/lipgloss"
+)
+
+// viewportView renders every lyric line into a scrollable window of the
+// given height with a scrollbar on the right edge. Unless the user has
+// scrolled away, the window keeps the playing line in view.
+func (m *Model) viewportView(height int) string {
+	var rows []string
+	var owners []int
+	curFirst, curLast := -1, -1
+	for index := range m.state.Lines {
+		lineRows := m.renderLine(index)
+		if index == m.viewIndex() {
+			curFirst, curLast = len(rows), len(rows)+len(lineRows)-1
+		}
+		rows = append(rows, lineRows...)
+		owners = append(owners, repeatIndex(index, len(lineRows))...)
+	}
+
+	if (!m.scrolled || m.vpJump) && curFirst >= 0 {
+		// keep the playing (or jumped-to) line in view, placed per the
+		// vertical alignment
+		m.vpTop = curFirst + m.blockTop(height, curLast-curFirst+1)
+	}
+	m.vpJump = false
+	m.vpTop = max(0, min(m.vpTop, len(rows)-height))
+
+	lines := make([]string, height)
+	m.rowLines = make([]int, height)
+	thumbStart, thumbEnd := scrollThumb(m.vpTop, height, len(rows))
+	for i := range lines {
+		m.rowLines[i] = -1
+		text := strings.Repeat(" ", m.textWidth())
+		if row := m.vpTop + i; row < len(rows) {
+			text = rows[row]
+			m.rowLines[i] = owners[row]
+		}
+		bar := m.styleBefore.Render("│")
+		if i >= thumbStart && i < thumbEnd {
+			bar = m.styleCurrent.Render("┃")
+		}
+		lines[i] = text + bar
+	}
+	return gloss.JoinVertical(gloss.Left, lines...)
+}
+
+// timestampWidth is the width of the timestamp column: "[mm:ss.xx] ".
+const timestampWidth = 11
+
+// scrollThumb returns the rows [start, end) of the scrollbar thumb for a
+// window of height rows starting at row top of total rows.
+func scrollThumb(top, height, total int) (start, end int) {
+	if total <= height {
+		return 0, height
+	}
+	size := max(1, height*height/total)
+	start = top * (height - size) / max(1, total-height)
+	return start, start + size
+}
+
+// scrollViewport moves the viewport by delta rows, detaching it from the
+// playing line for a while.
+func (m *Model) scrollViewport(delta int) {
+	m.vpTop = max(0, m.vpTop+delta)
+	m.scrolled = true
+	m.scrollUntil = time.Now().Add(scrollTimeout)
+}
+
+// textWidth returns the width available to lyric text, leaving room for
+// the viewport's scrollbar and the timestamp column.
+func (m *Model) textWidth() int {
+	width := m.lyricsWidth
+	if m.viewport {
+		width--
+	}
+	if m.timestamps {
+		width -= timestampWidth
+	}
+	return max(1, width)
+}
@@ -0,0 +1,175 @@
+package ui
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/best8oy/LyricsMPRIS/websocket"
+)
+
+// wsCommand is a command a WebSocket client sends:
+//
+//	{"cmd": "playpause"}
+//	{"cmd": "seek", "to": 123.4}      position in seconds
+//	{"cmd": "offset", "delta": 0.1}   lyric offset change in seconds
+type wsCommand struct {
+	Cmd   string   `json:"cmd"`
+	To    *float64 `json:"to"`
+	Delta *float64 `json:"delta"`
+}
+
+// wsReply answers every command, with the error when it failed.
+type wsReply struct {
+	Type  string `json:"type"` // always "reply"
+	Cmd   string `json:"cmd"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// serveWebSocket streams the events of h to a WebSocket client, starting
+// with a snapshot, and runs the commands it sends back. Commands need the
+// token: any site the user visits could otherwise drive the player, and
+// neither the Origin, which other clients need not send, nor the Host,
+// which DNS rebinding sets to the attacker's name, proves a request local.
+// Without a token the events go to same-origin pages on a loopback host
+// only, and every command is refused.
+func serveWebSocket(w http.ResponseWriter, r *http.Request, h *hub, opts Options) {
+	if opts.HTTPToken != "" {
+		token := r.URL.Query().Get("token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(opts.HTTPToken)) != 1 {
+			http.Error(w, "bad token", http.StatusUnauthorized)
+			return
+		}
+	} else if !loopbackHost(r.Host) || !sameOrigin(r) {
+		http.Error(w, "requests from other hosts need -http-token", http.StatusForbidden)
+		return
+	}
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	sub := h.subscribe(true)
+	if sub == nil {
+		return
+	}
+	defer h.unsubscribe(sub)
+	if opts.Verbose {
+		log.Print("listen-http: websocket client connected")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				if opts.Verbose && !errors.Is(err, websocket.ErrClosed) {
+					log.Printf("listen-http: websocket: %v", err)
+				}
+				return
+			}
+			reply := runCommand(r, msg, opts)
+			if err := conn.WriteText(encodeJSON(reply), clientWriteTimeout); err != nil {
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		case msg, ok := <-sub.events:
+			if !ok {
+				return
+			}
+			if err := conn.WriteText(msg, clientWriteTimeout); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// sameOrigin reports whether r comes from a page served here, or from
+// something other than a browser, which sends no Origin.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// loopbackHost reports whether host, a Host header with or without a
+// port, names this machine: localhost or a loopback address.
+func loopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// runCommand runs the command in msg and returns its reply. A malformed
+// command only gets an error reply, as does any command without a token.
+func runCommand(r *http.Request, msg []byte, opts Options) wsReply {
+	var c wsCommand
+	if err := json.Unmarshal(msg, &c); err != nil {
+		return wsReply{Type: "reply", Error: "malformed command: " + err.Error()}
+	}
+	reply := wsReply{Type: "reply", Cmd: c.Cmd}
+	if opts.HTTPToken == "" {
+		reply.Error = "commands need -http-token"
+		return reply
+	}
+	var err error
+	switch c.Cmd {
+	case "playpause":
+		err = opts.Player.PlayPause(r.Context())
+	case "seek":
+		if c.To == nil || *c.To < 0 {
+			err = errors.New(`"to" must be a position in seconds`)
+			break
+		}
+		err = opts.Player.SetPosition(r.Context(), *c.To)
+	case "offset":
+		if c.Delta == nil {
+			err = errors.New(`"delta" must be an offset change in seconds`)
+			break
+		}
+		err = sendOffset(opts.offsets, *c.Delta)
+	default:
+		err = fmt.Errorf("unknown command %q", c.Cmd)
+	}
+	if err != nil {
+		reply.Error = err.Error()
+	} else {
+		reply.OK = true
+	}
+	return reply
+}
+
+// sendOffset passes a lyric offset change to the pool.
+func sendOffset(offsets chan<- float64, delta float64) error {
+	if offsets == nil {
+		return errors.New("no lyric offset in this mode")
+	}
+	select {
+	case offsets <- delta:
+		return nil
+	default:
+		return errors.New("too many offset changes queued")
+	}
+}
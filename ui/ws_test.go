@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/best8oy/LyricsMPRIS/mpris"
+)
+
+func TestLoopbackHost(t *testing.T) {
+	for host, want := range map[string]bool{
+		"localhost":          true,
+		"LOCALHOST:8277":     true,
+		"127.0.0.1":          true,
+		"127.3.2.1:8277":     true,
+		"[::1]:8277":         true,
+		"::1":                true,
+		"evil.example":       false,
+		"evil.example:8277":  false,
+		"localhost.evil.com": false,
+		"192.168.1.5:8277":   false,
+		"[::2]:8277":         false,
+		"":                   false,
+	} {
+		if got := loopbackHost(host); got != want {
+			t.Errorf("loopbackHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestWebSocketAccess(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string // -http-token
+		host   string
+		origin string
+		query  string
+		want   int // status; 400 is the handshake failing past the checks
+	}{
+		{name: "local page", host: "localhost:8277", origin: "http://localhost:8277", want: http.StatusBadRequest},
+		{name: "local client without origin", host: "127.0.0.1:8277", want: http.StatusBadRequest},
+		{name: "rebound name", host: "evil.example:8277", origin: "http://evil.example:8277", want: http.StatusForbidden},
+		{name: "rebound name without origin", host: "evil.example:8277", want: http.StatusForbidden},
+		{name: "other origin", host: "localhost:8277", origin: "http://evil.example", want: http.StatusForbidden},
+		{name: "token", token: "s3cret", host: "evil.example:8277", query: "?token=s3cret", want: http.StatusBadRequest},
+		{name: "wrong token", token: "s3cret", host: "localhost:8277", query: "?token=guess", want: http.StatusUnauthorized},
+		{name: "no token", token: "s3cret", host: "localhost:8277", want: http.StatusUnauthorized},
+	}
+	h := newHub(Options{})
+	defer h.close()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/ws"+tt.query, nil)
+			r.Host = tt.host
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			serveWebSocket(w, r, h, Options{HTTPToken: tt.token})
+			if w.Code != tt.want {
+				t.Errorf("status %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebSocketCommandsNeedToken(t *testing.T) {
+	r := httptest.NewRequest("GET", "/ws", nil)
+	if reply := runCommand(r, []byte(`{"cmd":"playpause"}`), Options{}); reply.OK || reply.Error != "commands need -http-token" {
+		t.Errorf("command without a token: %+v", reply)
+	}
+	player := newFakePlayer(mpris.TrackMetadata{Title: "Song", Artist: "Band"}, 200, 15, true)
+	opts := Options{HTTPToken: "s3cret", Player: player}
+	if reply := runCommand(r, []byte(`{"cmd":"stop"}`), opts); reply.Error != `unknown command "stop"` {
+		t.Errorf("command with a token: %+v", reply)
+	}
+	for _, cmd := range []string{`{"cmd":"playpause"}`, `{"cmd":"seek","to":42.5}`} {
+		if reply := runCommand(r, []byte(cmd), opts); !reply.OK {
+			t.Errorf("%s: %+v", cmd, reply)
+		}
+	}
+	if got, want := player.used(), []string{"playpause", "position 42.5"}; !slices.Equal(got, want) {
+		t.Errorf("controls %q, want %q", got, want)
+	}
+	if reply := runCommand(r, []byte(`{"cmd":`), Options{}); reply.OK || reply.Error == "" {
+		t.Errorf("malformed command: %+v", reply)
+	}
+}
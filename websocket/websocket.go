@@ -0,0 +1,227 @@
+// Package websocket is the server side of the WebSocket protocol (RFC
+// 6455), as much of it as a local control socket needs: text and binary
+// messages, fragmentation, ping and close. Extensions and subprotocols are
+// not negotiated.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxMessage is the largest message a Conn reads; larger ones fail the
+// connection.
+const MaxMessage = 64 << 10
+
+// controlTimeout bounds writing a pong or close frame.
+const controlTimeout = 5 * time.Second
+
+// acceptGUID is appended to the client's key to prove the handshake was
+// understood.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Frame opcodes.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// ErrClosed is returned by ReadMessage once the peer closed the connection.
+var ErrClosed = errors.New("websocket: connection closed")
+
+// Conn is a WebSocket connection. Reads must come from one goroutine;
+// writes may come from any.
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	wmu  sync.Mutex
+}
+
+// Upgrade answers a WebSocket handshake request, taking over the
+// connection. On failure it has replied with an error status.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") || key == "" {
+		http.Error(w, "websocket handshake expected", http.StatusBadRequest)
+		return nil, errors.New("websocket: not a handshake request")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket: unsupported version")
+	}
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, err
+	}
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	_, err = fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(sum[:]))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{conn: conn, br: brw.Reader}, nil
+}
+
+// headerContains reports whether the comma separated header name holds
+// token, ignoring case.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage returns the next text or binary message, answering pings on
+// the way. It returns ErrClosed once the peer closes the connection.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var msg []byte
+	started := false
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.writeFrame(opClose, nil)
+			return nil, ErrClosed
+		case opText, opBinary:
+			if started {
+				return nil, errors.New("websocket: new message within a fragmented one")
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				return nil, errors.New("websocket: continuation without a message")
+			}
+		default:
+			return nil, fmt.Errorf("websocket: unknown opcode %#x", op)
+		}
+		if len(msg)+len(payload) > MaxMessage {
+			return nil, errors.New("websocket: message too large")
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+// readFrame reads one frame, unmasking its payload.
+func (c *Conn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, op = head[0]&0x80 != 0, head[0]&0x0f
+	if head[1]&0x80 == 0 {
+		return false, 0, nil, errors.New("websocket: unmasked client frame")
+	}
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if op >= opClose && (n > 125 || !fin) {
+		return false, 0, nil, errors.New("websocket: malformed control frame")
+	}
+	if n > MaxMessage {
+		return false, 0, nil, errors.New("websocket: message too large")
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// WriteText sends p as one text message, failing after timeout if the
+// peer does not take it.
+func (c *Conn) WriteText(p []byte, timeout time.Duration) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(timeout))
+	return c.writeFrameLocked(opText, p)
+}
+
+// writeFrame sends a control frame.
+func (c *Conn) writeFrame(op byte, p []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(controlTimeout))
+	return c.writeFrameLocked(op, p)
+}
+
+// writeFrameLocked writes p as one unmasked frame, as servers send them.
+func (c *Conn) writeFrameLocked(op byte, p []byte) error {
+	head := make([]byte, 2, 10+len(p))
+	head[0] = 0x80 | op
+	switch n := len(p); {
+	case n <= 125:
+		head[1] = byte(n)
+	case n <= 0xffff:
+		head[1] = 126
+		head = binary.BigEndian.AppendUint16(head, uint16(n))
+	default:
+		head[1] = 127
+		head = binary.BigEndian.AppendUint64(head, uint64(n))
+	}
+	_, err := c.conn.Write(append(head, p...))
+	return err
+}
+
+// Close closes the connection, telling the peer first when it can.
+func (c *Conn) Close() error {
+	c.wmu.Lock()
+	c.conn.SetWriteDeadline(time.Now().Add(controlTimeout))
+	c.writeFrameLocked(opClose, nil)
+	c.wmu.Unlock()
+	return c.conn.Close()
+}
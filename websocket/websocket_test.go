@@ -0,0 +1,322 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pipe returns a Conn on one end of an in-memory connection and the
+// client's end. What the server sends is read off the client's end in the
+// background, so that it never blocks the server, and comes on frames.
+func pipe(t *testing.T) (c *Conn, client net.Conn, frames <-chan frame) {
+	t.Helper()
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	ch := make(chan frame, 16)
+	go func() {
+		defer close(ch)
+		r := bufio.NewReader(client)
+		for {
+			f, err := readServerFrame(r)
+			if err != nil {
+				return
+			}
+			ch <- f
+		}
+	}()
+	return &Conn{conn: server, br: bufio.NewReader(server)}, client, ch
+}
+
+// frame is a frame the server sent.
+type frame struct {
+	fin     bool
+	op      byte
+	masked  bool
+	payload []byte
+}
+
+func readServerFrame(r io.Reader) (frame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return frame{}, err
+	}
+	f := frame{fin: head[0]&0x80 != 0, op: head[0] & 0x0f, masked: head[1]&0x80 != 0}
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return frame{}, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return frame{}, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	f.payload = make([]byte, n)
+	_, err := io.ReadFull(r, f.payload)
+	return f, err
+}
+
+// Lengths of clientFrame: the shortest encoding, or the 16- or 64-bit
+// extended length whatever the payload's.
+const (
+	lenShortest = iota
+	len16
+	len64
+)
+
+// clientFrame returns a frame as a client sends it, masked unless unmasked
+// is set.
+func clientFrame(fin bool, op byte, payload []byte, length int, unmasked bool) []byte {
+	b := []byte{op, 0}
+	if fin {
+		b[0] |= 0x80
+	}
+	switch n := len(payload); {
+	case length == len64 || length == lenShortest && n > 0xffff:
+		b[1] = 127
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	case length == len16 || n > 125:
+		b[1] = 126
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b[1] = byte(n)
+	}
+	if unmasked {
+		return append(b, payload...)
+	}
+	b[1] |= 0x80
+	mask := [4]byte{0x37, 0xfa, 0x21, 0x3d}
+	b = append(b, mask[:]...)
+	for i, c := range payload {
+		b = append(b, c^mask[i%4])
+	}
+	return b
+}
+
+// send writes frames from the client in the background.
+func send(client net.Conn, frames ...[]byte) {
+	go func() {
+		for _, f := range frames {
+			if _, err := client.Write(f); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+func TestReadMessage(t *testing.T) {
+	long := bytes.Repeat([]byte("0123456789"), 1000)
+	tests := []struct {
+		name   string
+		frames [][]byte
+		want   []byte
+	}{
+		{"text", [][]byte{clientFrame(true, opText, []byte("hello"), lenShortest, false)}, []byte("hello")},
+		{"binary", [][]byte{clientFrame(true, opBinary, []byte{0, 1, 0xff}, lenShortest, false)}, []byte{0, 1, 0xff}},
+		{"empty", [][]byte{clientFrame(true, opText, nil, lenShortest, false)}, []byte{}},
+		{"16-bit length", [][]byte{clientFrame(true, opText, long[:200], lenShortest, false)}, long[:200]},
+		{"16-bit length of a short payload", [][]byte{clientFrame(true, opText, []byte("hi"), len16, false)}, []byte("hi")},
+		{"64-bit length", [][]byte{clientFrame(true, opText, long, len64, false)}, long},
+		{"fragments", [][]byte{
+			clientFrame(false, opText, []byte("hel"), lenShortest, false),
+			clientFrame(false, opContinuation, []byte("lo "), lenShortest, false),
+			clientFrame(true, opContinuation, []byte("there"), lenShortest, false),
+		}, []byte("hello there")},
+		{"pong between fragments", [][]byte{
+			clientFrame(false, opText, []byte("hel"), lenShortest, false),
+			clientFrame(true, opPong, []byte("late"), lenShortest, false),
+			clientFrame(true, opContinuation, []byte("lo"), lenShortest, false),
+		}, []byte("hello")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, client, _ := pipe(t)
+			send(client, tt.frames...)
+			got, err := c.ReadMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("read %d bytes %.20q, want %d bytes %.20q", len(got), got, len(tt.want), tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMessageAnswersPingMidMessage(t *testing.T) {
+	c, client, frames := pipe(t)
+	send(client,
+		clientFrame(false, opText, []byte("hel"), lenShortest, false),
+		clientFrame(true, opPing, []byte("are you there"), lenShortest, false),
+		clientFrame(true, opContinuation, []byte("lo"), lenShortest, false),
+	)
+	got, err := c.ReadMessage()
+	if err != nil || string(got) != "hello" {
+		t.Fatalf("read %q, %v; want hello", got, err)
+	}
+	select {
+	case f := <-frames:
+		if f.op != opPong || !f.fin || f.masked || string(f.payload) != "are you there" {
+			t.Errorf("answered with %+v, want an unmasked pong of the ping's payload", f)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no pong")
+	}
+}
+
+func TestReadMessageRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames [][]byte
+		want   string
+	}{
+		{"unmasked frame", [][]byte{clientFrame(true, opText, []byte("hi"), lenShortest, true)}, "unmasked client frame"},
+		{"frame over the limit", [][]byte{clientFrame(true, opText, make([]byte, MaxMessage+1), lenShortest, false)}, "message too large"},
+		{"message over the limit", [][]byte{
+			clientFrame(false, opBinary, make([]byte, MaxMessage/2+1), lenShortest, false),
+			clientFrame(true, opContinuation, make([]byte, MaxMessage/2+1), lenShortest, false),
+		}, "message too large"},
+		{"ping over 125 bytes", [][]byte{clientFrame(true, opPing, make([]byte, 126), lenShortest, false)}, "malformed control frame"},
+		{"fragmented ping", [][]byte{clientFrame(false, opPing, []byte("hi"), lenShortest, false)}, "malformed control frame"},
+		{"continuation first", [][]byte{clientFrame(true, opContinuation, []byte("hi"), lenShortest, false)}, "continuation without a message"},
+		{"message within a message", [][]byte{
+			clientFrame(false, opText, []byte("hel"), lenShortest, false),
+			clientFrame(true, opText, []byte("lo"), lenShortest, false),
+		}, "new message within a fragmented one"},
+		{"unknown opcode", [][]byte{clientFrame(true, 0x3, []byte("hi"), lenShortest, false)}, "unknown opcode 0x3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, client, _ := pipe(t)
+			send(client, tt.frames...)
+			if _, err := c.ReadMessage(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	// the client closing: the server answers and stops reading
+	c, client, frames := pipe(t)
+	send(client, clientFrame(true, opClose, []byte{0x03, 0xe8}, lenShortest, false))
+	if _, err := c.ReadMessage(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("error %v, want ErrClosed", err)
+	}
+	if f := <-frames; f.op != opClose || f.masked {
+		t.Errorf("answered with %+v, want an unmasked close", f)
+	}
+
+	// the server closing: it tells the client, then hangs up
+	c, _, frames = pipe(t)
+	c.Close()
+	if f := <-frames; f.op != opClose || !f.fin {
+		t.Errorf("closed with %+v, want a close frame", f)
+	}
+	if _, ok := <-frames; ok {
+		t.Error("frames after the close")
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	for _, n := range []int{0, 125, 126, 0xffff, 0x10000} {
+		c, _, frames := pipe(t)
+		p := bytes.Repeat([]byte("x"), n)
+		if err := c.WriteText(p, time.Second); err != nil {
+			t.Fatal(err)
+		}
+		f := <-frames
+		if f.op != opText || !f.fin || f.masked || !bytes.Equal(f.payload, p) {
+			t.Errorf("%d bytes: sent %v %v %v with %d bytes", n, f.op, f.fin, f.masked, len(f.payload))
+		}
+	}
+}
+
+func TestWriteTextTimesOut(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	c := &Conn{conn: server, br: bufio.NewReader(server)}
+	// nobody reads the client's end
+	if err := c.WriteText([]byte("hi"), 10*time.Millisecond); err == nil {
+		t.Error("write to a stalled peer succeeded")
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		if msg, err := c.ReadMessage(); err == nil {
+			c.WriteText(msg, time.Second)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+	}{
+		{"handshake", map[string]string{"Connection": "keep-alive, Upgrade", "Upgrade": "WebSocket", "Sec-WebSocket-Version": "13"}, http.StatusSwitchingProtocols},
+		{"no upgrade", map[string]string{"Connection": "keep-alive", "Sec-WebSocket-Version": "13"}, http.StatusBadRequest},
+		{"old version", map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "8"}, http.StatusUpgradeRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(time.Second))
+			req, _ := http.NewRequest("GET", srv.URL, nil)
+			// the sample key of RFC 6455
+			req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if err := req.Write(conn); err != nil {
+				t.Fatal(err)
+			}
+			br := bufio.NewReader(conn)
+			resp, err := http.ReadResponse(br, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status != http.StatusSwitchingProtocols {
+				return
+			}
+			if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+				t.Errorf("accept %q", got)
+			}
+			conn.Write(clientFrame(true, opText, []byte("echo"), lenShortest, false))
+			if f, err := readServerFrame(br); err != nil || f.op != opText || string(f.payload) != "echo" {
+				t.Errorf("echo %+v, %v", f, err)
+			}
+		})
+	}
+}